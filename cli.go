@@ -0,0 +1,490 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package main
+
+import (
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/internal/save"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/proposal"
+	"MPC_ECDSA/pkg/protocol"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// usage describes the commands, printed on a wrong command line.
+const usage = `usage: MPC_ECDSA [<command> [flags]]
+
+Without command, the parties connect and the center server reads the stages from the terminal.
+
+commands:
+  daemon --listen <addr> [--token-file <path>]
+                              serve the local HTTP API, addr is unix:<path> or a loopback host:port,
+                              which requires the bearer token of the file, created if it does not exist
+  keygen [--key-id <id>]
+  refresh --key-id <id> [--aux]
+  reshare [--key-id <id>]
+  presign --key-id <id> [--rounds 3|6] [--id <presign_id>] [--signers a,b]
+  sign --key-id <id> (--digest <hex> | --message <msg> [--scheme <scheme>])
+       [--rounds 3|6 [--presign-id <presign_id>]] [--signers a,b]
+  keys list
+  keys show <key_id>
+  verify (--public-key <hex> | --key-id <id>) (--digest <hex> | --message <msg> [--scheme <scheme>]) --signature <hex>
+
+Every party runs the same protocol command: the center server proposes it, the other parties only accept
+a proposal matching their own flags. The commands print JSON on the standard output, and the logs on the
+standard error. Run a command with -h for its flags.
+`
+
+// The config files are selected by flags, or by these environment variables.
+const (
+	connConfigEnv    = "MPC_ECDSA_CONN_CONFIG"
+	keygenConfigEnv  = "MPC_ECDSA_KEYGEN_CONFIG"
+	refreshConfigEnv = "MPC_ECDSA_REFRESH_CONFIG"
+	signConfigEnv    = "MPC_ECDSA_SIGN_CONFIG"
+	keyStoreDirEnv   = "MPC_ECDSA_KEYSTORE_DIR"
+)
+
+// nodeFlags are the flags locating the config files and the key store of the party.
+type nodeFlags struct {
+	paths       communication.ConfigPaths
+	keyStoreDir string
+}
+
+// newNodeFlags registers the node flags on fs, their default is the value of their environment variable.
+func newNodeFlags(fs *flag.FlagSet) *nodeFlags {
+	f := &nodeFlags{}
+	fs.StringVar(&f.paths.Conn, "conn-config", os.Getenv(connConfigEnv), "connection config, ./config/connConfig.json if empty (env "+connConfigEnv+")")
+	fs.StringVar(&f.paths.KeyGen, "keygen-config", os.Getenv(keygenConfigEnv), "keygen config, ./config/keygenConfig.json if empty (env "+keygenConfigEnv+")")
+	fs.StringVar(&f.paths.Refresh, "refresh-config", os.Getenv(refreshConfigEnv), "reshare config, ./config/refreshConfig.json if empty (env "+refreshConfigEnv+")")
+	fs.StringVar(&f.paths.Sign, "sign-config", os.Getenv(signConfigEnv), "sign config, ./config/signConfig.json if empty (env "+signConfigEnv+")")
+	fs.StringVar(&f.keyStoreDir, "keystore", os.Getenv(keyStoreDirEnv), "key store directory, replaces keyStoreDir of the connection config (env "+keyStoreDirEnv+")")
+	return f
+}
+
+// signersFlag is a comma separated list of party IDs.
+type signersFlag []party.ID
+
+func (s *signersFlag) String() string { return fmt.Sprint([]party.ID(*s)) }
+
+func (s *signersFlag) Set(value string) error {
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			*s = append(*s, party.ID(id))
+		}
+	}
+	return nil
+}
+
+// run executes the command line args, and returns the exit code:
+// 0 on success, 1 if the command failed and 2 for a wrong command line.
+func run(args []string) int {
+	if len(args) == 0 {
+		return runREPL(args)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "daemon":
+		return runDaemon(args)
+	case "keygen", "refresh", "reshare", "presign", "sign":
+		return runStage(command, args)
+	case "keys":
+		return runKeys(args)
+	case "verify":
+		return runVerify(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stderr, usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		return 2
+	}
+}
+
+// parseFlags parses args with fs, and returns false if the command line is wrong.
+func parseFlags(fs *flag.FlagSet, args []string) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "unexpected arguments %v\n", fs.Args())
+		return false
+	}
+	return true
+}
+
+// exitCode returns the exit code of a command which returned err.
+func exitCode(err error) int {
+	if err != nil {
+		log.Errorln(err)
+		return 1
+	}
+	return 0
+}
+
+// runREPL connects the parties, and runs the stages typed at the terminal of the center server.
+func runREPL(args []string) int {
+	fs := flag.NewFlagSet("MPC_ECDSA", flag.ContinueOnError)
+	node := newNodeFlags(fs)
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	localConn, store, presigns, err := setUp(node.paths, node.keyStoreDir, true)
+	if err != nil {
+		return exitCode(err)
+	}
+	return exitCode(repl(localConn, store, presigns))
+}
+
+// runDaemon connects the parties, and serves the local API until the process is interrupted.
+func runDaemon(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	node := newNodeFlags(fs)
+	listenAddr := fs.String("listen", "", "address of the API, e.g. unix:/run/mpc_ecdsa.sock or 127.0.0.1:9000")
+	tokenFile := fs.String("token-file", "", "file of the bearer token of the API, required on a TCP address")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	if *listenAddr == "" {
+		fmt.Fprintln(os.Stderr, "--listen is required")
+		return 2
+	}
+	localConn, store, presigns, err := setUp(node.paths, node.keyStoreDir, true)
+	if err != nil {
+		return exitCode(err)
+	}
+	//The daemon keeps the connections open, and executes the stages posted to its API as jobs.
+	d, err := newDaemon(localConn, localConn, store, presigns)
+	if err != nil {
+		return exitCode(err)
+	}
+	defer d.Close()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return exitCode(d.serve(ctx, *listenAddr, *tokenFile))
+}
+
+// runStage connects the parties and executes a single stage: the center server proposes it,
+// the other parties accept a proposal matching their flags only. It prints the job as JSON.
+func runStage(command string, args []string) int {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	node := newNodeFlags(fs)
+	req := &apiRequest{}
+	fs.StringVar(&req.KeyID, "key-id", "", "ID of the key")
+	var signers signersFlag
+	var digestHex string
+	switch command {
+	case "refresh":
+		fs.BoolVar(&req.AuxOnly, "aux", false, "rotate only the Paillier keys and Pedersen parameters")
+	case "presign":
+		fs.IntVar(&req.Rounds, "rounds", 3, "presignature protocol, 3 or 6 rounds")
+		fs.StringVar(&req.PresignID, "id", "", "name of the presignatures, the session ID if empty")
+		fs.Var(&signers, "signers", "comma separated signers, the ones of the sign config if empty")
+	case "sign":
+		fs.IntVar(&req.Rounds, "rounds", 0, "sign with a presignature of the 3 or 6 rounds protocol, without presignature if 0")
+		fs.StringVar(&req.PresignID, "presign-id", "", "presignature to use, the oldest available one if empty")
+		fs.StringVar(&digestHex, "digest", "", "hex encoded 32 bytes digest to sign")
+		fs.StringVar(&req.Message, "message", "", "message to sign, the one of the sign config if empty")
+		fs.StringVar((*string)(&req.Scheme), "scheme", "", "digest scheme of the message, sha256 if empty")
+		fs.Var(&signers, "signers", "comma separated signers, the ones of the sign config if empty")
+	}
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	req.Signers = signers
+	if digestHex != "" {
+		if req.Message != "" {
+			fmt.Fprintln(os.Stderr, "--digest and --message are exclusive")
+			return 2
+		}
+		req.Scheme, req.Message = digest.Raw, digestHex
+	}
+	if req.PresignID != "" && command == "sign" && req.Rounds == 0 {
+		fmt.Fprintln(os.Stderr, "--presign-id requires --rounds 3 or 6")
+		return 2
+	}
+	stage, err := req.stageOf(command)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	req.Stage = stage
+
+	localConn, store, presigns, err := setUp(node.paths, node.keyStoreDir, true)
+	if err != nil {
+		return exitCode(err)
+	}
+	j := executeOnce(localConn, localConn, store, presigns, &req.stageRequest)
+	printJSON(os.Stdout, j)
+	if j.State != jobSucceeded {
+		return 1
+	}
+	return 0
+}
+
+// executeOnce proposes or reviews the stage of req, executes it once accepted, and returns the outcome as a job.
+// The messages go through transport, the connections of localConn or an in-process network.
+func executeOnce(localConn *communication.LocalConn, transport communication.Transport, store save.KeyStore, presigns *save.PresignPool, req *stageRequest) *job {
+	now := time.Now()
+	j := &job{Stage: req.Stage, KeyID: req.KeyID, State: jobQueued, Created: now, Updated: now}
+	fail := func(err error) *job {
+		j.State, j.Error, j.Culprits, j.Abort, j.Updated = jobFailed, err.Error(), culprits(err), abortReport(err), time.Now()
+		if errors.Is(err, errRejected) {
+			j.State = jobRejected
+		}
+		return j
+	}
+
+	mux := protocol.NewMux(transport, localConn.LocalConfig.OtherPartyIDs, protocol.DefaultMaxPending, protocol.DefaultPendingTTL)
+	defer mux.Close()
+	control, err := mux.Open(controlProtocolID, nil, localConn.LocalConfig.OtherPartyIDs)
+	if err != nil {
+		return fail(err)
+	}
+	var p *proposal.SessionProposal
+	if localConn.LocalConfig.CenterServerID == localConn.LocalConfig.LocalID {
+		p, err = propose(localConn, control, presigns, req)
+	} else {
+		p, err = review(localConn, control, commandPolicy(req))
+		// review does not tell why the proposal was rejected
+		if errors.Is(err, errRejected) {
+			err = fmt.Errorf("%w, see the logs", err)
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+	j.SessionID, j.KeyID = hex.EncodeToString(p.SessionID), p.KeyID
+
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	result, err := stepIntoStage(localConn, p, mux, store, presigns, pl)
+	if err != nil {
+		return fail(err)
+	}
+	j.State, j.Result, j.Updated = jobSucceeded, result, time.Now()
+	return j
+}
+
+// commandPolicy returns the policy of a party which is not the center server: the proposal must be the stage of its command,
+// for the key, presignature, signers and message of its flags, if they are set.
+func commandPolicy(req *stageRequest) proposal.Policy {
+	return func(p *proposal.SessionProposal) error {
+		if p.Protocol != req.Stage {
+			return fmt.Errorf("proposed %s instead of %s", p.Protocol, req.Stage)
+		}
+		if req.KeyID != "" && p.KeyID != req.KeyID {
+			return fmt.Errorf("proposed key %q instead of %q", p.KeyID, req.KeyID)
+		}
+		if req.PresignID != "" && p.PresignID != req.PresignID {
+			return fmt.Errorf("proposed presignature %q instead of %q", p.PresignID, req.PresignID)
+		}
+		if len(req.Signers) > 0 && party.NewIDSlice(req.Signers).String() != p.Signers.String() {
+			return fmt.Errorf("proposed signers %v instead of %v", p.Signers, party.NewIDSlice(req.Signers))
+		}
+		if req.Message != "" {
+			d, _, err := messageDigest(req.Scheme, req.Message)
+			if err != nil {
+				return err
+			}
+			if p.Digest == nil || p.Digest.Scheme != d.Scheme || hex.EncodeToString(p.Digest.Hash) != hex.EncodeToString(d.Hash) {
+				return errors.New("proposed another digest")
+			}
+		}
+		return nil
+	}
+}
+
+// culprits returns the parties blamed by the protocol error err, if any.
+func culprits(err error) []party.ID {
+	var protocolErr protocol.Error
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Culprits
+	}
+	return nil
+}
+
+// abortReport returns the report of the protocol execution aborted with err, if any.
+func abortReport(err error) *protocol.AbortReport {
+	var report *protocol.AbortReport
+	if errors.As(err, &report) {
+		return report
+	}
+	return nil
+}
+
+// keyInfo describes a key of the key store.
+type keyInfo struct {
+	KeyID     string `json:"keyID"`
+	PublicKey string `json:"publicKey"`
+	// SelfID is the ID of the party in the key
+	SelfID    party.ID   `json:"selfID"`
+	Threshold int        `json:"threshold"`
+	PartyIDs  []party.ID `json:"partyIDs"`
+}
+
+// keyInfoOf returns the description of the key keyID of the store.
+func keyInfoOf(store save.KeyStore, keyID string) (*keyInfo, error) {
+	config, err := store.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := config.PublicPoint().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &keyInfo{
+		KeyID:     keyID,
+		PublicKey: hex.EncodeToString(publicKey),
+		SelfID:    config.ID,
+		Threshold: config.Threshold,
+		PartyIDs:  config.PartyIDs(),
+	}, nil
+}
+
+// listKeys returns the description of all the keys of the store.
+func listKeys(store save.KeyStore) ([]*keyInfo, error) {
+	keyIDs, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*keyInfo, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		key, err := keyInfoOf(store, keyID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// runKeys prints the keys of the key store, without connecting to the other parties.
+func runKeys(args []string) int {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "show") {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	fs := flag.NewFlagSet("keys "+args[0], flag.ContinueOnError)
+	node := newNodeFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if (args[0] == "list" && fs.NArg() != 0) || (args[0] == "show" && fs.NArg() != 1) {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	_, store, _, err := setUp(node.paths, node.keyStoreDir, false)
+	if err != nil {
+		return exitCode(err)
+	}
+	var out interface{}
+	if args[0] == "list" {
+		out, err = listKeys(store)
+	} else {
+		out, err = keyInfoOf(store, fs.Arg(0))
+	}
+	if err != nil {
+		return exitCode(err)
+	}
+	printJSON(os.Stdout, out)
+	return 0
+}
+
+// verifyResult is printed by the verify command.
+type verifyResult struct {
+	Valid     bool   `json:"valid"`
+	PublicKey string `json:"publicKey"`
+	Digest    string `json:"digest"`
+}
+
+// runVerify checks a signature r || s, or r || s || v, on a digest, without connecting to the other parties.
+// It prints the result as JSON, and exits with 1 if the signature is invalid.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	node := newNodeFlags(fs)
+	publicKeyHex := fs.String("public-key", "", "hex encoded compressed public key")
+	keyID := fs.String("key-id", "", "ID of the key of the key store, instead of --public-key")
+	digestHex := fs.String("digest", "", "hex encoded 32 bytes digest")
+	message := fs.String("message", "", "message, instead of --digest")
+	scheme := fs.String("scheme", "", "digest scheme of the message, sha256 if empty")
+	signatureHex := fs.String("signature", "", "hex encoded r || s, or r || s || v")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	if (*publicKeyHex == "") == (*keyID == "") || (*digestHex == "") == (*message == "") || *signatureHex == "" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	if *keyID != "" {
+		_, store, _, err := setUp(node.paths, node.keyStoreDir, false)
+		if err != nil {
+			return exitCode(err)
+		}
+		key, err := keyInfoOf(store, *keyID)
+		if err != nil {
+			return exitCode(err)
+		}
+		*publicKeyHex = key.PublicKey
+	}
+	publicKey, err := decodePublicKey(*publicKeyHex)
+	if err != nil {
+		return exitCode(fmt.Errorf("invalid public key: %w", err))
+	}
+	if *digestHex != "" {
+		*scheme, *message = string(digest.Raw), *digestHex
+	}
+	d, _, err := messageDigest(digest.Scheme(*scheme), *message)
+	if err != nil {
+		return exitCode(err)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(*signatureHex, "0x"))
+	if err != nil || (len(signature) != 64 && len(signature) != ecdsa.RecoverableLen) {
+		return exitCode(errors.New("the signature must be 64 or 65 hex encoded bytes"))
+	}
+
+	// the signature is valid if it recovers the public key, try every recovery ID if v is not given
+	recoveryIDs := []byte{0, 1, 2, 3}
+	if len(signature) == ecdsa.RecoverableLen {
+		recoveryIDs = signature[64:]
+	}
+	valid := false
+	for _, v := range recoveryIDs {
+		recovered, err := ecdsa.RecoverPublicKey(d.Hash, append(signature[:64:64], v))
+		valid = valid || (err == nil && recovered.Equal(publicKey))
+	}
+	printJSON(os.Stdout, &verifyResult{Valid: valid, PublicKey: *publicKeyHex, Digest: hex.EncodeToString(d.Hash)})
+	if !valid {
+		return 1
+	}
+	return 0
+}
+
+// printJSON writes v to w as indented JSON.
+func printJSON(w io.Writer, v interface{}) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Errorln("fail to print the result", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package main
+
+import (
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/party"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteOnce(t *testing.T) {
+	ids := party.NewIDSlice([]party.ID{"a", "b", "c"})
+	parties := newTestParties(t, ids)
+	// runAll executes the command of each party together, as separate processes would
+	runAll := func(reqs map[party.ID]*stageRequest) map[party.ID]*job {
+		network := communication.NewMemoryNetwork(ids)
+		jobs := make(map[party.ID]*job, len(reqs))
+		var mtx sync.Mutex
+		var wg sync.WaitGroup
+		for id, req := range reqs {
+			wg.Add(1)
+			go func(id party.ID, req *stageRequest) {
+				defer wg.Done()
+				p := parties[id]
+				j := executeOnce(p.localConn, network.Transport(id), p.store, p.presigns, req)
+				mtx.Lock()
+				jobs[id] = j
+				mtx.Unlock()
+			}(id, req)
+		}
+		wg.Wait()
+		return jobs
+	}
+
+	// b does not choose the key ID, it uses the one of the proposal
+	jobs := runAll(map[party.ID]*stageRequest{
+		"a": {Stage: "KeyGen", KeyID: "wallet1"},
+		"b": {Stage: "KeyGen"},
+		"c": {Stage: "KeyGen", KeyID: "wallet1"},
+	})
+	for id, j := range jobs {
+		require.Equal(t, jobSucceeded, j.State, "%v: %s", id, j.Error)
+		assert.Equal(t, "wallet1", j.KeyID)
+		assert.Equal(t, jobs["a"].Result.PublicKey, j.Result.PublicKey)
+	}
+	publicKey := jobs["a"].Result.PublicKey
+
+	hash := sha256.Sum256([]byte("transfer"))
+	digestHex := hex.EncodeToString(hash[:])
+	sign := func(digestHex string) *stageRequest {
+		return &stageRequest{Stage: "Sign", KeyID: "wallet1", Scheme: digest.Raw, Message: digestHex}
+	}
+	other := sha256.Sum256([]byte("another transfer"))
+	jobs = runAll(map[party.ID]*stageRequest{"a": sign(digestHex), "b": sign(digestHex), "c": sign(hex.EncodeToString(other[:]))})
+	for id, j := range jobs {
+		assert.Equal(t, jobRejected, j.State, "%v rejects another digest", id)
+	}
+	assert.True(t, strings.Contains(jobs["a"].Error, "c: proposed another digest"), jobs["a"].Error)
+
+	signers := sign(digestHex)
+	signers.Signers = []party.ID{"a", "b"}
+	jobs = runAll(map[party.ID]*stageRequest{"a": signers, "b": sign(digestHex), "c": sign(digestHex)})
+	for id, j := range jobs {
+		require.Equal(t, jobSucceeded, j.State, "%v: %s", id, j.Error)
+	}
+	assert.Nil(t, jobs["c"].Result.Signature, "c is not a signer")
+	signature := jobs["b"].Result.Signature
+	require.NotNil(t, signature)
+	assert.Equal(t, digestHex, signature.Digest)
+
+	verify := func(digestHex, signatureHex string) int {
+		return runVerify([]string{"--public-key", publicKey, "--digest", digestHex, "--signature", signatureHex})
+	}
+	assert.Equal(t, 0, verify(digestHex, signature.R+signature.S+hex.EncodeToString([]byte{signature.V})))
+	assert.Equal(t, 0, verify(digestHex, signature.R+signature.S), "without recovery ID")
+	assert.Equal(t, 1, verify(hex.EncodeToString(other[:]), signature.R+signature.S))
+}
@@ -0,0 +1,120 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Command recover reconstructs the full private key of an MPC wallet from the key stores of Threshold+1 parties,
+// and exports it. It must only be run offline, for disaster recovery.
+//
+//	$ export MPC_ECDSA_PASSPHRASE=<passphrase>
+//	$ go run ./cmd/recover -keystore ./a/keystore -keystore ./b/keystore -keystore ./c/keystore -key-id wallet1 -format wif
+//
+// All the key stores must be encrypted with the same passphrase, see MPC_ECDSA_NEW_PASSPHRASE.
+// The keystore format encrypts the key with the password in MPC_ECDSA_EXPORT_PASSWORD.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"MPC_ECDSA/internal/save"
+	"MPC_ECDSA/pkg/recovery"
+	"MPC_ECDSA/protocols/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// passphraseEnv is the environment variable holding the passphrase of the key stores.
+	passphraseEnv = "MPC_ECDSA_PASSPHRASE"
+	// exportPasswordEnv is the environment variable holding the password of an exported Ethereum keystore.
+	exportPasswordEnv = "MPC_ECDSA_EXPORT_PASSWORD"
+)
+
+// keyStoreDirs collects the repeated -keystore flags.
+type keyStoreDirs []string
+
+func (d *keyStoreDirs) String() string { return strings.Join(*d, ",") }
+
+func (d *keyStoreDirs) Set(dir string) error {
+	*d = append(*d, dir)
+	return nil
+}
+
+func main() {
+	var dirs keyStoreDirs
+	flag.Var(&dirs, "keystore", "key store directory of a party, repeated for each party")
+	keyID := flag.String("key-id", "", "ID of the key to reconstruct")
+	format := flag.String("format", "wif", "export format: wif, hex or keystore")
+	light := flag.Bool("light", false, "use light scrypt parameters for the keystore format")
+	out := flag.String("out", "", "file the exported key is written to, standard output if not set")
+	flag.Parse()
+
+	if err := run(dirs, *keyID, *format, *light, *out); err != nil {
+		log.Errorln(err)
+		os.Exit(1)
+	}
+}
+
+// run loads the shares of keyID from dirs, reconstructs the private key and writes it to out in the given format.
+func run(dirs []string, keyID, format string, light bool, out string) error {
+	if keyID == "" {
+		return errors.New("-key-id is required")
+	}
+	passphrase := []byte(os.Getenv(passphraseEnv))
+	configs := make([]*config.Config, 0, len(dirs))
+	for _, dir := range dirs {
+		store, err := save.NewFileKeyStore(dir, passphrase)
+		if err != nil {
+			return err
+		}
+		c, err := store.Get(keyID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+		log.Infof("loaded the share of %s from %s", c.ID, dir)
+		configs = append(configs, c)
+	}
+
+	secret, err := recovery.Reconstruct(configs)
+	if err != nil {
+		return err
+	}
+	log.Infoln("the shares are consistent and match the public key")
+
+	var exported []byte
+	switch format {
+	case "wif":
+		wif, err := recovery.WIF(secret)
+		if err != nil {
+			return err
+		}
+		exported = []byte(wif + "\n")
+	case "hex":
+		h, err := recovery.Hex(secret)
+		if err != nil {
+			return err
+		}
+		exported = []byte(h + "\n")
+	case "keystore":
+		params := recovery.StandardScrypt
+		if light {
+			params = recovery.LightScrypt
+		}
+		if exported, err = recovery.KeystoreV3(secret, []byte(os.Getenv(exportPasswordEnv)), params); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(exported)
+		return err
+	}
+	return os.WriteFile(out, exported, 0600)
+}
@@ -22,7 +22,7 @@ import (
 	"sync"
 	"time"
 
-	log "github.com/sirupsen/logrus"
+	"MPC_ECDSA/pkg/logging"
 )
 
 type ID string
@@ -56,8 +56,26 @@ type LocalConfig struct {
 	ServerKeyPath string `json:"serverKeyPath"`
 	//Represents the timeout duration in seconds for network operations.
 	TimeOutSecond int `json:"timeOutSecond"`
+	//Represents the maximum duration in seconds to wait for the messages of a protocol round, no limit if it is not set.
+	RoundTimeoutSecond int `json:"roundTimeoutSecond"`
+	//Represents the maximum size in bytes of a message, DefaultMaxMessageSize is used if it is not set.
+	MaxMessageSize int `json:"maxMessageSize"`
+	//Represents the directory where the key shares are stored, "./keystore" is used if it is not set.
+	KeyStoreDir string `json:"keyStoreDir"`
+	//Represents the number of available presignatures below which a warning asks to top up the presignature pool.
+	PresignPoolMinDepth int `json:"presignPoolMinDepth"`
 	//Represents the ID of the center server.
 	CenterServerID party.ID `json:"centerServerID"`
+	//Represents the hex encoded BIP-340 public key of the center server, which signs the session proposals.
+	CoordinatorPublicKey string `json:"coordinatorPublicKey"`
+	//Represents the stages this party accepts to execute, all of them if it is empty.
+	AllowedStages []string `json:"allowedStages"`
+	//Represents the number of seconds a session proposal of the center server is valid, 300 if it is empty.
+	ProposalTTLSecond int `json:"proposalTTLSecond"`
+	//Represents the path of the JSON or YAML signing rules evaluated before this party releases a signature share, no rules if it is empty.
+	PolicyPath string `json:"policyPath"`
+	//Represents the path of the tamper-evident record of the decisions on the signing requests, policy-record.jsonl in keyStoreDir if it is empty.
+	PolicyRecordPath string `json:"policyRecordPath"`
 
 	//Represents a list of all party IDs involved in the protocol.
 	PartyIDs []party.ID `json:"partyIDs"`
@@ -65,8 +83,17 @@ type LocalConfig struct {
 	// keygen Config
 	//Indicates whether mnemonic phrases are used for key generation.
 	UseMnemonic bool `json:"useMnemonic"`
+	//Represents the hex encoded compressed secp256k1 public key of the operator the mnemonic is encrypted to.
+	//The mnemonic is stored in plaintext with the key share if it is not set.
+	MnemonicRecipient string `json:"mnemonicRecipient"`
 	//Represents the threshold value used in the protocol.
 	Threshold int `json:"threshold"`
+	//Represents the ID of the party sharing an existing private key in KeyImport.
+	ImportDealer party.ID `json:"importDealer"`
+	//Represents the hex encoded compressed secp256k1 public key of the private key shared in KeyImport.
+	ImportPublicKey string `json:"importPublicKey"`
+	//Represents the hex encoded compressed secp256k1 public key of the key share recovered by the lost party in KeyRepair.
+	RepairPublicKey string `json:"repairPublicKey"`
 
 	// refresh config
 	// new threshold used in reshare, will replace the old threshold
@@ -89,6 +116,27 @@ type LocalConfig struct {
 	Signers []party.ID `json:"signers"`
 	//Represents the message that needs to be signed.
 	MessageToSign string `json:"messageToSign"`
+	//Represents the digest scheme applied to messageToSign before signing: raw, sha256, sha256d, keccak256, eip191 or eip712.
+	//A raw digest is given as 32 hex encoded bytes, sha256 is used if it is empty.
+	MessageDigest string `json:"messageDigest"`
+	//Represents the number of presignatures generated by a single presign execution, 1 if it is empty.
+	PresignCount int `json:"presignCount"`
+}
+
+// ConfigPaths are the paths of the config files of a party, an empty path selects the file of the same name in ./config.
+type ConfigPaths struct {
+	Conn    string
+	KeyGen  string
+	Refresh string
+	Sign    string
+}
+
+// orDefault returns path, or defaultPath if path is empty.
+func orDefault(path, defaultPath string) string {
+	if path == "" {
+		return defaultPath
+	}
+	return path
 }
 
 type LocalConn struct {
@@ -98,6 +146,19 @@ type LocalConn struct {
 	PreSign3      interface{}
 	PreSignRecord interface{}
 	PreSign6      interface{}
+
+	//the paths of the config files, loaded by the Load methods
+	Paths ConfigPaths
+	//Logger receives the log entries of the connections, logging.Default() if it is nil
+	Logger logging.Logger
+
+	//the frame codec of each connection in IDConnMap
+	codecs map[party.ID]*FrameCodec
+}
+
+// logger returns the logger of the connections, with the ID of the local party.
+func (connConf *LocalConn) logger() logging.Logger {
+	return logging.Or(connConf.Logger).WithField(logging.FieldParty, connConf.LocalConfig.LocalID)
 }
 
 // LoadCertPool function loads a certificate authority (CA) file and creates a new x509.CertPool
@@ -145,7 +206,8 @@ func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
 
 // The StartServer function is used to establish connections between parties.
 func (connConf *LocalConn) StartServer() error {
-	log.Infoln("start build connection between parties")
+	logger := connConf.logger()
+	logger.Infoln("start build connection between parties")
 	timeOut := connConf.LocalConfig.TimeOutSecond
 	//set a timeout for the context to ensure the function doesn't run indefinitely.
 	ctx, cancelCtx := context.WithTimeout(context.Background(), time.Duration(timeOut)*time.Second)
@@ -154,7 +216,7 @@ func (connConf *LocalConn) StartServer() error {
 	//load the TLS configuration
 	tlsConfig, err := LoadTLSConfig(connConf.LocalConfig.CaPath, connConf.LocalConfig.ServerCertPath, connConf.LocalConfig.ServerKeyPath)
 	if err != nil {
-		log.Errorln("fail load TLS config")
+		logger.Errorln("fail load TLS config")
 		return err
 	}
 
@@ -176,10 +238,11 @@ func (connConf *LocalConn) StartServer() error {
 		if parties[index].ConnRole == "server" {
 			// Start a goroutine to act as a client and handle sending
 			go func(index int, oID party.ID) {
-				log.Infof("begin dial and write %v", oID)
+				peerLogger := logger.WithField(logging.FieldPeer, oID)
+				peerLogger.Infoln("begin dial and write")
 				var conn net.Conn
 				for {
-					log.Infof("dial id = %v, addr = %v", oID, parties[index].Address)
+					peerLogger.Infof("dial %v", parties[index].Address)
 					conn, err = tls.Dial("tcp", parties[index].Address, tlsConfig)
 
 					if err == nil {
@@ -189,18 +252,19 @@ func (connConf *LocalConn) StartServer() error {
 				}
 				// Send its own ID to the other party
 				for {
-					log.Infof("writing local ID %v to %v", localID, oID)
-					_, err = conn.Write([]byte(localID))
+					peerLogger.Infoln("writing local ID")
+					//the ID is framed like any other message, so it cannot be merged with the first message
+					err = NewFrameCodec(conn, 0).WriteFrame(0, []byte(localID))
 					if err != nil {
-						log.Errorln("fail write local ID")
+						peerLogger.Errorln("fail write local ID")
 						panic(err)
 					}
-					log.Infof("successfully connect to %v", oID)
+					peerLogger.Infoln("successfully connect")
 					//add the connection to the connMap
 					connMap[oID] = conn
 					break
 				}
-				log.Infoln("done dial and write")
+				peerLogger.Infoln("done dial and write")
 
 			}(index, oID)
 
@@ -211,14 +275,13 @@ func (connConf *LocalConn) StartServer() error {
 	// If there's no fixed IP, this party cannot be a server, so it cannot listen
 	if connConf.LocalConfig.LocalCanBeServer {
 		go func(ctx context.Context) {
-			buf := make([]byte, 1024)
 			// Listen for connections
 			listener, err := tls.Listen("tcp", connConf.LocalConfig.LocalAddr, tlsConfig)
 			if err != nil {
-				log.Errorln("fail listen tcp")
+				logger.Errorln("fail listen tcp")
 			}
 
-			log.Infof("start listen %v\n", connConf.LocalConfig.LocalID)
+			logger.Infof("start listen on %v", connConf.LocalConfig.LocalAddr)
 			defer listener.Close()
 
 			var otherID party.ID
@@ -226,19 +289,18 @@ func (connConf *LocalConn) StartServer() error {
 				//accept a new connection
 				conn, err := listener.Accept()
 				if err != nil {
-					log.Errorln("fail read Accept")
+					logger.Errorln("fail read Accept")
 				}
-				log.Infoln("accept success")
+				logger.Infoln("accept success")
 				//read the other party's ID from the connection
-				n, err := conn.Read(buf)
+				_, idBytes, err := NewFrameCodec(conn, 0).ReadFrame()
 				if err != nil {
-					log.Errorln("fail read otherID ID")
+					logger.Errorln("fail read otherID ID")
 					panic(err)
 				}
-				//The ID is extracted from the received bytes.
-				otherID = party.ID(buf[:n])
+				otherID = party.ID(idBytes)
 
-				log.Infof("successfully connect to %v", otherID)
+				logger.WithField(logging.FieldPeer, otherID).Infoln("successfully connect")
 				//add the connection to the connMap
 				connMap[otherID] = conn
 			}
@@ -258,11 +320,16 @@ func (connConf *LocalConn) StartServer() error {
 
 	select {
 	case <-ch: //If a value is received from the ch channel, it means that all connections are set up successfully
-		log.Infof("parties set up  %v connections", otherPartyNum)
+		logger.Infof("parties set up %v connections", otherPartyNum)
 		connConf.IDConnMap = connMap
+		//every connection gets one codec, shared by all senders and receivers
+		connConf.codecs = make(map[party.ID]*FrameCodec, len(connMap))
+		for id, conn := range connMap {
+			connConf.codecs[id] = NewFrameCodec(conn, connConf.LocalConfig.MaxMessageSize)
+		}
 
 	case <-ctx.Done(): //If the ctx.Done() channel is closed, it means that the timeout specified in the context has elapsed.
-		log.Errorln("timeout")
+		logger.Errorln("timeout")
 		return err
 	}
 
@@ -271,22 +338,22 @@ func (connConf *LocalConn) StartServer() error {
 
 // LoadConnConfig method is responsible for loading the connection configuration
 func (connConf *LocalConn) LoadConnConfig() error {
-	jsonFile, err := os.Open("./config/connConfig.json")
+	jsonFile, err := os.Open(orDefault(connConf.Paths.Conn, "./config/connConfig.json"))
 	if err != nil {
-		log.Errorln("fail open connConfig.json")
+		connConf.logger().Errorln("fail open connConfig.json")
 		return err
 	}
-	log.Infoln("successfully open connConfig.json")
+	connConf.logger().Infoln("successfully open connConfig.json")
 	defer jsonFile.Close()
 
 	// Read the contents of the file
 	byteValue, _ := io.ReadAll(jsonFile)
 	err = json.Unmarshal(byteValue, &connConf.LocalConfig)
 	if err != nil {
-		log.Errorln("fail unmarshal connConfig.json")
+		connConf.logger().Errorln("fail unmarshal connConfig.json")
 		return err
 	}
-	log.Infoln("done unmarshal connConfig")
+	connConf.logger().Infoln("done unmarshal connConfig")
 	return nil
 }
 
@@ -294,31 +361,31 @@ func (connConf *LocalConn) LoadConnConfig() error {
 func (connConf *LocalConn) LoadConnConfigReshare() error {
 	jsonFile, err := os.Open("./config/connConfigReshare.json")
 	if err != nil {
-		log.Errorln("fail open connConfigReshare.json")
+		connConf.logger().Errorln("fail open connConfigReshare.json")
 		return err
 	}
-	log.Infoln("successfully open connConfigReshare.json")
+	connConf.logger().Infoln("successfully open connConfigReshare.json")
 	defer jsonFile.Close()
 
 	// Read the contents of the file
 	byteValue, _ := io.ReadAll(jsonFile)
 	err = json.Unmarshal(byteValue, &connConf.LocalConfig)
 	if err != nil {
-		log.Errorln("fail unmarshal connConfig.json")
+		connConf.logger().Errorln("fail unmarshal connConfig.json")
 		return err
 	}
-	log.Infoln("done unmarshal connConfig")
+	connConf.logger().Infoln("done unmarshal connConfig")
 	return nil
 }
 
 // LoadKeyGenConfig method is responsible for loading the keygen configuration
 func (connConf *LocalConn) LoadKeyGenConfig() error {
-	jsonFile, err := os.Open("./config/keygenConfig.json")
+	jsonFile, err := os.Open(orDefault(connConf.Paths.KeyGen, "./config/keygenConfig.json"))
 	if err != nil {
-		log.Errorln("fail open keygenConfig.json")
+		connConf.logger().Errorln("fail open keygenConfig.json")
 		return err
 	}
-	log.Infoln("successfully open keygenConfig.json")
+	connConf.logger().Infoln("successfully open keygenConfig.json")
 	defer jsonFile.Close()
 	// Read the contents of the file
 	byteValue, _ := io.ReadAll(jsonFile)
@@ -327,23 +394,27 @@ func (connConf *LocalConn) LoadKeyGenConfig() error {
 	tmpConf := &LocalConfig{}
 	err = json.Unmarshal(byteValue, tmpConf)
 	if err != nil {
-		log.Errorln("fail unmarshal keygenConfig.json")
+		connConf.logger().Errorln("fail unmarshal keygenConfig.json")
 		return err
 	}
 	connConf.LocalConfig.Threshold = tmpConf.Threshold
 	connConf.LocalConfig.UseMnemonic = tmpConf.UseMnemonic
-	log.Infoln("done unmarshal keygenConfig and add new config item to localconn")
+	connConf.LocalConfig.MnemonicRecipient = tmpConf.MnemonicRecipient
+	connConf.LocalConfig.ImportDealer = tmpConf.ImportDealer
+	connConf.LocalConfig.ImportPublicKey = tmpConf.ImportPublicKey
+	connConf.LocalConfig.RepairPublicKey = tmpConf.RepairPublicKey
+	connConf.logger().Infoln("done unmarshal keygenConfig and add new config item to localconn")
 	return nil
 }
 
 // LoadRefreshConfig method is responsible for loading the refresh configuration
 func (connConf *LocalConn) LoadRefreshConfig() error {
-	jsonFile, err := os.Open("./config/refreshConfig.json")
+	jsonFile, err := os.Open(orDefault(connConf.Paths.Refresh, "./config/refreshConfig.json"))
 	if err != nil {
-		log.Errorln("fail open refreshConfig.json")
+		connConf.logger().Errorln("fail open refreshConfig.json")
 		return err
 	}
-	log.Infoln("successfully open refreshConfig.json")
+	connConf.logger().Infoln("successfully open refreshConfig.json")
 	defer jsonFile.Close()
 	// Read the contents of the file
 	byteValue, _ := io.ReadAll(jsonFile)
@@ -352,7 +423,7 @@ func (connConf *LocalConn) LoadRefreshConfig() error {
 	tmpConf := &LocalConfig{}
 	err = json.Unmarshal(byteValue, tmpConf)
 	if err != nil {
-		log.Errorln("fail unmarshal refreshConfig.json")
+		connConf.logger().Errorln("fail unmarshal refreshConfig.json")
 		return err
 	}
 	// update the local config
@@ -364,18 +435,18 @@ func (connConf *LocalConn) LoadRefreshConfig() error {
 	connConf.LocalConfig.IsOldCommittee = tmpConf.IsOldCommittee
 	connConf.LocalConfig.IsNewCommittee = tmpConf.IsNewCommittee
 
-	log.Infoln("done unmarshal refreshConfig and add new config item to localconn")
+	connConf.logger().Infoln("done unmarshal refreshConfig and add new config item to localconn")
 	return nil
 }
 
 // LoadConfig method is responsible for loading the configuration
 func (connConf *LocalConn) LoadSignConfig() error {
-	jsonFile, err := os.Open("./config/signConfig.json")
+	jsonFile, err := os.Open(orDefault(connConf.Paths.Sign, "./config/signConfig.json"))
 	if err != nil {
-		log.Errorln("fail open signConfig.json")
+		connConf.logger().Errorln("fail open signConfig.json")
 		return err
 	}
-	log.Infoln("successfully open signConfig.json")
+	connConf.logger().Infoln("successfully open signConfig.json")
 	defer jsonFile.Close()
 	// Read the contents of the file
 	byteValue, _ := io.ReadAll(jsonFile)
@@ -384,12 +455,14 @@ func (connConf *LocalConn) LoadSignConfig() error {
 	tmpConf := &LocalConfig{}
 	err = json.Unmarshal(byteValue, tmpConf)
 	if err != nil {
-		log.Errorln("fail unmarshal signConfig.json")
+		connConf.logger().Errorln("fail unmarshal signConfig.json")
 		return err
 	}
 	connConf.LocalConfig.Signers = tmpConf.Signers
 	connConf.LocalConfig.MessageToSign = tmpConf.MessageToSign
-	log.Infoln("done unmarshal signConfig and add new config item to localconn")
+	connConf.LocalConfig.MessageDigest = tmpConf.MessageDigest
+	connConf.LocalConfig.PresignCount = tmpConf.PresignCount
+	connConf.logger().Infoln("done unmarshal signConfig and add new config item to localconn")
 	return nil
 }
 
@@ -417,15 +490,28 @@ func SetUpConnReshare() LocalConn {
 
 }
 
+// codec returns the frame codec of the connection to the given party.
+// If IDConnMap was filled without StartServer, a codec is created for the call.
+func (connConf *LocalConn) codec(id party.ID) (*FrameCodec, error) {
+	if c, ok := connConf.codecs[id]; ok {
+		return c, nil
+	}
+	conn, ok := connConf.IDConnMap[id]
+	if !ok {
+		return nil, fmt.Errorf("communication: no connection to party %v", id)
+	}
+	return NewFrameCodec(conn, connConf.LocalConfig.MaxMessageSize), nil
+}
+
 // P2pSend function is used to send a message to a specific party in a point-to-point manner
 func (connConf *LocalConn) P2pSend(toPartyID party.ID, message []byte) error {
-	// Prepend the size of the message to the message data
-	msgSize := IntToBytes(len(message))
-	message = append(msgSize, message...)
-	//Write the message to the connection associated with the specified party ID
-	_, err := connConf.IDConnMap[toPartyID].Write(message)
+	c, err := connConf.codec(toPartyID)
 	if err != nil {
-		log.Errorf("fail send messsage to %v", toPartyID)
+		return err
+	}
+	//Write the message as a single frame to the connection associated with the specified party ID
+	if err = c.WriteFrame(0, message); err != nil {
+		connConf.logger().WithField(logging.FieldPeer, toPartyID).Errorf("fail send message: %v", err)
 		return err
 	}
 	return nil
@@ -433,43 +519,18 @@ func (connConf *LocalConn) P2pSend(toPartyID party.ID, message []byte) error {
 
 // P2pReceive function receives a message from a specific party in a point-to-point manner.
 func (connConf *LocalConn) P2pReceive(fromPartyID party.ID) ([]byte, error) {
-
-	buf := make([]byte, 1e6)
-	conn := connConf.IDConnMap[fromPartyID]
-	size := -1
-	tmpSize := 0
-	n, err := conn.Read(buf)
-
-	var tmpMsgByte []byte
-	if err != nil || n <= 4 {
-		log.Errorln("fail read message")
+	c, err := connConf.codec(fromPartyID)
+	if err != nil {
 		return nil, err
 	}
-	//extract the size of the message from the first 4 bytes of the buffer.
-	sizeByte := buf[:4]
-	size = BytesToInt(sizeByte)
-	// initialize tmpMsgByte to store the received message data, excluding the size bytes.
-	tmpMsgByte = buf[4:n]
-	//update tmpSize to indicate the current size of the received data.
-	tmpSize = n - 4
-	//enter a loop to read additional data from the connection until the size of the received data reaches the expected size
-	for tmpSize < size {
-		//read data into a new buffer buf and appends it to tmpMsgByte
-		buf := make([]byte, 1e6)
-		n, err := conn.Read(buf)
-		if err != nil {
-			log.Errorln(err)
-		}
-		tmpMsgByte = append(tmpMsgByte, buf[:n]...)
-		tmpSize += n
-	}
-
+	//read exactly one frame, the bytes of the next frame stay in the connection
+	_, message, err := c.ReadFrame()
 	if err != nil {
-		log.Errorf("fail receive messsage from %v", fromPartyID)
+		connConf.logger().WithField(logging.FieldPeer, fromPartyID).Errorf("fail receive message: %v", err)
 		return nil, err
 	}
-	log.Infof("receive from party %v message len is %v \n", fromPartyID, size)
-	return tmpMsgByte, nil
+	connConf.logger().WithField(logging.FieldPeer, fromPartyID).WithFields(logging.Payload(message)).Debugln("received message")
+	return message, nil
 }
 
 // BroadcastSend function sends a message to each participant individually in a broadcast manner
@@ -479,10 +540,10 @@ func (connConf *LocalConn) BroadcastSend(message []byte) error {
 			//call the P2pSend function to send the message to the specified party.
 			err := connConf.P2pSend(id, message)
 			if err != nil {
-				log.Errorf("fail broadcast messsage to %v", id)
+				connConf.logger().WithField(logging.FieldPeer, id).Errorln("fail broadcast message")
 				return
 			}
-			log.Infof("send to party %v message len is %v\n", id, len(message))
+			connConf.logger().WithField(logging.FieldPeer, id).WithFields(logging.Payload(message)).Debugln("sent message")
 		}(id)
 
 	}
@@ -508,92 +569,9 @@ func (connConf *LocalConn) BroadcastReceive() (map[party.ID][]byte, error) {
 			//call the P2pReceive function to receive the message from the specified party.
 			receiveMsg, err := connConf.P2pReceive(fromPartyID)
 			if err != nil {
-				log.Errorf("receive from %v errror", fromPartyID)
-				return
-			}
-			log.Infof("receive from %v msg", fromPartyID)
-			//If the message is successfully received, it locks the mutex,
-			mutex.Lock()
-			//update the msgMap with the received message,
-			(*msgMap)[fromPartyID] = receiveMsg
-			// unlock the mutex.
-			mutex.Unlock()
-		}(fromPartyID, &msgMap)
-
-	}
-	wg.Wait()
-
-	log.Infoln("received all broadcast messages")
-	return msgMap, nil
-}
-
-// BroadcastReceive function receives a message from other new parties in a broadcast manner.
-func (connConf *LocalConn) BroadcastReceiveFromNewparty() (map[party.ID][]byte, error) {
-	// create a msgMap to store the received messages from other parties
-	msgMap := make(map[party.ID][]byte, connConf.LocalConfig.NewPartyCount-1)
-
-	//initialize a mutex to control concurrent writes to the msgMap.
-	var mutex sync.Mutex
-
-	//create a WaitGroup wg to control the execution of the message receiving goroutines.
-	//The number of goroutines is equal to the total number of other parties
-	wg := sync.WaitGroup{}
-	wg.Add(connConf.LocalConfig.NewPartyCount - 1)
-
-	for _, fromPartyID := range connConf.LocalConfig.NewPartyIDs {
-		if fromPartyID == connConf.LocalConfig.LocalID {
-			continue
-		}
-		go func(fromPartyID party.ID, msgMap *map[party.ID][]byte) {
-			defer wg.Done()
-			//call the P2pReceive function to receive the message from the specified party.
-			receiveMsg, err := connConf.P2pReceive(fromPartyID)
-			if err != nil {
-				log.Errorf("receive from %v errror", fromPartyID)
-				return
-			}
-			log.Infof("receive from %v msg", fromPartyID)
-			//If the message is successfully received, it locks the mutex,
-			mutex.Lock()
-			//update the msgMap with the received message,
-			(*msgMap)[fromPartyID] = receiveMsg
-			// unlock the mutex.
-			mutex.Unlock()
-		}(fromPartyID, &msgMap)
-
-	}
-	wg.Wait()
-
-	log.Infoln("received all broadcast messages from new parties")
-	return msgMap, nil
-}
-
-// BroadcastReceive function receives a message from old parties in a broadcast manner.
-func (connConf *LocalConn) BroadcastReceiveFromOldparty() (map[party.ID][]byte, error) {
-	// create a msgMap to store the received messages from other parties
-	msgMap := make(map[party.ID][]byte, connConf.LocalConfig.OldPartyCount-1)
-
-	//initialize a mutex to control concurrent writes to the msgMap.
-	var mutex sync.Mutex
-
-	//create a WaitGroup wg to control the execution of the message receiving goroutines.
-	//The number of goroutines is equal to the total number of other parties
-	wg := sync.WaitGroup{}
-	wg.Add(connConf.LocalConfig.OldPartyCount)
-
-	for _, fromPartyID := range connConf.LocalConfig.OldPartyIDs {
-		if fromPartyID == connConf.LocalConfig.LocalID {
-			continue
-		}
-		go func(fromPartyID party.ID, msgMap *map[party.ID][]byte) {
-			defer wg.Done()
-			//call the P2pReceive function to receive the message from the specified party.
-			receiveMsg, err := connConf.P2pReceive(fromPartyID)
-			if err != nil {
-				log.Errorf("receive from %v errror", fromPartyID)
+				connConf.logger().WithField(logging.FieldPeer, fromPartyID).Errorln("fail receive broadcast message")
 				return
 			}
-			log.Infof("receive from %v msg", fromPartyID)
 			//If the message is successfully received, it locks the mutex,
 			mutex.Lock()
 			//update the msgMap with the received message,
@@ -605,7 +583,7 @@ func (connConf *LocalConn) BroadcastReceiveFromOldparty() (map[party.ID][]byte,
 	}
 	wg.Wait()
 
-	log.Infoln("received all broadcast messages from old parties")
+	connConf.logger().Debugln("received all broadcast messages")
 	return msgMap, nil
 }
 
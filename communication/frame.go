@@ -0,0 +1,150 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package communication
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	// FrameMagic marks the beginning of every frame.
+	FrameMagic uint16 = 0x4d50 // "MP"
+	// FrameVersion is the version of the frame header written by this package.
+	FrameVersion uint8 = 1
+	// FrameHeaderSize is the size of the frame header: magic (2), version (1), flags (1), length (4).
+	FrameHeaderSize = 8
+	// DefaultMaxMessageSize is the maximum payload size used when none is configured.
+	DefaultMaxMessageSize = 64 << 20
+)
+
+var (
+	// ErrBadMagic is returned when a frame does not start with FrameMagic, the stream cannot be read any further.
+	ErrBadMagic = errors.New("communication: bad frame magic")
+	// ErrUnsupportedVersion is returned when a frame header has a version this package does not understand.
+	ErrUnsupportedVersion = errors.New("communication: unsupported frame version")
+)
+
+// FrameTooLargeError is returned when a frame exceeds the maximum message size.
+// When reading, the payload is discarded so that the next frame can still be read.
+type FrameTooLargeError struct {
+	Size int
+	Max  int
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("communication: frame of %d bytes exceeds the maximum of %d bytes", e.Size, e.Max)
+}
+
+// TruncatedFrameError is returned when the stream ends in the middle of a frame.
+type TruncatedFrameError struct {
+	// Want is the number of bytes of the header or payload being read
+	Want int
+	// Got is the number of bytes read before the stream ended
+	Got int
+}
+
+func (e *TruncatedFrameError) Error() string {
+	return fmt.Sprintf("communication: truncated frame, got %d of %d bytes", e.Got, e.Want)
+}
+
+// FrameCodec reads and writes length-prefixed frames over a stream.
+// One goroutine may write while another one reads, concurrent writes never interleave.
+type FrameCodec struct {
+	rw      io.ReadWriter
+	maxSize int
+
+	readMtx  sync.Mutex
+	writeMtx sync.Mutex
+}
+
+// NewFrameCodec returns a FrameCodec over rw.
+// Frames whose payload is larger than maxSize are rejected, if maxSize is not positive DefaultMaxMessageSize is used.
+func NewFrameCodec(rw io.ReadWriter, maxSize int) *FrameCodec {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+	return &FrameCodec{
+		rw:      rw,
+		maxSize: maxSize,
+	}
+}
+
+// WriteFrame writes payload as a single frame with the given flags.
+func (c *FrameCodec) WriteFrame(flags uint8, payload []byte) error {
+	if len(payload) > c.maxSize {
+		return &FrameTooLargeError{Size: len(payload), Max: c.maxSize}
+	}
+	//header and payload are written in one call, so that a frame is never split by another writer
+	frame := make([]byte, FrameHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(frame[0:2], FrameMagic)
+	frame[2] = FrameVersion
+	frame[3] = flags
+	binary.BigEndian.PutUint32(frame[4:8], uint32(len(payload)))
+	copy(frame[FrameHeaderSize:], payload)
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	_, err := c.rw.Write(frame)
+	return err
+}
+
+// ReadFrame reads the next frame, and returns its flags and payload.
+// io.EOF is returned if the stream ended cleanly between two frames.
+func (c *FrameCodec) ReadFrame() (uint8, []byte, error) {
+	c.readMtx.Lock()
+	defer c.readMtx.Unlock()
+
+	header := make([]byte, FrameHeaderSize)
+	if err := readFull(c.rw, header, false); err != nil {
+		return 0, nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:2]) != FrameMagic {
+		return 0, nil, ErrBadMagic
+	}
+	if header[2] != FrameVersion {
+		return 0, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, header[2])
+	}
+	flags := header[3]
+	size := int64(binary.BigEndian.Uint32(header[4:8]))
+
+	if size > int64(c.maxSize) {
+		//skip the payload, the following frames stay readable
+		n, err := io.CopyN(io.Discard, c.rw, size)
+		if err != nil {
+			return 0, nil, truncated(int(size), int(n), err, true)
+		}
+		return 0, nil, &FrameTooLargeError{Size: int(size), Max: c.maxSize}
+	}
+
+	payload := make([]byte, size)
+	if err := readFull(c.rw, payload, true); err != nil {
+		return 0, nil, err
+	}
+	return flags, payload, nil
+}
+
+// readFull fills buf from r.
+// If the stream ends, a TruncatedFrameError is returned, unless nothing was read yet and the frame has not started.
+func readFull(r io.Reader, buf []byte, started bool) error {
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		return truncated(len(buf), n, err, started)
+	}
+	return nil
+}
+
+// truncated converts the end of the stream inside a frame into a TruncatedFrameError, other errors are returned as is.
+func truncated(want, got int, err error, started bool) error {
+	if errors.Is(err, io.ErrUnexpectedEOF) || (err == io.EOF && (started || got > 0)) {
+		return &TruncatedFrameError{Want: want, Got: got}
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package communication
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFrameCodecCoalesced checks that frames written back to back are read one by one.
+func TestFrameCodecCoalesced(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewFrameCodec(&buf, 0)
+	require.NoError(t, c.WriteFrame(0, []byte("first")))
+	require.NoError(t, c.WriteFrame(1, []byte{}))
+	require.NoError(t, c.WriteFrame(0, []byte("third")))
+
+	for _, expected := range []struct {
+		flags   uint8
+		payload []byte
+	}{{0, []byte("first")}, {1, []byte{}}, {0, []byte("third")}} {
+		flags, payload, err := c.ReadFrame()
+		require.NoError(t, err)
+		assert.Equal(t, expected.flags, flags)
+		assert.Equal(t, expected.payload, payload)
+	}
+	_, _, err := c.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
+
+// TestFrameCodecErrors checks the errors returned for malformed streams.
+func TestFrameCodecErrors(t *testing.T) {
+	t.Run("too large", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewFrameCodec(&buf, 0).WriteFrame(0, make([]byte, 16)))
+		require.NoError(t, NewFrameCodec(&buf, 0).WriteFrame(0, []byte("next")))
+
+		c := NewFrameCodec(&buf, 8)
+		_, _, err := c.ReadFrame()
+		var tooLarge *FrameTooLargeError
+		require.True(t, errors.As(err, &tooLarge))
+		assert.Equal(t, 16, tooLarge.Size)
+		// the oversized payload was skipped
+		_, payload, err := c.ReadFrame()
+		require.NoError(t, err)
+		assert.Equal(t, []byte("next"), payload)
+
+		err = c.WriteFrame(0, make([]byte, 9))
+		assert.True(t, errors.As(err, &tooLarge))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewFrameCodec(&buf, 0).WriteFrame(0, []byte("payload")))
+		for _, n := range []int{3, FrameHeaderSize, FrameHeaderSize + 3} {
+			c := NewFrameCodec(bytes.NewBuffer(append([]byte(nil), buf.Bytes()[:n]...)), 0)
+			_, _, err := c.ReadFrame()
+			var truncated *TruncatedFrameError
+			assert.True(t, errors.As(err, &truncated), "%d bytes: %v", n, err)
+		}
+	})
+
+	t.Run("bad header", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewFrameCodec(&buf, 0).WriteFrame(0, []byte("payload")))
+		data := buf.Bytes()
+
+		badMagic := append([]byte(nil), data...)
+		badMagic[0] ^= 0xff
+		_, _, err := NewFrameCodec(bytes.NewBuffer(badMagic), 0).ReadFrame()
+		assert.ErrorIs(t, err, ErrBadMagic)
+
+		badVersion := append([]byte(nil), data...)
+		badVersion[2] = FrameVersion + 1
+		_, _, err = NewFrameCodec(bytes.NewBuffer(badVersion), 0).ReadFrame()
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+}
+
+// TestFrameCodecConcurrent sends from several goroutines while another one receives on the same connection.
+func TestFrameCodecConcurrent(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	sender := NewFrameCodec(a, 0)
+	receiver := NewFrameCodec(b, 0)
+
+	senders, perSender := 4, 50
+	wg := sync.WaitGroup{}
+	wg.Add(senders)
+	for i := 0; i < senders; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perSender; j++ {
+				assert.NoError(t, sender.WriteFrame(0, []byte(fmt.Sprintf("%d-%d", i, j))))
+			}
+		}(i)
+	}
+
+	next := make([]int, senders)
+	for k := 0; k < senders*perSender; k++ {
+		_, payload, err := receiver.ReadFrame()
+		require.NoError(t, err)
+		var i, j int
+		_, err = fmt.Sscanf(string(payload), "%d-%d", &i, &j)
+		require.NoError(t, err)
+		// frames of one sender arrive whole and in order
+		assert.Equal(t, next[i], j)
+		next[i]++
+	}
+	wg.Wait()
+}
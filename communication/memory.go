@@ -0,0 +1,133 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package communication
+
+import (
+	"MPC_ECDSA/pkg/party"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MemoryNetwork connects a set of parties running in the same process using Go channels.
+// Every ordered pair of parties gets its own queue, so messages between two parties keep their order,
+// exactly like a connection of the TLS mesh.
+type MemoryNetwork struct {
+	parties party.IDSlice
+	// queues[to][from] holds the messages sent by from to to
+	queues map[party.ID]map[party.ID]*memoryQueue
+}
+
+// memoryQueue is an unbounded FIFO, so that a sender never blocks on a receiver
+// which is still busy finalizing the previous round.
+type memoryQueue struct {
+	mtx    sync.Mutex
+	items  [][]byte
+	notify chan struct{}
+}
+
+// MemoryTransport is the Transport of a single party of a MemoryNetwork.
+type MemoryTransport struct {
+	network *MemoryNetwork
+	selfID  party.ID
+}
+
+// NewMemoryNetwork creates an in-process network between the given parties.
+func NewMemoryNetwork(parties []party.ID) *MemoryNetwork {
+	ids := party.NewIDSlice(parties)
+	queues := make(map[party.ID]map[party.ID]*memoryQueue, len(ids))
+	for _, to := range ids {
+		queues[to] = make(map[party.ID]*memoryQueue, len(ids)-1)
+		for _, from := range ids {
+			if from == to {
+				continue
+			}
+			queues[to][from] = &memoryQueue{notify: make(chan struct{}, 1)}
+		}
+	}
+	return &MemoryNetwork{
+		parties: ids,
+		queues:  queues,
+	}
+}
+
+// Transport returns the Transport used by the party id.
+func (n *MemoryNetwork) Transport(id party.ID) *MemoryTransport {
+	return &MemoryTransport{
+		network: n,
+		selfID:  id,
+	}
+}
+
+// Send implements Transport.
+func (t *MemoryTransport) Send(ctx context.Context, to party.ID, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q, ok := t.network.queues[to][t.selfID]
+	if !ok {
+		return fmt.Errorf("communication: party %v is not part of the network", to)
+	}
+	// copy the data, the caller is free to reuse its buffer
+	q.push(append([]byte(nil), data...))
+	return nil
+}
+
+// Broadcast implements Transport.
+func (t *MemoryTransport) Broadcast(ctx context.Context, data []byte) error {
+	for _, id := range t.network.parties {
+		if id == t.selfID {
+			continue
+		}
+		if err := t.Send(ctx, id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive implements Transport.
+func (t *MemoryTransport) Receive(ctx context.Context, from party.ID) ([]byte, error) {
+	q, ok := t.network.queues[t.selfID][from]
+	if !ok {
+		return nil, fmt.Errorf("communication: party %v is not part of the network", from)
+	}
+	return q.pop(ctx)
+}
+
+// push appends data to the queue and wakes up a pending pop.
+func (q *memoryQueue) push(data []byte) {
+	q.mtx.Lock()
+	q.items = append(q.items, data)
+	q.mtx.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop blocks until the queue is not empty, or ctx is done.
+func (q *memoryQueue) pop(ctx context.Context) ([]byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		q.mtx.Lock()
+		if len(q.items) > 0 {
+			data := q.items[0]
+			q.items = q.items[1:]
+			q.mtx.Unlock()
+			return data, nil
+		}
+		q.mtx.Unlock()
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package communication
+
+import (
+	"MPC_ECDSA/pkg/party"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Transport is the abstraction the protocol handlers use to exchange messages with the other parties.
+// Messages between two parties must be delivered reliably and in order.
+// The TLS mesh set up by LocalConn is one implementation, MemoryNetwork provides an in-process one.
+type Transport interface {
+	// Send delivers a message to a single party.
+	Send(ctx context.Context, to party.ID, data []byte) error
+	// Broadcast delivers the same message to every other party known to the transport.
+	Broadcast(ctx context.Context, data []byte) error
+	// Receive blocks until the next message from the given party is available.
+	Receive(ctx context.Context, from party.ID) ([]byte, error)
+}
+
+// ReceiveAll receives exactly one message from each of the given parties, reading from every party concurrently.
+// If one of the receptions fails, the first error is returned.
+func ReceiveAll(ctx context.Context, t Transport, from []party.ID) (map[party.ID][]byte, error) {
+	// create a msgMap to store the received messages from other parties
+	msgMap := make(map[party.ID][]byte, len(from))
+	//initialize a mutex to control concurrent writes to the msgMap and firstErr.
+	var mutex sync.Mutex
+	var firstErr error
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(from))
+	for _, fromPartyID := range from {
+		go func(fromPartyID party.ID) {
+			defer wg.Done()
+			receiveMsg, err := t.Receive(ctx, fromPartyID)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			msgMap[fromPartyID] = receiveMsg
+		}(fromPartyID)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return msgMap, nil
+}
+
+// Send implements Transport over the TLS connection to the given party.
+func (connConf *LocalConn) Send(ctx context.Context, to party.ID, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return connConf.P2pSend(to, data)
+}
+
+// Broadcast implements Transport by sending the message over every established connection,
+// and waits until all of them have been written.
+func (connConf *LocalConn) Broadcast(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var mutex sync.Mutex
+	var firstErr error
+	wg := sync.WaitGroup{}
+	wg.Add(len(connConf.IDConnMap))
+	for id := range connConf.IDConnMap {
+		go func(id party.ID) {
+			defer wg.Done()
+			if err := connConf.P2pSend(id, data); err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mutex.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Receive implements Transport by reading the next message from the TLS connection to the given party.
+// If ctx is done before a message arrives, the pending read is interrupted and ctx.Err() is returned.
+// A frame interrupted in the middle is lost, so the connection should not be used for the same session afterwards.
+func (connConf *LocalConn) Receive(ctx context.Context, from party.ID) ([]byte, error) {
+	conn, ok := connConf.IDConnMap[from]
+	if !ok {
+		return nil, fmt.Errorf("communication: no connection to party %v", from)
+	}
+	type result struct {
+		data []byte
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		data, err := connConf.P2pReceive(from)
+		resultCh <- result{data, err}
+	}()
+	select {
+	case res := <-resultCh:
+		return res.data, res.err
+	case <-ctx.Done():
+		// unblock the pending read, the deadline is cleared so that later sessions can read again
+		_ = conn.SetReadDeadline(time.Now())
+		<-resultCh
+		_ = conn.SetReadDeadline(time.Time{})
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,568 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package main
+
+import (
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/internal/save"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// jobState is the state of a job of the daemon.
+type jobState string
+
+const (
+	// jobQueued jobs wait for the center server to propose them
+	jobQueued jobState = "queued"
+	// jobRunning jobs were accepted by all the parties, and their protocol is executing
+	jobRunning jobState = "running"
+	// jobSucceeded jobs have a result
+	jobSucceeded jobState = "succeeded"
+	// jobFailed jobs have an error
+	jobFailed jobState = "failed"
+	// jobRejected jobs were not accepted by all the parties, no protocol was executed
+	jobRejected jobState = "rejected"
+)
+
+// job is an execution of a stage by the daemon, tracked through the API.
+type job struct {
+	ID    string `json:"id,omitempty"`
+	Stage string `json:"stage"`
+	KeyID string `json:"keyID,omitempty"`
+	// SessionID is the hex encoded session ID of the proposal, shared by the jobs of all the parties
+	SessionID string   `json:"sessionID,omitempty"`
+	State     jobState `json:"state"`
+	Error     string   `json:"error,omitempty"`
+	// Culprits are the parties blamed by the protocol when it aborts
+	Culprits []party.ID `json:"culprits,omitempty"`
+	// Abort is the report of the aborted protocol execution, with the messages of the culprits
+	Abort   *protocol.AbortReport `json:"abort,omitempty"`
+	Result  *stageResult          `json:"result,omitempty"`
+	Created time.Time             `json:"created"`
+	Updated time.Time             `json:"updated"`
+
+	// request is the stage proposed by the center server
+	request *stageRequest
+}
+
+// daemonJobQueue is the number of jobs the center server accepts before they are proposed.
+const daemonJobQueue = 64
+
+// daemon keeps the connections to the other parties open, and executes the stages requested through a local HTTP API.
+//
+// The center server proposes the stages posted to its API one at a time, the other parties review the proposals
+// in the background. Every party tracks its executions as jobs, which any local client can query.
+type daemon struct {
+	localConn *communication.LocalConn
+	mux       *protocol.Mux
+	control   *protocol.MuxSession
+	store     save.KeyStore
+	presigns  *save.PresignPool
+
+	mtx  sync.Mutex
+	jobs map[string]*job
+	// order lists the IDs of the jobs by creation
+	order []string
+	// queue holds the jobs of the center server until they are proposed
+	queue chan *job
+	// token is the bearer token of the API, which accepts any local client if it is empty
+	token []byte
+}
+
+// newDaemon returns a daemon whose messages go through transport, the connections of localConn or an in-process network.
+func newDaemon(localConn *communication.LocalConn, transport communication.Transport, store save.KeyStore, presigns *save.PresignPool) (*daemon, error) {
+	others := localConn.LocalConfig.OtherPartyIDs
+	mux := protocol.NewMux(transport, others, protocol.DefaultMaxPending, protocol.DefaultPendingTTL)
+	//The instructions of the center server have their own session
+	control, err := mux.Open(controlProtocolID, nil, others)
+	if err != nil {
+		mux.Close()
+		return nil, err
+	}
+	return &daemon{
+		localConn: localConn,
+		mux:       mux,
+		control:   control,
+		store:     store,
+		presigns:  presigns,
+		jobs:      make(map[string]*job),
+		queue:     make(chan *job, daemonJobQueue),
+	}, nil
+}
+
+// Close closes the mux of the daemon, which stops run.
+func (d *daemon) Close() {
+	d.mux.Close()
+}
+
+// isCenter returns true if the daemon runs the center server, which proposes the stages.
+func (d *daemon) isCenter() bool {
+	return d.localConn.LocalConfig.CenterServerID == d.localConn.LocalConfig.LocalID
+}
+
+// run executes the jobs until ctx is done or the mux is closed: the center server proposes the queued jobs,
+// the other parties review the proposals of the center server.
+func (d *daemon) run(ctx context.Context) error {
+	if d.isCenter() {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case j := <-d.queue:
+				d.proposeJob(j)
+			}
+		}
+	}
+	for {
+		p, err := review(d.localConn, d.control)
+		if errors.Is(err, errRejected) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		j, err := d.newJob(p.Protocol, p.KeyID, nil)
+		if err != nil {
+			return err
+		}
+		d.update(j, func(j *job) {
+			j.SessionID = hex.EncodeToString(p.SessionID)
+			j.State = jobRunning
+		})
+		d.executeJob(j, func(pl *pool.Pool) (*stageResult, error) {
+			return stepIntoStage(d.localConn, p, d.mux, d.store, d.presigns, pl)
+		})
+	}
+}
+
+// proposeJob is called by the center server: it proposes the stage of the job, and executes it once accepted.
+func (d *daemon) proposeJob(j *job) {
+	p, err := propose(d.localConn, d.control, d.presigns, j.request)
+	if err != nil {
+		d.update(j, func(j *job) {
+			j.State, j.Error = jobFailed, err.Error()
+			if errors.Is(err, errRejected) {
+				j.State = jobRejected
+			}
+		})
+		return
+	}
+	d.update(j, func(j *job) {
+		j.SessionID = hex.EncodeToString(p.SessionID)
+		j.State = jobRunning
+	})
+	d.executeJob(j, func(pl *pool.Pool) (*stageResult, error) {
+		return stepIntoStage(d.localConn, p, d.mux, d.store, d.presigns, pl)
+	})
+}
+
+// executeJob runs the stage of the job with a new pool, and records its outcome.
+func (d *daemon) executeJob(j *job, stage func(pl *pool.Pool) (*stageResult, error)) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	result, err := stage(pl)
+	d.update(j, func(j *job) {
+		if err != nil {
+			j.State, j.Error, j.Culprits, j.Abort = jobFailed, err.Error(), culprits(err), abortReport(err)
+			return
+		}
+		j.State, j.Result = jobSucceeded, result
+	})
+}
+
+// newJob creates a queued job with a random ID.
+func (d *daemon) newJob(stage, keyID string, req *stageRequest) (*job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	j := &job{
+		ID:      hex.EncodeToString(id),
+		Stage:   stage,
+		KeyID:   keyID,
+		State:   jobQueued,
+		Created: now,
+		Updated: now,
+		request: req,
+	}
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.jobs[j.ID] = j
+	d.order = append(d.order, j.ID)
+	return j, nil
+}
+
+// update modifies the job with f, under the lock of the daemon.
+func (d *daemon) update(j *job, f func(j *job)) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	f(j)
+	j.Updated = time.Now()
+	log.Infof("job %s of stage %s is %s", j.ID, j.Stage, j.State)
+}
+
+// job returns a copy of the job id, nil if it does not exist.
+func (d *daemon) job(id string) *job {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	j, ok := d.jobs[id]
+	if !ok {
+		return nil
+	}
+	copied := *j
+	return &copied
+}
+
+// listJobs returns a copy of all the jobs, oldest first.
+func (d *daemon) listJobs() []*job {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	jobs := make([]*job, 0, len(d.order))
+	for _, id := range d.order {
+		copied := *d.jobs[id]
+		jobs = append(jobs, &copied)
+	}
+	return jobs
+}
+
+// apiRequest is the body of the POST endpoints of the API.
+type apiRequest struct {
+	stageRequest
+	// Rounds selects the presignatures of /presign and /sign, 3 or 6, /sign signs without presignature if it is 0
+	Rounds int `json:"rounds,omitempty"`
+	// AuxOnly makes /refresh rotate only the Paillier keys and Pedersen parameters
+	AuxOnly bool `json:"auxOnly,omitempty"`
+}
+
+// stageOf returns the stage requested by a POST to the endpoint.
+func (req *apiRequest) stageOf(endpoint string) (string, error) {
+	switch endpoint {
+	case "jobs":
+		return req.Stage, nil
+	case "keygen":
+		return "KeyGen", nil
+	case "refresh":
+		if req.AuxOnly {
+			return "KeyRefreshAux", nil
+		}
+		return "KeyRefresh", nil
+	case "reshare":
+		return "KeyReshare", nil
+	case "presign":
+		switch req.Rounds {
+		case 3:
+			return "PreSign3", nil
+		case 6:
+			return "PreSign6", nil
+		}
+	case "sign":
+		switch req.Rounds {
+		case 0:
+			return "Sign", nil
+		case 3:
+			return "SignAfterPreSign3", nil
+		case 6:
+			return "SignAfterPreSign6", nil
+		}
+	default:
+		return "", errNotFound
+	}
+	return "", fmt.Errorf("rounds must be 3 or 6, found %d", req.Rounds)
+}
+
+// errNotFound is returned for an unknown endpoint or job.
+var errNotFound = errors.New("not found")
+
+// ServeHTTP implements http.Handler. The API is:
+//
+//	POST /keygen, /refresh, /reshare, /presign, /sign   queue a job proposing the stage, on the center server only
+//	POST /jobs                                          queue a job proposing any stage
+//	GET  /jobs                                          list the jobs
+//	GET  /jobs/<id>                                     get a job
+//	GET  /keys                                          list the keys of the key store with their public key
+//
+// The bodies are JSON encoded, a queued job is returned with the status 202.
+// The requests are checked by authorize first.
+func (d *daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if status, err := d.authorize(r); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && path == "jobs":
+		writeJSON(w, http.StatusOK, d.listJobs())
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "jobs/"):
+		j := d.job(strings.TrimPrefix(path, "jobs/"))
+		if j == nil {
+			writeError(w, http.StatusNotFound, errNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, j)
+	case r.Method == http.MethodGet && path == "keys":
+		keys, err := listKeys(d.store)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+	case r.Method == http.MethodPost:
+		d.submit(w, r, path)
+	default:
+		writeError(w, http.StatusNotFound, errNotFound)
+	}
+}
+
+// authorize returns an error with its status if the request must not be served. The API is only meant for local
+// clients which are not browsers: a web page could otherwise post a job to a loopback address, with a form
+// or through a DNS name rebound to the loopback.
+//   - the Host must be localhost or a loopback IP, and the request must not come from a web page, with an Origin
+//   - the POST bodies must be application/json, which a form cannot send without the consent of the API
+//   - the bearer token must match if the daemon has one.
+func (d *daemon) authorize(r *http.Request) (int, error) {
+	if !isLoopbackHost(r.Host) {
+		return http.StatusForbidden, fmt.Errorf("host %q is not a loopback address", r.Host)
+	}
+	if r.Header.Get("Origin") != "" {
+		return http.StatusForbidden, errors.New("requests from web pages are not allowed")
+	}
+	if len(d.token) > 0 {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), d.token) != 1 {
+			return http.StatusUnauthorized, errors.New("invalid bearer token")
+		}
+	}
+	if r.Method == http.MethodPost {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("the body must be application/json")
+		}
+	}
+	return 0, nil
+}
+
+// submit queues a job for the stage posted to the endpoint.
+func (d *daemon) submit(w http.ResponseWriter, r *http.Request, endpoint string) {
+	req := &apiRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	stage, err := req.stageOf(endpoint)
+	if errors.Is(err, errNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !d.isCenter() {
+		writeError(w, http.StatusForbidden, fmt.Errorf("only the center server %v proposes stages", d.localConn.LocalConfig.CenterServerID))
+		return
+	}
+	// the stage is fully checked when it is proposed, reject the obvious mistakes right away
+	known := false
+	for _, s := range stages {
+		known = known || s == stage
+	}
+	if !known {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown stage %q", stage))
+		return
+	}
+	if req.KeyID != "" {
+		if err = save.ValidateKeyID(req.KeyID); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	req.Stage = stage
+	j, err := d.newJob(stage, req.KeyID, &req.stageRequest)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	select {
+	case d.queue <- j:
+	default:
+		d.update(j, func(j *job) {
+			j.State, j.Error = jobFailed, "too many queued jobs"
+		})
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many queued jobs"))
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d.job(j.ID))
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorln("fail to write the API response", err)
+	}
+}
+
+// writeError writes err as the JSON body of the response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// listen listens on addr, a unix socket if it starts with "unix:", a TCP address otherwise.
+// The API must only be reachable by the local clients: a TCP address must be a loopback address.
+func listen(addr string) (net.Listener, error) {
+	path := strings.TrimPrefix(addr, "unix:")
+	if path == addr {
+		if !isLoopback(addr) {
+			return nil, fmt.Errorf("the API is only for local clients, %q is not a loopback address", addr)
+		}
+		return net.Listen("tcp", addr)
+	}
+	// remove the socket left by a previous run, but never a regular file
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// isLoopback returns true if the host of the TCP address addr is localhost or a loopback IP,
+// an empty host listens on all the interfaces.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	return isLoopbackName(host)
+}
+
+// isLoopbackHost returns true if host, the Host of a request with or without port, is localhost or a loopback IP.
+func isLoopbackHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return isLoopbackName(name)
+	}
+	return isLoopbackName(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+}
+
+// isLoopbackName returns true if host is localhost or a loopback IP.
+func isLoopbackName(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// readToken returns the bearer token of the API stored in path, which only its owner may read.
+// A random token is created if the file does not exist.
+func readToken(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		random := make([]byte, 32)
+		if _, err = rand.Read(random); err != nil {
+			return nil, err
+		}
+		token := []byte(hex.EncodeToString(random))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = f.Write(append(token, '\n')); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err = f.Close(); err != nil {
+			return nil, err
+		}
+		log.Infof("the bearer token of the API is written to %s", path)
+		return token, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("the token file %s must be a regular file only readable by its owner (0600)", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	token := bytes.TrimSpace(data)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("the token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// serve runs the daemon and its API on addr until ctx is done. The clients authenticate with the token of tokenFile,
+// which is required on a TCP address since any local user can connect to it, and optional on a unix socket.
+func (d *daemon) serve(ctx context.Context, addr, tokenFile string) error {
+	if tokenFile != "" {
+		token, err := readToken(tokenFile)
+		if err != nil {
+			return err
+		}
+		d.token = token
+	} else if !strings.HasPrefix(addr, "unix:") {
+		return fmt.Errorf("the API on the TCP address %q requires a token file", addr)
+	}
+	l, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: d, ReadHeaderTimeout: 10 * time.Second}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		if err := d.run(ctx); err != nil && ctx.Err() == nil {
+			log.Errorln("daemon stopped:", err)
+		}
+		cancel()
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+		d.Close()
+	}()
+	log.Infof("serve the API on %s", addr)
+	if err = server.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
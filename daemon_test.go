@@ -0,0 +1,235 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package main
+
+import (
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/internal/save"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/taproot"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testParty is a party of the tests, with its key store and presignature pool.
+type testParty struct {
+	localConn *communication.LocalConn
+	store     *save.FileKeyStore
+	presigns  *save.PresignPool
+}
+
+// newTestParties creates the parties ids, whose center server is the first one, and sets its proposal key.
+func newTestParties(t *testing.T, ids party.IDSlice) map[party.ID]*testParty {
+	secret, public, err := taproot.GenKey(rand.Reader)
+	require.NoError(t, err)
+	t.Setenv(proposalKeyEnv, hex.EncodeToString(secret))
+
+	parties := make(map[party.ID]*testParty, len(ids))
+	for _, id := range ids {
+		localConn := &communication.LocalConn{LocalConfig: communication.LocalConfig{
+			LocalID:              id,
+			PartyIDs:             ids,
+			OtherPartyIDs:        ids.Remove(id),
+			Threshold:            1,
+			CenterServerID:       ids[0],
+			CoordinatorPublicKey: hex.EncodeToString(public),
+		}}
+		dir := t.TempDir()
+		store, err := save.NewFileKeyStore(dir, []byte("passphrase"))
+		require.NoError(t, err)
+		presigns, err := save.NewPresignPool(filepath.Join(dir, "presign"), []byte("passphrase"))
+		require.NoError(t, err)
+		parties[id] = &testParty{localConn: localConn, store: store, presigns: presigns}
+	}
+	return parties
+}
+
+// newTestDaemons starts a daemon for each party, connected by an in-process network, and returns their API servers.
+func newTestDaemons(t *testing.T, ids party.IDSlice) map[party.ID]*httptest.Server {
+	parties := newTestParties(t, ids)
+	network := communication.NewMemoryNetwork(ids)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	servers := make(map[party.ID]*httptest.Server, len(ids))
+	for id, p := range parties {
+		d, err := newDaemon(p.localConn, network.Transport(id), p.store, p.presigns)
+		require.NoError(t, err)
+		t.Cleanup(d.Close)
+		go func() { _ = d.run(ctx) }()
+		server := httptest.NewServer(d)
+		t.Cleanup(server.Close)
+		servers[id] = server
+	}
+	return servers
+}
+
+// call sends a request to the API and decodes its JSON response into v.
+func call(t *testing.T, server *httptest.Server, method, path string, body, v interface{}) int {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+// wait polls the job until it is done.
+func wait(t *testing.T, server *httptest.Server, id string) *job {
+	deadline := time.Now().Add(5 * time.Minute)
+	for time.Now().Before(deadline) {
+		j := &job{}
+		require.Equal(t, http.StatusOK, call(t, server, http.MethodGet, "/jobs/"+id, nil, j))
+		if j.State != jobQueued && j.State != jobRunning {
+			return j
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestDaemon(t *testing.T) {
+	ids := party.NewIDSlice([]party.ID{"a", "b", "c"})
+	servers := newTestDaemons(t, ids)
+	center := servers["a"]
+
+	j := &job{}
+	require.Equal(t, http.StatusAccepted, call(t, center, http.MethodPost, "/keygen", map[string]string{"keyID": "wallet1"}, j))
+	j = wait(t, center, j.ID)
+	require.Equal(t, jobSucceeded, j.State, j.Error)
+	publicKey := j.Result.PublicKey
+	require.NotEmpty(t, publicKey)
+
+	message := "hello, daemon"
+	require.Equal(t, http.StatusAccepted, call(t, center, http.MethodPost, "/sign",
+		map[string]interface{}{"keyID": "wallet1", "signers": []string{"a", "b"}, "message": message}, j))
+	j = wait(t, center, j.ID)
+	require.Equal(t, jobSucceeded, j.State, j.Error)
+	signature := j.Result.Signature
+	d, err := digest.Compute(digest.SHA256, []byte(message))
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(d.Hash), signature.Digest)
+	compact, err := hex.DecodeString(signature.R + signature.S)
+	require.NoError(t, err)
+	recovered, err := ecdsa.RecoverPublicKey(d.Hash, append(compact, signature.V))
+	require.NoError(t, err)
+	data, err := recovered.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, hex.EncodeToString(data), "the signature is valid for the key")
+
+	// the other parties track the same executions, c is not a signer of the signature
+	var jobs []*job
+	require.Equal(t, http.StatusOK, call(t, servers["b"], http.MethodGet, "/jobs", nil, &jobs))
+	require.Len(t, jobs, 2)
+	assert.Equal(t, jobSucceeded, jobs[1].State, jobs[1].Error)
+	assert.Equal(t, j.SessionID, jobs[1].SessionID)
+	var keys []*keyInfo
+	require.Equal(t, http.StatusOK, call(t, servers["c"], http.MethodGet, "/keys", nil, &keys))
+	require.Len(t, keys, 1)
+	assert.Equal(t, "wallet1", keys[0].KeyID)
+	assert.Equal(t, publicKey, keys[0].PublicKey)
+
+	// only the center server proposes, and the proposals are checked
+	assert.Equal(t, http.StatusForbidden, call(t, servers["b"], http.MethodPost, "/keygen", map[string]string{}, nil))
+	assert.Equal(t, http.StatusBadRequest, call(t, center, http.MethodPost, "/presign", map[string]interface{}{"keyID": "wallet1", "rounds": 4}, nil))
+	require.Equal(t, http.StatusAccepted, call(t, center, http.MethodPost, "/refresh", map[string]string{}, j))
+	j = wait(t, center, j.ID)
+	assert.Equal(t, jobRejected, j.State, "KeyRefresh requires a key ID")
+	assert.Equal(t, http.StatusNotFound, call(t, center, http.MethodGet, "/jobs/unknown", nil, nil))
+}
+
+// TestListen checks that the API only listens on loopback TCP addresses.
+func TestListen(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:0", "localhost:0", "[::1]:0"} {
+		l, err := listen(addr)
+		if err != nil && addr == "[::1]:0" {
+			// IPv6 may be disabled
+			continue
+		}
+		require.NoError(t, err, addr)
+		require.NoError(t, l.Close())
+	}
+	for _, addr := range []string{":0", "0.0.0.0:0", "[::]:0", "192.0.2.1:9000", "example.com:9000"} {
+		_, err := listen(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+// TestAuthorize checks that the API refuses the requests a web page could send, and checks the bearer token.
+func TestAuthorize(t *testing.T) {
+	ids := party.NewIDSlice([]party.ID{"a", "b"})
+	p := newTestParties(t, ids)["a"]
+	d, err := newDaemon(p.localConn, communication.NewMemoryNetwork(ids).Transport("a"), p.store, p.presigns)
+	require.NoError(t, err)
+	defer d.Close()
+
+	request := func(contentType, host, origin, token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/keygen", strings.NewReader(`{"keyID": "wallet1"}`))
+		r.Host = host
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, r)
+		return w.Code
+	}
+	// a form posted by a web page
+	assert.Equal(t, http.StatusUnsupportedMediaType, request("text/plain", "127.0.0.1:9000", "", ""))
+	assert.Equal(t, http.StatusUnsupportedMediaType, request("", "127.0.0.1:9000", "", ""))
+	assert.Equal(t, http.StatusForbidden, request("application/json", "127.0.0.1:9000", "http://example.com", ""))
+	// a DNS name rebound to the loopback
+	assert.Equal(t, http.StatusForbidden, request("application/json", "attacker.example:9000", "", ""))
+	assert.Equal(t, http.StatusAccepted, request("application/json; charset=utf-8", "localhost", "", ""))
+
+	path := filepath.Join(t.TempDir(), "token")
+	token, err := readToken(path)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	again, err := readToken(path)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+	d.token = token
+	assert.Equal(t, http.StatusUnauthorized, request("application/json", "[::1]:9000", "", ""))
+	assert.Equal(t, http.StatusUnauthorized, request("application/json", "[::1]:9000", "", "wrong"))
+	assert.Equal(t, http.StatusAccepted, request("application/json", "[::1]:9000", "", string(token)))
+
+	// a token readable by other users is refused
+	require.NoError(t, os.Chmod(path, 0644))
+	_, err = readToken(path)
+	assert.Error(t, err)
+	// the TCP API requires a token
+	assert.Error(t, d.serve(context.Background(), "127.0.0.1:0", ""))
+}
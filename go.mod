@@ -8,6 +8,7 @@ require (
 	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.9.0
 	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -17,7 +18,6 @@ require (
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
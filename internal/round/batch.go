@@ -0,0 +1,313 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package round
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// batchContent carries the encoded content of every instance of a batch, for the same message.
+type batchContent struct {
+	Round             Number
+	ReliableBroadcast bool
+	Data              [][]byte
+}
+
+// RoundNumber implements Content.
+func (c *batchContent) RoundNumber() Number { return c.Round }
+
+// Reliable implements BroadcastContent.
+func (c *batchContent) Reliable() bool { return c.ReliableBroadcast }
+
+// batch runs count instances of a protocol in lockstep, as a single session.
+// Each round, the messages of all the instances to the same recipient are sent together,
+// so the number of messages exchanged does not depend on count.
+type batch struct {
+	*Helper
+	sessions []Session
+	collect  func(results []interface{}) interface{}
+}
+
+// batchBroadcast is a batch whose instances expect a broadcast message.
+type batchBroadcast struct {
+	*batch
+}
+
+// batchKey identifies a message sent by each instance of a batch.
+type batchKey struct {
+	Broadcast bool
+	To        party.ID
+}
+
+// NewBatch creates a session running count instances of the protocol started by start.
+// Instance l is started with a session ID derived from the SSID of the batch and l,
+// so that each instance is bound to the batch and still distinct from the others.
+// The instances are processed in parallel over pl, so start is given pl only when count is 1,
+// and nil otherwise, since a pool must not be used from within its own workers.
+//
+// When all the instances succeed, the batch outputs collect(results), where results[l] is the output of instance l.
+// If any instance aborts, the batch aborts with the culprits of all the aborted instances.
+func NewBatch(info Info, sessionID []byte, pl *pool.Pool, count int, start func(sessionID []byte, pl *pool.Pool) (Session, error), collect func(results []interface{}) interface{}) (Session, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("batch: count %d is invalid", count)
+	}
+	countBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(countBytes, uint32(count))
+	helper, err := NewSession(info, sessionID, pl, &hash.BytesWithDomain{
+		TheDomain: "Batch Count",
+		Bytes:     countBytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	instancePool := pl
+	if count > 1 {
+		instancePool = nil
+	}
+	sessions := make([]Session, count)
+	for l := range sessions {
+		indexBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(indexBytes, uint32(l))
+		instanceID := helper.Hash().Fork(&hash.BytesWithDomain{
+			TheDomain: "Batch Index",
+			Bytes:     indexBytes,
+		}).Sum()
+		if sessions[l], err = start(instanceID, instancePool); err != nil {
+			return nil, fmt.Errorf("batch: instance %d: %w", l, err)
+		}
+	}
+	b := &batch{Helper: helper, sessions: sessions, collect: collect}
+	return b.next(), nil
+}
+
+// next returns b as a BroadcastRound when its instances expect a broadcast message.
+func (b *batch) next() Session {
+	if _, ok := b.sessions[0].(BroadcastRound); ok {
+		return &batchBroadcast{b}
+	}
+	return b
+}
+
+// parallelize calls f for each instance, in parallel over the pool when there are several.
+func (b *batch) parallelize(f func(l int) interface{}) []interface{} {
+	if len(b.sessions) == 1 {
+		return []interface{}{f(0)}
+	}
+	return b.Pool.Parallelize(len(b.sessions), f)
+}
+
+// forEach calls f for each instance in parallel, and returns the first error.
+func (b *batch) forEach(f func(l int) error) error {
+	errs := b.parallelize(func(l int) interface{} {
+		if err := f(l); err != nil {
+			return fmt.Errorf("batch: instance %d: %w", l, err)
+		}
+		return nil
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err.(error)
+		}
+	}
+	return nil
+}
+
+// instanceMessage decodes the content of instance l from msg into prototype.
+func (b *batch) instanceMessage(msg Message, l int, prototype Content) (Message, error) {
+	content, ok := msg.Content.(*batchContent)
+	if !ok || len(content.Data) != len(b.sessions) {
+		return Message{}, ErrInvalidContent
+	}
+	if prototype == nil {
+		return Message{}, ErrInvalidContent
+	}
+	if err := cbor.Unmarshal(content.Data[l], prototype); err != nil {
+		return Message{}, err
+	}
+	msg.Content = prototype
+	return msg, nil
+}
+
+// VerifyMessage implements Round.
+func (b *batch) VerifyMessage(msg Message) error {
+	return b.forEach(func(l int) error {
+		r := b.sessions[l]
+		m, err := b.instanceMessage(msg, l, r.MessageContent())
+		if err != nil {
+			return err
+		}
+		return r.VerifyMessage(m)
+	})
+}
+
+// StoreMessage implements Round.
+func (b *batch) StoreMessage(msg Message) error {
+	return b.forEach(func(l int) error {
+		r := b.sessions[l]
+		m, err := b.instanceMessage(msg, l, r.MessageContent())
+		if err != nil {
+			return err
+		}
+		return r.StoreMessage(m)
+	})
+}
+
+// StoreBroadcastMessage implements BroadcastRound.
+func (b *batchBroadcast) StoreBroadcastMessage(msg Message) error {
+	return b.forEach(func(l int) error {
+		r, ok := b.sessions[l].(BroadcastRound)
+		if !ok {
+			return ErrBatchDiverged
+		}
+		m, err := b.instanceMessage(msg, l, r.BroadcastContent())
+		if err != nil {
+			return err
+		}
+		return r.StoreBroadcastMessage(m)
+	})
+}
+
+// Finalize implements Round.
+//
+// - finalize all the instances in parallel
+// - output or abort once all the instances have finished
+// - otherwise, combine the messages of the instances, and send one message per recipient.
+func (b *batch) Finalize(out chan<- *Message) (Session, error) {
+	count := len(b.sessions)
+	messages := make([][]*Message, count)
+	results := b.parallelize(func(l int) interface{} {
+		instanceOut := make(chan *Message, b.N()+1)
+		next, err := b.sessions[l].Finalize(instanceOut)
+		close(instanceOut)
+		if err != nil {
+			return err
+		}
+		for msg := range instanceOut {
+			messages[l] = append(messages[l], msg)
+		}
+		return next
+	})
+	next := make([]Session, count)
+	for l, result := range results {
+		if err, ok := result.(error); ok {
+			return nil, fmt.Errorf("batch: instance %d: %w", l, err)
+		}
+		next[l] = result.(Session)
+	}
+
+	// abort with the culprits of all the aborted instances
+	var abortErr error
+	var culprits []party.ID
+	for l, r := range next {
+		if abort, ok := r.(*Abort); ok {
+			if abortErr == nil {
+				abortErr = fmt.Errorf("batch: instance %d: %w", l, abort.Err)
+			}
+			culprits = append(culprits, abort.Culprits...)
+		}
+	}
+	if abortErr != nil {
+		return b.AbortRound(abortErr, party.NewIDSlice(culprits)...), nil
+	}
+
+	if _, ok := next[0].(*Output); ok {
+		outputs := make([]interface{}, count)
+		for l, r := range next {
+			output, ok := r.(*Output)
+			if !ok {
+				return b.AbortRound(ErrBatchDiverged), nil
+			}
+			outputs[l] = output.Result
+		}
+		if b.collect == nil {
+			return b.ResultRound(outputs), nil
+		}
+		return b.ResultRound(b.collect(outputs)), nil
+	}
+
+	// every instance must be in the same round, and send the same messages
+	var keys []batchKey
+	contents := map[batchKey]*batchContent{}
+	for l, r := range next {
+		if r.Number() != next[0].Number() {
+			return b.AbortRound(ErrBatchDiverged), nil
+		}
+		if len(messages[l]) != len(messages[0]) {
+			return b.AbortRound(ErrBatchDiverged), nil
+		}
+		for _, msg := range messages[l] {
+			key := batchKey{Broadcast: msg.Broadcast, To: msg.To}
+			content, ok := contents[key]
+			if !ok {
+				if l > 0 {
+					return b.AbortRound(ErrBatchDiverged), nil
+				}
+				content = &batchContent{
+					Round: msg.Content.RoundNumber(),
+					Data:  make([][]byte, 0, count),
+				}
+				if bc, ok := msg.Content.(BroadcastContent); ok && msg.Broadcast {
+					content.ReliableBroadcast = bc.Reliable()
+				}
+				contents[key] = content
+				keys = append(keys, key)
+			}
+			if len(content.Data) != l || content.Round != msg.Content.RoundNumber() {
+				return b.AbortRound(ErrBatchDiverged), nil
+			}
+			data, err := cbor.Marshal(msg.Content)
+			if err != nil {
+				return nil, fmt.Errorf("batch: instance %d: %w", l, err)
+			}
+			content.Data = append(content.Data, data)
+		}
+	}
+
+	for _, key := range keys {
+		var err error
+		if key.Broadcast {
+			err = b.BroadcastMessage(out, contents[key])
+		} else {
+			err = b.SendMessage(out, contents[key], key.To)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return (&batch{Helper: b.Helper, sessions: next, collect: b.collect}).next(), nil
+}
+
+// MessageContent implements Round.
+func (b *batch) MessageContent() Content {
+	if b.sessions[0].MessageContent() == nil {
+		return nil
+	}
+	return &batchContent{}
+}
+
+// BroadcastContent implements BroadcastRound.
+func (b *batchBroadcast) BroadcastContent() BroadcastContent {
+	if b.sessions[0].(BroadcastRound).BroadcastContent() == nil {
+		return nil
+	}
+	return &batchContent{}
+}
+
+// Number implements Round.
+func (b *batch) Number() Number { return b.sessions[0].Number() }
+
+// ExpectedSenders implements Session, all the instances receive from the same parties.
+func (b *batch) ExpectedSenders() party.IDSlice { return b.sessions[0].ExpectedSenders() }
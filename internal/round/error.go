@@ -6,4 +6,29 @@ var (
 	ErrNilFields      = errors.New("message contained empty fields")
 	ErrInvalidContent = errors.New("content is not the right type")
 	ErrOutChanFull    = errors.New("content is not the right type")
+	// ErrBatchDiverged is returned when the instances of a batch no longer run the same round.
+	ErrBatchDiverged = errors.New("batch: instances diverged")
 )
+
+// CheckError is returned by a round when a check on the messages of a party fails.
+type CheckError struct {
+	// Check names the check which failed, such as the zero-knowledge proof "affg" or "nth"
+	Check string
+	// Err describes the failure
+	Err error
+}
+
+// Error implement error.
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap implement errors.Wrapper.
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// CheckFailed returns a CheckError for the failed check, with the given message.
+func CheckFailed(check, message string) error {
+	return &CheckError{Check: check, Err: errors.New(message)}
+}
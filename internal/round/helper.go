@@ -9,6 +9,7 @@ import (
 
 	"MPC_ECDSA/internal/types"
 	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/logging"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pool"
@@ -36,6 +37,9 @@ type Helper struct {
 
 	//localConn is about communication
 	localConn communication.LocalConn
+
+	//logger receives the log entries of the execution, logging.Default() if it is nil
+	logger logging.Logger
 }
 
 // NewSession creates a new *Helper which can be embedded in the first Round,
@@ -220,6 +224,9 @@ func (h *Helper) PartyIDs() party.IDSlice { return h.partyIDs }
 // OtherPartyIDs returns a sorted list of parties that does not contain SelfID.
 func (h *Helper) OtherPartyIDs() party.IDSlice { return h.otherPartyIDs }
 
+// ExpectedSenders returns all the other parties, a round which only receives messages from some of them overrides it.
+func (h *Helper) ExpectedSenders() party.IDSlice { return h.otherPartyIDs }
+
 // Threshold is the maximum number of parties that are assumed to be corrupted during the execution of this protocol.
 func (h *Helper) Threshold() int { return h.Info.Threshold }
 
@@ -228,3 +235,11 @@ func (h *Helper) N() int { return len(h.Info.PartyIDs) }
 
 // Group returns the curve used for this protocol.
 func (h *Helper) Group() curve.Curve { return h.Info.Group }
+
+// SetLogger sets the logger of this protocol execution, it must be called before the execution starts.
+func (h *Helper) SetLogger(l logging.Logger) { h.logger = l }
+
+// Logger returns the logger of this protocol execution, with the protocol, SSID and party fields.
+func (h *Helper) Logger() logging.Logger {
+	return logging.Session(h.logger, h.Info.ProtocolID, h.ssid, h.Info.SelfID)
+}
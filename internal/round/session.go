@@ -2,6 +2,7 @@ package round
 
 import (
 	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/logging"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
 )
@@ -58,4 +59,18 @@ type Session interface {
 	Threshold() int
 	// N returns the total number of parties participating in the protocol.
 	N() int
+	// ExpectedSenders returns the parties which send a message to this party in the current round.
+	// Each of them sends a broadcast message if the round is a BroadcastRound, and a normal message
+	// if MessageContent is not nil.
+	ExpectedSenders() party.IDSlice
+	// SetLogger sets the logger of this protocol execution, it must be called before the execution starts.
+	SetLogger(l logging.Logger)
+	// Logger returns the logger of this protocol execution, with the protocol, SSID and party fields.
+	Logger() logging.Logger
+}
+
+// Logger returns the logger of the session r, with the number of its current round.
+// Secret values, such as the shares and the Paillier keys of the session, must not be logged.
+func Logger(r Session) logging.Logger {
+	return r.Logger().WithField(logging.FieldRound, r.Number())
 }
@@ -0,0 +1,54 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"MPC_ECDSA/pkg/protocol"
+)
+
+// abortReportExtension is the extension of the files written by SaveAbortReport.
+const abortReportExtension = ".json"
+
+// SaveAbortReport writes report as JSON in dir, one file per aborted execution named after its SSID,
+// and returns the path of the file. The directory is created if it does not exist.
+// The report only contains messages exchanged between the parties, it is not encrypted.
+func SaveAbortReport(dir string, report *protocol.AbortReport) (string, error) {
+	if len(report.SSID) == 0 {
+		return "", errors.New("save: abort report without SSID")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, hex.EncodeToString(report.SSID)+abortReportExtension)
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadAbortReport reads a report written by SaveAbortReport.
+func LoadAbortReport(path string) (*protocol.AbortReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report := &protocol.AbortReport{}
+	if err = json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
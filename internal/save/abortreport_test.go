@@ -0,0 +1,49 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"testing"
+
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/protocol"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAbortReport saves a report and checks that its evidence can be decoded once loaded.
+func TestAbortReport(t *testing.T) {
+	evidence := &protocol.Message{SSID: []byte{1, 2, 3}, From: "c", To: "a", Protocol: "cmp/sign", RoundNumber: 2, Data: []byte("proof")}
+	data, err := cbor.Marshal(evidence)
+	require.NoError(t, err)
+	report := &protocol.AbortReport{
+		Protocol: "cmp/sign",
+		SSID:     []byte{1, 2, 3},
+		Round:    2,
+		SelfID:   "a",
+		Culprits: []party.ID{"c"},
+		Check:    "enc",
+		Reason:   "failed to validate enc proof for K",
+		Evidence: [][]byte{data},
+	}
+
+	path, err := SaveAbortReport(t.TempDir(), report)
+	require.NoError(t, err)
+	loaded, err := LoadAbortReport(path)
+	require.NoError(t, err)
+	assert.Equal(t, report, loaded)
+	assert.Equal(t, "culprits: [c]: failed to validate enc proof for K", loaded.Error())
+	msgs, err := loaded.Messages()
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, evidence, msgs[0])
+
+	_, err = SaveAbortReport(t.TempDir(), &protocol.AbortReport{})
+	assert.Error(t, err, "a report without SSID")
+}
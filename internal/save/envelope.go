@@ -0,0 +1,211 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EnvelopeVersion is the version of the encrypted file format written by this package.
+	EnvelopeVersion = 1
+	// KDFArgon2id derives the file key with Argon2id.
+	KDFArgon2id = "argon2id"
+	// KDFScrypt derives the file key with scrypt.
+	KDFScrypt = "scrypt"
+
+	saltSize = 16
+
+	// maxKDFFactor bounds the cost of the KDF parameters read from a file to this multiple of the defaults,
+	// so that a tampered header cannot make Decrypt exhaust the memory or the CPU before the header is authenticated.
+	maxKDFFactor = 4
+	// maxScryptN, maxScryptR and maxScryptP are 4 times the recommended scrypt parameters N = 2^15, r = 8 and p = 1.
+	maxScryptN = maxKDFFactor << 15
+	maxScryptR = maxKDFFactor * 8
+	maxScryptP = maxKDFFactor
+)
+
+// envelopeMagic starts every encrypted file, so that encrypted and legacy plaintext files can be told apart.
+var envelopeMagic = []byte("MPCENC")
+
+var (
+	// ErrNotEncrypted is returned when decrypting data which is not in the envelope format.
+	ErrNotEncrypted = errors.New("save: data is not encrypted")
+	// ErrEmptyPassphrase is returned when encrypting or decrypting with an empty passphrase.
+	ErrEmptyPassphrase = errors.New("save: empty passphrase")
+	// ErrDecrypt is returned when the passphrase is wrong or the file has been tampered with.
+	ErrDecrypt = errors.New("save: wrong passphrase or corrupted file")
+)
+
+// KDFParams describes how the file key is derived from the passphrase.
+// Only the fields of the selected KDF are used.
+type KDFParams struct {
+	// Name is KDFArgon2id or KDFScrypt.
+	Name string
+	// Salt is replaced by a fresh random salt every time Encrypt is called.
+	Salt []byte
+	// Time, Memory (in KiB) and Threads are the Argon2id parameters.
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	// N, R and P are the scrypt parameters.
+	N int
+	R int
+	P int
+}
+
+// DefaultKDFParams returns the Argon2id parameters used for new files.
+func DefaultKDFParams() KDFParams {
+	return KDFParams{
+		Name:    KDFArgon2id,
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+}
+
+// envelopeHeader is authenticated together with the ciphertext, so that the KDF parameters cannot be downgraded.
+type envelopeHeader struct {
+	Version uint8
+	KDF     KDFParams
+	Nonce   []byte
+}
+
+// envelope is the encrypted file format: envelopeMagic followed by the CBOR encoding of envelope.
+type envelope struct {
+	Header     []byte
+	Ciphertext []byte
+}
+
+// IsEncrypted reports whether data is in the envelope format.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// Encrypt seals plaintext with a key derived from passphrase.
+// The KDF parameters are stored in the header of the result, a fresh salt and nonce are generated for every call.
+func Encrypt(plaintext, passphrase []byte, params KDFParams) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	params.Salt = make([]byte, saltSize)
+	if _, err := rand.Read(params.Salt); err != nil {
+		return nil, fmt.Errorf("save: failed to generate salt: %w", err)
+	}
+	key, err := deriveKey(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	header := envelopeHeader{
+		Version: EnvelopeVersion,
+		KDF:     params,
+		Nonce:   make([]byte, aead.NonceSize()),
+	}
+	if _, err = rand.Read(header.Nonce); err != nil {
+		return nil, fmt.Errorf("save: failed to generate nonce: %w", err)
+	}
+	headerBytes, err := cbor.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	data, err := cbor.Marshal(envelope{
+		Header:     headerBytes,
+		Ciphertext: aead.Seal(nil, header.Nonce, plaintext, headerBytes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(nil), envelopeMagic...), data...), nil
+}
+
+// Decrypt opens data sealed by Encrypt.
+func Decrypt(data, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	if !IsEncrypted(data) {
+		return nil, ErrNotEncrypted
+	}
+	var env envelope
+	if err := cbor.Unmarshal(data[len(envelopeMagic):], &env); err != nil {
+		return nil, fmt.Errorf("save: invalid envelope: %w", err)
+	}
+	var header envelopeHeader
+	if err := cbor.Unmarshal(env.Header, &header); err != nil {
+		return nil, fmt.Errorf("save: invalid envelope header: %w", err)
+	}
+	if header.Version != EnvelopeVersion {
+		return nil, fmt.Errorf("save: unsupported envelope version %d", header.Version)
+	}
+	key, err := deriveKey(passphrase, header.KDF)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, errors.New("save: invalid envelope nonce")
+	}
+	plaintext, err := aead.Open(nil, header.Nonce, env.Ciphertext, env.Header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// Reencrypt decrypts data with oldPassphrase, and encrypts it again with newPassphrase using the given KDF parameters.
+func Reencrypt(data, oldPassphrase, newPassphrase []byte, params KDFParams) ([]byte, error) {
+	plaintext, err := Decrypt(data, oldPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(plaintext, newPassphrase, params)
+}
+
+// deriveKey derives the 32 byte file key from the passphrase.
+// The KDF parameters may come from an unauthenticated header, their cost is bounded by maxKDFFactor.
+func deriveKey(passphrase []byte, params KDFParams) ([]byte, error) {
+	if len(params.Salt) < saltSize {
+		return nil, errors.New("save: KDF salt is too short")
+	}
+	defaults := DefaultKDFParams()
+	switch params.Name {
+	case KDFArgon2id:
+		if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+			return nil, errors.New("save: invalid argon2id parameters")
+		}
+		if params.Time > maxKDFFactor*defaults.Time || params.Memory > maxKDFFactor*defaults.Memory || params.Threads > maxKDFFactor*defaults.Threads {
+			return nil, errors.New("save: argon2id parameters exceed the limits")
+		}
+		return argon2.IDKey(passphrase, params.Salt, params.Time, params.Memory, params.Threads, chacha20poly1305.KeySize), nil
+	case KDFScrypt:
+		if params.N > maxScryptN || params.R > maxScryptR || params.P > maxScryptP {
+			return nil, errors.New("save: scrypt parameters exceed the limits")
+		}
+		key, err := scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, fmt.Errorf("save: invalid scrypt parameters: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("save: unknown KDF %q", params.Name)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// light KDF parameters, so that the tests run fast
+var testKDFParams = []KDFParams{
+	{Name: KDFArgon2id, Time: 1, Memory: 1024, Threads: 1},
+	{Name: KDFScrypt, N: 1 << 10, R: 8, P: 1},
+}
+
+// TestEncryptDecrypt checks the round trip of the envelope, and that a wrong passphrase or a modified file is rejected.
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte("secret share")
+	passphrase := []byte("correct horse battery staple")
+	for _, params := range testKDFParams {
+		t.Run(params.Name, func(t *testing.T) {
+			encrypted, err := Encrypt(plaintext, passphrase, params)
+			require.NoError(t, err)
+			assert.True(t, IsEncrypted(encrypted))
+			assert.NotContains(t, string(encrypted), string(plaintext))
+
+			decrypted, err := Decrypt(encrypted, passphrase)
+			require.NoError(t, err)
+			assert.Equal(t, plaintext, decrypted)
+
+			_, err = Decrypt(encrypted, []byte("wrong passphrase"))
+			assert.ErrorIs(t, err, ErrDecrypt)
+
+			// the header is authenticated
+			var env envelope
+			require.NoError(t, cbor.Unmarshal(encrypted[len(envelopeMagic):], &env))
+			var header envelopeHeader
+			require.NoError(t, cbor.Unmarshal(env.Header, &header))
+			header.KDF.Salt[0] ^= 1
+			env.Header, err = cbor.Marshal(header)
+			require.NoError(t, err)
+			tampered, err := cbor.Marshal(env)
+			require.NoError(t, err)
+			_, err = Decrypt(append(append([]byte(nil), envelopeMagic...), tampered...), passphrase)
+			assert.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+
+	_, err := Decrypt(plaintext, passphrase)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+	_, err = Encrypt(plaintext, nil, testKDFParams[0])
+	assert.ErrorIs(t, err, ErrEmptyPassphrase)
+}
+
+// TestReencrypt checks that after a passphrase rotation only the new passphrase opens the data.
+func TestReencrypt(t *testing.T) {
+	plaintext := []byte("secret share")
+	oldPassphrase, newPassphrase := []byte("old"), []byte("new")
+	encrypted, err := Encrypt(plaintext, oldPassphrase, testKDFParams[0])
+	require.NoError(t, err)
+
+	rotated, err := Reencrypt(encrypted, oldPassphrase, newPassphrase, testKDFParams[1])
+	require.NoError(t, err)
+	_, err = Decrypt(rotated, oldPassphrase)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	decrypted, err := Decrypt(rotated, newPassphrase)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, decrypted)
+
+	_, err = Reencrypt(encrypted, newPassphrase, oldPassphrase, testKDFParams[0])
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+// TestKDFLimits checks that KDF parameters beyond the limits are refused before the key is derived.
+func TestKDFLimits(t *testing.T) {
+	passphrase := []byte("passphrase")
+	encrypted, err := Encrypt([]byte("secret share"), passphrase, testKDFParams[0])
+	require.NoError(t, err)
+	var env envelope
+	require.NoError(t, cbor.Unmarshal(encrypted[len(envelopeMagic):], &env))
+	var header envelopeHeader
+	require.NoError(t, cbor.Unmarshal(env.Header, &header))
+
+	for _, kdf := range []KDFParams{
+		{Name: KDFArgon2id, Time: 1, Memory: 1 << 30, Threads: 1},
+		{Name: KDFArgon2id, Time: 1 << 20, Memory: 1024, Threads: 1},
+		{Name: KDFScrypt, N: 1 << 30, R: 8, P: 1},
+		{Name: KDFScrypt, N: 1 << 10, R: 1 << 20, P: 1},
+	} {
+		kdf.Salt = header.KDF.Salt
+		tamperedHeader := header
+		tamperedHeader.KDF = kdf
+		env.Header, err = cbor.Marshal(tamperedHeader)
+		require.NoError(t, err)
+		tampered, err := cbor.Marshal(env)
+		require.NoError(t, err)
+		_, err = Decrypt(append(append([]byte(nil), envelopeMagic...), tampered...), passphrase)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrDecrypt, "the key should not be derived")
+
+		_, err = Encrypt([]byte("secret share"), passphrase, kdf)
+		assert.Error(t, err)
+	}
+}
+
+// TestPlaintextFiles checks that plaintext files are only loaded and rotated if AllowPlaintext is set.
+func TestPlaintextFiles(t *testing.T) {
+	defer func() { AllowPlaintext = false }()
+	plaintext := []byte("legacy share")
+	path := filepath.Join(t.TempDir(), "keygen_result.data")
+	require.NoError(t, os.WriteFile(path, plaintext, 0600))
+
+	_, err := openFile("keygen", plaintext, []byte("passphrase"))
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+	assert.ErrorIs(t, rotateFile(path, []byte("old"), []byte("new")), ErrNotEncrypted)
+
+	AllowPlaintext = true
+	data, err := openFile("keygen", plaintext, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, data)
+	require.NoError(t, rotateFile(path, []byte("old"), []byte("new")))
+	encrypted, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data, err = Decrypt(encrypted, []byte("new"))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, data)
+}
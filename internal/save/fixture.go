@@ -36,6 +36,13 @@ const (
 	presign6FixtureFileFormat    = "presign6_result_%s.data"
 )
 
+// FileKDFParams are the KDF parameters used to encrypt the saved results.
+var FileKDFParams = DefaultKDFParams()
+
+// AllowPlaintext lets the files saved in plaintext by earlier versions be loaded, and encrypted by RotatePassphrase.
+// It is off by default, since a plaintext file put in place of an encrypted one would otherwise be trusted.
+var AllowPlaintext = false
+
 func makeTestFixtureFilePath(dirFormat, fileFormat, stage string, index string) string {
 	_, callerFileName, _, _ := runtime.Caller(0)
 	srcDirName := filepath.Dir(callerFileName)
@@ -121,9 +128,11 @@ func LoadLocalKeyGenResults() (map[party.ID]interface{}, error) {
 	}
 	return configs, nil
 }
-func LoadKeyGenResult2() (interface{}, error) {
+
+// LoadKeyGenResult2 loads the keygen configuration from an encrypted file, and returns it as an interface{}
+func LoadKeyGenResult2(passphrase []byte) (interface{}, error) {
 	//read the configuration from a file
-	fileResult, err := ReadFixtureFile("keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
+	fileResult, err := ReadEncryptedFixtureFile(passphrase, "keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -154,8 +163,8 @@ func LoadLocalKeyGenResultWithID(id party.ID) (*protocols.Config, error) {
 	return config, nil
 }
 
-// SaveKeyGenResult saves the keygen configuration to a file
-func SaveKeyGenResult(config *protocols.Config) error {
+// SaveKeyGenResult saves the keygen configuration to a file encrypted with passphrase
+func SaveKeyGenResult(config *protocols.Config, passphrase []byte) error {
 	//marshal the configuration
 	//marshalledConfig, err := config.MarshalBinary()
 	marshalledConfig, err := cbor.Marshal(config)
@@ -163,15 +172,15 @@ func SaveKeyGenResult(config *protocols.Config) error {
 		return err
 	}
 	//write the configuration to a file
-	err = WriteFixtureFile(marshalledConfig, "keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
+	err = WriteEncryptedFixtureFile(marshalledConfig, passphrase, "keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// SaveKeyGenResult saves the keygen configuration to a file
-func SaveReshareResult(config *protocols.Config) error {
+// SaveReshareResult saves the reshare configuration to a file encrypted with passphrase
+func SaveReshareResult(config *protocols.Config, passphrase []byte) error {
 	//marshal the configuration
 	//marshalledConfig, err := config.MarshalBinary()
 	marshalledConfig, err := cbor.Marshal(config)
@@ -179,17 +188,17 @@ func SaveReshareResult(config *protocols.Config) error {
 		return err
 	}
 	//write the configuration to a file
-	err = WriteFixtureFile(marshalledConfig, "reshare", "", reshareFixtureDirFormat, reshareFixtureFileFormat)
+	err = WriteEncryptedFixtureFile(marshalledConfig, passphrase, "reshare", "", reshareFixtureDirFormat, reshareFixtureFileFormat)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// LoadKeyGenConfig loads the keygen configuration from a file
-func LoadReshareResult() (*protocols.Config, error) {
+// LoadReshareResult loads the reshare configuration from a file encrypted with passphrase
+func LoadReshareResult(passphrase []byte) (*protocols.Config, error) {
 	//read the configuration from a file
-	fileResult, err := ReadFixtureFile("keygen", "", reshareFixtureDirFormat, reshareFixtureFileFormat)
+	fileResult, err := ReadEncryptedFixtureFile(passphrase, "keygen", "", reshareFixtureDirFormat, reshareFixtureFileFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -202,10 +211,10 @@ func LoadReshareResult() (*protocols.Config, error) {
 	return config, nil
 }
 
-// LoadKeyGenConfig loads the keygen configuration from a file
-func LoadKeyGenResult() (*protocols.Config, error) {
+// LoadKeyGenResult loads the keygen configuration from a file encrypted with passphrase
+func LoadKeyGenResult(passphrase []byte) (*protocols.Config, error) {
 	//read the configuration from a file
-	fileResult, err := ReadFixtureFile("keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
+	fileResult, err := ReadEncryptedFixtureFile(passphrase, "keygen", "", keygenFixtureDirFormat, keygenFixtureFileFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -220,8 +229,8 @@ func LoadKeyGenResult() (*protocols.Config, error) {
 
 // LoadKeyGenConfig loads the keygen configuration from a file
 
-// SavePresign3Result saves the presign3 result to a file
-func SavePresign3Result(result *ecdsa3rounds.PreSignature3, presignIndex string) error {
+// SavePresign3Result saves the presign3 result to a file encrypted with passphrase
+func SavePresign3Result(result *ecdsa3rounds.PreSignature3, presignIndex string, passphrase []byte) error {
 	// marshal the result
 	marshalledResult, err := cbor.Marshal(result)
 	if err != nil {
@@ -229,17 +238,17 @@ func SavePresign3Result(result *ecdsa3rounds.PreSignature3, presignIndex string)
 		return err
 	}
 	// write the result to a file
-	err = WriteFixtureFile(marshalledResult, "presign3", presignIndex, presign3FixtureDirFormat, presign3FixtureFileFormat)
+	err = WriteEncryptedFixtureFile(marshalledResult, passphrase, "presign3", presignIndex, presign3FixtureDirFormat, presign3FixtureFileFormat)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// LoadPresign3Result loads the presign3 result from a file
-func LoadPresign3Result(presignIndex string) (*ecdsa3rounds.PreSignature3, error) {
+// LoadPresign3Result loads the presign3 result from a file encrypted with passphrase
+func LoadPresign3Result(presignIndex string, passphrase []byte) (*ecdsa3rounds.PreSignature3, error) {
 	// read the result from a file
-	fileResult, err := ReadFixtureFile("presign3", presignIndex, presign3FixtureDirFormat, presign3FixtureFileFormat)
+	fileResult, err := ReadEncryptedFixtureFile(passphrase, "presign3", presignIndex, presign3FixtureDirFormat, presign3FixtureFileFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -252,8 +261,8 @@ func LoadPresign3Result(presignIndex string) (*ecdsa3rounds.PreSignature3, error
 	return result, nil
 }
 
-// SavePresign6Result saves the presign6 result to a file
-func SavePresign6Result(result *ecdsa.PreSignature, presignIndex string) error {
+// SavePresign6Result saves the presign6 result to a file encrypted with passphrase
+func SavePresign6Result(result *ecdsa.PreSignature, presignIndex string, passphrase []byte) error {
 	// marshal the result
 	marshalledResult, err := cbor.Marshal(result)
 	if err != nil {
@@ -261,17 +270,17 @@ func SavePresign6Result(result *ecdsa.PreSignature, presignIndex string) error {
 		return err
 	}
 	// write the result to a file
-	err = WriteFixtureFile(marshalledResult, "presign6", presignIndex, presign6FixtureDirFormat, presign6FixtureFileFormat)
+	err = WriteEncryptedFixtureFile(marshalledResult, passphrase, "presign6", presignIndex, presign6FixtureDirFormat, presign6FixtureFileFormat)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// LoadPresign6Result loads the presign3 result from a file
-func LoadPresign6Result(presignIndex string) (*ecdsa.PreSignature, error) {
+// LoadPresign6Result loads the presign6 result from a file encrypted with passphrase
+func LoadPresign6Result(presignIndex string, passphrase []byte) (*ecdsa.PreSignature, error) {
 	// read the result from a file
-	fileResult, err := ReadFixtureFile("presign6", presignIndex, presign6FixtureDirFormat, presign6FixtureFileFormat)
+	fileResult, err := ReadEncryptedFixtureFile(passphrase, "presign6", presignIndex, presign6FixtureDirFormat, presign6FixtureFileFormat)
 	if err != nil {
 		return nil, err
 	}
@@ -348,27 +357,111 @@ func WriteFixtureFile(result []byte, stage string, index string, dirFormat strin
 // returns: []byte, int, error
 func ReadFixtureFile(stage string, index string, dirFormat string, fileFormat string) ([]byte, error) {
 	fixtureFileName := makeTestFixtureFilePath(dirFormat, fileFormat, stage, index)
-	// open file
-	fd, err := os.Open(fixtureFileName)
+	// read the whole file
+	result, err := os.ReadFile(fixtureFileName)
 	if err != nil {
-		log.Errorf("unable to open save file %s for reading", fixtureFileName)
+		log.Errorf("unable to read save file %s", fixtureFileName)
 		return nil, err
 	}
-	// read file
-	jsonResult := make([]byte, 16384)
-	_, err = fd.Read(jsonResult)
+	log.Infof("done read save file %s", fixtureFileName)
+	return result, nil
+}
+
+// WriteEncryptedFixtureFile encrypts result with passphrase using FileKDFParams, and saves it to a file like WriteFixtureFile
+func WriteEncryptedFixtureFile(result []byte, passphrase []byte, stage string, index string, dirFormat string, fileFormat string) error {
+	encrypted, err := Encrypt(result, passphrase, FileKDFParams)
+	if err != nil {
+		log.Errorf("unable to encrypt %s result", stage)
+		return err
+	}
+	return WriteFixtureFile(encrypted, stage, index, dirFormat, fileFormat)
+}
+
+// ReadEncryptedFixtureFile reads a file saved by WriteEncryptedFixtureFile and decrypts it with passphrase.
+// Files written before encryption was introduced are only returned as is if AllowPlaintext is set,
+// they are encrypted the next time they are saved or when RotatePassphrase is called.
+func ReadEncryptedFixtureFile(passphrase []byte, stage string, index string, dirFormat string, fileFormat string) ([]byte, error) {
+	data, err := ReadFixtureFile(stage, index, dirFormat, fileFormat)
 	if err != nil {
-		log.Errorf("unable to read save file %s", fixtureFileName)
 		return nil, err
 	}
-	// close file
-	err = fd.Close()
+	return openFile(stage, data, passphrase)
+}
+
+// openFile decrypts the saved result of stage with passphrase, a plaintext result is only accepted if AllowPlaintext is set.
+func openFile(stage string, data, passphrase []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		if !AllowPlaintext {
+			log.Errorf("%s result is stored in plaintext, it is only loaded if plaintext files are allowed", stage)
+			return nil, ErrNotEncrypted
+		}
+		log.Warnf("%s result is stored in plaintext, it will be encrypted when it is saved again", stage)
+		return data, nil
+	}
+	result, err := Decrypt(data, passphrase)
 	if err != nil {
-		log.Errorf("unable to close save file %s", fixtureFileName)
+		log.Errorf("unable to decrypt %s result", stage)
 		return nil, err
 	}
-	log.Infof("done read save file %s", fixtureFileName)
-	return jsonResult, nil
+	return result, nil
+}
+
+// RotatePassphrase encrypts the saved keygen, reshare and presign results again under newPassphrase.
+// Plaintext files are encrypted as well if AllowPlaintext is set, otherwise they make it fail. Each file is replaced atomically,
+// if an error occurs the files already processed use newPassphrase and the others still use oldPassphrase.
+func RotatePassphrase(oldPassphrase, newPassphrase []byte) error {
+	_, callerFileName, _, _ := runtime.Caller(0)
+	srcDirName := filepath.Dir(callerFileName)
+	for _, dirFormat := range []string{keygenFixtureDirFormat, reshareFixtureDirFormat, presign3FixtureDirFormat, presign6FixtureDirFormat} {
+		dir := filepath.Clean(fmt.Sprintf(dirFormat, srcDirName))
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if err = rotateFile(filepath.Join(dir, entry.Name()), oldPassphrase, newPassphrase); err != nil {
+				log.Errorf("unable to rotate passphrase of %s", entry.Name())
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rotateFile encrypts the file at filePath again under newPassphrase, through a temporary file renamed over it.
+func rotateFile(filePath string, oldPassphrase, newPassphrase []byte) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var encrypted []byte
+	switch {
+	case IsEncrypted(data):
+		encrypted, err = Reencrypt(data, oldPassphrase, newPassphrase, FileKDFParams)
+	case AllowPlaintext:
+		encrypted, err = Encrypt(data, newPassphrase, FileKDFParams)
+	default:
+		return ErrNotEncrypted
+	}
+	if err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, encrypted, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	log.Infof("done rotate passphrase of %s", filePath)
+	return nil
 }
 
 // delete fixture file
@@ -306,7 +306,7 @@ func TestLoadPresign3Result(t *testing.T) {
 	Chdir()
 	// read the save file
 	// please run this test after TestKeygenAndSave
-	result, err := LoadPresign3Result("【ID_TO_BE_ADD】")
+	result, err := LoadPresign3Result("【ID_TO_BE_ADD】", []byte("【PASSPHRASE_TO_BE_ADD】"))
 	if err != nil {
 		t.Errorf("unable to read save file: %v", err)
 	}
@@ -0,0 +1,203 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/protocols/config"
+
+	"github.com/fxamacker/cbor/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultKeyStoreDir is the directory of the FileKeyStore if none is configured.
+	DefaultKeyStoreDir = "./keystore"
+	// keyFileExtension is the extension of the files of a FileKeyStore.
+	keyFileExtension = ".key"
+)
+
+var (
+	// ErrKeyNotFound is returned when a key ID is not in the KeyStore.
+	ErrKeyNotFound = errors.New("save: key not found")
+	// ErrInvalidKeyID is returned for key IDs which cannot be used as a file name.
+	ErrInvalidKeyID = errors.New("save: invalid key ID, only letters, digits, '.', '_' and '-' are allowed")
+)
+
+// keyIDPattern restricts key IDs to names which are safe to use as a file name.
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,127}$`)
+
+// KeyStore stores the key shares of several MPC wallets, each of them addressed by a key ID.
+type KeyStore interface {
+	// Put stores config under keyID, replacing the config previously stored under it, if any.
+	Put(keyID string, config *config.Config) error
+	// Get returns the config stored under keyID, or ErrKeyNotFound.
+	Get(keyID string) (*config.Config, error)
+	// List returns the sorted IDs of the stored keys.
+	List() ([]string, error)
+	// Delete removes the config stored under keyID, or returns ErrKeyNotFound.
+	Delete(keyID string) error
+}
+
+// FileKeyStore is a KeyStore keeping one encrypted file per key in a directory.
+type FileKeyStore struct {
+	dir        string
+	passphrase []byte
+}
+
+// NewFileKeyStore returns a KeyStore rooted at dir, the directory is created if it does not exist.
+// The files are encrypted with passphrase using FileKDFParams.
+func NewFileKeyStore(dir string, passphrase []byte) (*FileKeyStore, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileKeyStore{
+		dir:        dir,
+		passphrase: passphrase,
+	}, nil
+}
+
+// KeyID returns the default key ID of config: the hex encoded compressed group public key.
+func KeyID(c *config.Config) (string, error) {
+	data, err := c.PublicPoint().MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// ValidateKeyID returns ErrInvalidKeyID if keyID cannot be used to address a key.
+func ValidateKeyID(keyID string) error {
+	if !keyIDPattern.MatchString(keyID) {
+		return fmt.Errorf("%w: %q", ErrInvalidKeyID, keyID)
+	}
+	return nil
+}
+
+// Put implements KeyStore, the file is replaced atomically.
+func (s *FileKeyStore) Put(keyID string, c *config.Config) error {
+	filePath, err := s.path(keyID)
+	if err != nil {
+		return err
+	}
+	marshalledConfig, err := cbor.Marshal(c)
+	if err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(marshalledConfig, s.passphrase, FileKDFParams)
+	if err != nil {
+		return err
+	}
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, encrypted, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	log.Infof("done save key %s", keyID)
+	return nil
+}
+
+// Get implements KeyStore.
+func (s *FileKeyStore) Get(keyID string) (*config.Config, error) {
+	filePath, err := s.path(keyID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	marshalledConfig, err := Decrypt(data, s.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	c := config.EmptyConfig(curve.Secp256k1{})
+	if err = cbor.Unmarshal(marshalledConfig, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List implements KeyStore.
+func (s *FileKeyStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	keyIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, keyFileExtension) {
+			continue
+		}
+		keyIDs = append(keyIDs, strings.TrimSuffix(name, keyFileExtension))
+	}
+	sort.Strings(keyIDs)
+	return keyIDs, nil
+}
+
+// Delete implements KeyStore.
+func (s *FileKeyStore) Delete(keyID string) error {
+	filePath, err := s.path(keyID)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("done delete key %s", keyID)
+	return nil
+}
+
+// Rotate encrypts all the keys of the store again under newPassphrase, which is used from now on.
+func (s *FileKeyStore) Rotate(newPassphrase []byte) error {
+	if len(newPassphrase) == 0 {
+		return ErrEmptyPassphrase
+	}
+	keyIDs, err := s.List()
+	if err != nil {
+		return err
+	}
+	for _, keyID := range keyIDs {
+		if err = rotateFile(filepath.Join(s.dir, keyID+keyFileExtension), s.passphrase, newPassphrase); err != nil {
+			log.Errorf("unable to rotate passphrase of key %s", keyID)
+			return err
+		}
+	}
+	s.passphrase = newPassphrase
+	return nil
+}
+
+// path returns the file of the given key.
+func (s *FileKeyStore) path(keyID string) (string, error) {
+	if err := ValidateKeyID(keyID); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, keyID+keyFileExtension), nil
+}
@@ -0,0 +1,67 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/pool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileKeyStore stores two wallets in the same directory, and checks that they are kept apart.
+func TestFileKeyStore(t *testing.T) {
+	defer func(params KDFParams) { FileKDFParams = params }(FileKDFParams)
+	FileKDFParams = testKDFParams[0]
+
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	configs1, partyIDs := test.GenerateConfig(group, 2, 1, rand.Reader, pl)
+	configs2, _ := test.GenerateConfig(group, 2, 1, rand.Reader, pl)
+	config1, config2 := configs1[partyIDs[0]], configs2[partyIDs[0]]
+
+	passphrase := []byte("passphrase")
+	store, err := NewFileKeyStore(t.TempDir(), passphrase)
+	require.NoError(t, err)
+
+	keyID1, err := KeyID(config1)
+	require.NoError(t, err)
+	assert.Len(t, keyID1, 66)
+	require.NoError(t, store.Put(keyID1, config1))
+	require.NoError(t, store.Put("wallet-2", config2))
+
+	keyIDs, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{keyID1, "wallet-2"}, keyIDs)
+
+	loaded, err := store.Get("wallet-2")
+	require.NoError(t, err)
+	assert.True(t, config2.PublicPoint().Equal(loaded.PublicPoint()))
+	assert.True(t, config2.ECDSA.Equal(loaded.ECDSA))
+
+	require.NoError(t, store.Rotate([]byte("new passphrase")))
+	loaded, err = store.Get(keyID1)
+	require.NoError(t, err)
+	assert.True(t, config1.PublicPoint().Equal(loaded.PublicPoint()))
+	oldStore, err := NewFileKeyStore(store.dir, passphrase)
+	require.NoError(t, err)
+	_, err = oldStore.Get(keyID1)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	require.NoError(t, store.Delete(keyID1))
+	_, err = store.Get(keyID1)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorIs(t, store.Delete(keyID1), ErrKeyNotFound)
+
+	for _, keyID := range []string{"", "../wallet", "a/b", ".hidden"} {
+		assert.ErrorIs(t, store.Put(keyID, config1), ErrInvalidKeyID, keyID)
+	}
+}
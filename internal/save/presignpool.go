@@ -0,0 +1,425 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/ecdsa3rounds"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/fxamacker/cbor/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// PresignKind is the protocol which generated a presignature.
+type PresignKind string
+
+const (
+	// Presign3Rounds presignatures are ecdsa3rounds.PreSignature3.
+	Presign3Rounds PresignKind = "presign3"
+	// Presign6Rounds presignatures are ecdsa.PreSignature.
+	Presign6Rounds PresignKind = "presign6"
+)
+
+// PresignState is the state of a presignature in a PresignPool, it only moves forward:
+// PresignAvailable -> PresignReserved -> PresignConsumed.
+// A reservation can be released back to PresignAvailable as long as it is not consumed.
+type PresignState uint8
+
+const (
+	// PresignAvailable presignatures can be reserved for a signature.
+	PresignAvailable PresignState = iota
+	// PresignReserved presignatures are held by a signature which has not released its share yet.
+	PresignReserved
+	// PresignConsumed presignatures are tombstones, their secret data has been deleted.
+	PresignConsumed
+)
+
+// the state of a presignature is the extension of its file, so that a state change is a single rename
+var presignStateExtensions = map[PresignState]string{
+	PresignAvailable: ".presign",
+	PresignReserved:  ".reserved",
+	PresignConsumed:  ".consumed",
+}
+
+// String implements fmt.Stringer.
+func (s PresignState) String() string {
+	switch s {
+	case PresignAvailable:
+		return "available"
+	case PresignReserved:
+		return "reserved"
+	case PresignConsumed:
+		return "consumed"
+	default:
+		return fmt.Sprintf("PresignState(%d)", uint8(s))
+	}
+}
+
+var (
+	// ErrPresignNotFound is returned when a presignature is not in the pool.
+	ErrPresignNotFound = errors.New("save: presignature not found")
+	// ErrPresignExists is returned when adding a presignature whose ID has already been used, even if it was consumed.
+	ErrPresignExists = errors.New("save: presignature ID already used")
+	// ErrPresignUnavailable is returned when reserving a presignature which is reserved or consumed.
+	ErrPresignUnavailable = errors.New("save: presignature is not available")
+	// ErrPresignNotReserved is returned when consuming or releasing a presignature which is not reserved by the lease.
+	ErrPresignNotReserved = errors.New("save: presignature is not reserved")
+)
+
+// PresignPool stores the presignatures of several keys, grouped by signer set, and makes sure that each of them is used at most once.
+// A presignature is reserved before signing, and consumed before its signature share is released:
+// consuming renames the file to a tombstone and deletes its content, so that a crash cannot bring it back.
+type PresignPool struct {
+	dir        string
+	passphrase []byte
+	// mtx serializes the state changes of this process, the renames protect against other processes.
+	mtx sync.Mutex
+}
+
+// PresignLease is a reserved presignature, it must be consumed before the signature share is released, or released if the signature is abandoned.
+type PresignLease struct {
+	Kind      PresignKind
+	KeyID     string
+	Signers   party.IDSlice
+	PresignID string
+	// data is the CBOR encoded presignature
+	data []byte
+	// path is the file of the presignature without its state extension
+	path  string
+	state PresignState
+}
+
+// NewPresignPool returns a PresignPool rooted at dir, the directory is created if it does not exist.
+// The presignatures are encrypted with passphrase using FileKDFParams.
+func NewPresignPool(dir string, passphrase []byte) (*PresignPool, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &PresignPool{
+		dir:        dir,
+		passphrase: passphrase,
+	}, nil
+}
+
+// Put adds an available presignature, generated by signers for the key keyID, to the pool.
+func (p *PresignPool) Put(kind PresignKind, keyID string, signers party.IDSlice, presignID string, data []byte) error {
+	base, err := p.path(kind, keyID, signers, presignID)
+	if err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if _, ok := stateOf(base); ok {
+		return fmt.Errorf("%w: %s", ErrPresignExists, presignID)
+	}
+	if err = os.MkdirAll(filepath.Dir(base), 0700); err != nil {
+		return err
+	}
+	encrypted, err := Encrypt(data, p.passphrase, FileKDFParams)
+	if err != nil {
+		return err
+	}
+	tmpPath := base + ".tmp"
+	if err = os.WriteFile(tmpPath, encrypted, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, base+presignStateExtensions[PresignAvailable]); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	log.Infof("done save %s %s of key %s", kind, presignID, keyID)
+	return nil
+}
+
+// Reserve moves the presignature presignID from available to reserved, and returns it.
+// If presignID is empty, the oldest available presignature of the signer set is reserved.
+func (p *PresignPool) Reserve(kind PresignKind, keyID string, signers party.IDSlice, presignID string) (*PresignLease, error) {
+	if presignID == "" {
+		available, err := p.Available(kind, keyID, signers)
+		if err != nil {
+			return nil, err
+		}
+		if len(available) == 0 {
+			return nil, fmt.Errorf("%w: the %s pool of key %s is empty", ErrPresignNotFound, kind, keyID)
+		}
+		presignID = available[0]
+	}
+	base, err := p.path(kind, keyID, signers, presignID)
+	if err != nil {
+		return nil, err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	state, ok := stateOf(base)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPresignNotFound, presignID)
+	}
+	if state != PresignAvailable {
+		return nil, fmt.Errorf("%w: %s is %s", ErrPresignUnavailable, presignID, state)
+	}
+	reservedPath := base + presignStateExtensions[PresignReserved]
+	// the rename fails if another process reserved it in the meantime
+	if err = os.Rename(base+presignStateExtensions[PresignAvailable], reservedPath); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrPresignUnavailable, presignID, err)
+	}
+	encrypted, err := os.ReadFile(reservedPath)
+	if err == nil {
+		var data []byte
+		if data, err = Decrypt(encrypted, p.passphrase); err == nil {
+			return &PresignLease{
+				Kind:      kind,
+				KeyID:     keyID,
+				Signers:   party.NewIDSlice(signers),
+				PresignID: presignID,
+				data:      data,
+				path:      base,
+				state:     PresignReserved,
+			}, nil
+		}
+	}
+	// the presignature could not be read, make it available again
+	_ = os.Rename(reservedPath, base+presignStateExtensions[PresignAvailable])
+	return nil, err
+}
+
+// Consume turns the reserved presignature of lease into a tombstone, it must be called before the signature share is released.
+// A consumed presignature can never be reserved again, even if the signature fails.
+func (p *PresignPool) Consume(lease *PresignLease) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if lease.state != PresignReserved {
+		return fmt.Errorf("%w: %s is %s", ErrPresignNotReserved, lease.PresignID, lease.state)
+	}
+	consumedPath := lease.path + presignStateExtensions[PresignConsumed]
+	// the rename is the point where the presignature is used
+	if err := os.Rename(lease.path+presignStateExtensions[PresignReserved], consumedPath); err != nil {
+		return err
+	}
+	lease.state = PresignConsumed
+	// delete the secret data, the empty file remains as a tombstone
+	if err := os.Truncate(consumedPath, 0); err != nil {
+		log.Errorf("fail to delete the data of consumed %s %s, err is %v", lease.Kind, lease.PresignID, err)
+	}
+	log.Infof("done consume %s %s of key %s", lease.Kind, lease.PresignID, lease.KeyID)
+	return nil
+}
+
+// Release makes the reserved presignature of lease available again, for signatures abandoned before their share was released.
+func (p *PresignPool) Release(lease *PresignLease) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if lease.state != PresignReserved {
+		return fmt.Errorf("%w: %s is %s", ErrPresignNotReserved, lease.PresignID, lease.state)
+	}
+	if err := os.Rename(lease.path+presignStateExtensions[PresignReserved], lease.path+presignStateExtensions[PresignAvailable]); err != nil {
+		return err
+	}
+	lease.state = PresignAvailable
+	return nil
+}
+
+// State returns the state of the presignature presignID.
+func (p *PresignPool) State(kind PresignKind, keyID string, signers party.IDSlice, presignID string) (PresignState, error) {
+	base, err := p.path(kind, keyID, signers, presignID)
+	if err != nil {
+		return 0, err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	state, ok := stateOf(base)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrPresignNotFound, presignID)
+	}
+	return state, nil
+}
+
+// Available returns the IDs of the available presignatures of a key and a signer set, the oldest first.
+func (p *PresignPool) Available(kind PresignKind, keyID string, signers party.IDSlice) ([]string, error) {
+	dir, err := p.signerSetDir(kind, keyID, signers)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	type presign struct {
+		id      string
+		modTime int64
+	}
+	presigns := make([]presign, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, presignStateExtensions[PresignAvailable]) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// it was reserved in the meantime
+			continue
+		}
+		presigns = append(presigns, presign{
+			id:      strings.TrimSuffix(name, presignStateExtensions[PresignAvailable]),
+			modTime: info.ModTime().UnixNano(),
+		})
+	}
+	sort.Slice(presigns, func(i, j int) bool {
+		if presigns[i].modTime != presigns[j].modTime {
+			return presigns[i].modTime < presigns[j].modTime
+		}
+		return presigns[i].id < presigns[j].id
+	})
+	presignIDs := make([]string, len(presigns))
+	for i, ps := range presigns {
+		presignIDs[i] = ps.id
+	}
+	return presignIDs, nil
+}
+
+// Depth returns the number of available presignatures of a key and a signer set,
+// so that the pool can be topped up when it runs low.
+func (p *PresignPool) Depth(kind PresignKind, keyID string, signers party.IDSlice) (int, error) {
+	available, err := p.Available(kind, keyID, signers)
+	if err != nil {
+		return 0, err
+	}
+	return len(available), nil
+}
+
+// Rotate encrypts all the presignatures of the pool again under newPassphrase, which is used from now on.
+func (p *PresignPool) Rotate(newPassphrase []byte) error {
+	if len(newPassphrase) == 0 {
+		return ErrEmptyPassphrase
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	err := filepath.WalkDir(p.dir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// the tombstones have no content
+		ext := filepath.Ext(filePath)
+		if entry.IsDir() || (ext != presignStateExtensions[PresignAvailable] && ext != presignStateExtensions[PresignReserved]) {
+			return nil
+		}
+		return rotateFile(filePath, p.passphrase, newPassphrase)
+	})
+	if err != nil {
+		return err
+	}
+	p.passphrase = newPassphrase
+	return nil
+}
+
+// PutPresign3 adds a presignature generated by Presign3rounds to the pool.
+func (p *PresignPool) PutPresign3(keyID string, signers party.IDSlice, presignID string, preSignature *ecdsa3rounds.PreSignature3) error {
+	data, err := cbor.Marshal(preSignature)
+	if err != nil {
+		log.Errorf("fail to marshal presign3 result , err is %v", err)
+		return err
+	}
+	return p.Put(Presign3Rounds, keyID, signers, presignID, data)
+}
+
+// ReservePresign3 reserves a presignature generated by Presign3rounds, see Reserve.
+func (p *PresignPool) ReservePresign3(keyID string, signers party.IDSlice, presignID string) (*ecdsa3rounds.PreSignature3, *PresignLease, error) {
+	lease, err := p.Reserve(Presign3Rounds, keyID, signers, presignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	preSignature := ecdsa3rounds.EmptyPreSignature(curve.Secp256k1{})
+	if err = cbor.Unmarshal(lease.data, preSignature); err != nil {
+		_ = p.Release(lease)
+		return nil, nil, err
+	}
+	return preSignature, lease, nil
+}
+
+// PutPresign6 adds a presignature generated by Presign to the pool.
+func (p *PresignPool) PutPresign6(keyID string, signers party.IDSlice, presignID string, preSignature *ecdsa.PreSignature) error {
+	data, err := cbor.Marshal(preSignature)
+	if err != nil {
+		log.Errorf("fail to marshal presign6 result , err is %v", err)
+		return err
+	}
+	return p.Put(Presign6Rounds, keyID, signers, presignID, data)
+}
+
+// ReservePresign6 reserves a presignature generated by Presign, see Reserve.
+func (p *PresignPool) ReservePresign6(keyID string, signers party.IDSlice, presignID string) (*ecdsa.PreSignature, *PresignLease, error) {
+	lease, err := p.Reserve(Presign6Rounds, keyID, signers, presignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	preSignature := ecdsa.EmptyPreSignature(curve.Secp256k1{})
+	if err = cbor.Unmarshal(lease.data, preSignature); err != nil {
+		_ = p.Release(lease)
+		return nil, nil, err
+	}
+	return preSignature, lease, nil
+}
+
+// signerSetDir returns the directory of the presignatures of a key and a signer set.
+// The signer set is identified by the hash of its sorted IDs, so that the order of the signers does not matter.
+func (p *PresignPool) signerSetDir(kind PresignKind, keyID string, signers party.IDSlice) (string, error) {
+	if kind != Presign3Rounds && kind != Presign6Rounds {
+		return "", fmt.Errorf("save: unknown presignature kind %q", kind)
+	}
+	if err := ValidateKeyID(keyID); err != nil {
+		return "", err
+	}
+	if len(signers) == 0 {
+		return "", errors.New("save: empty signer set")
+	}
+	h := sha256.New()
+	for _, id := range party.NewIDSlice(signers) {
+		h.Write([]byte(id))
+		h.Write([]byte{0})
+	}
+	return filepath.Join(p.dir, keyID, string(kind), hex.EncodeToString(h.Sum(nil)[:16])), nil
+}
+
+// path returns the file of a presignature, without its state extension.
+func (p *PresignPool) path(kind PresignKind, keyID string, signers party.IDSlice, presignID string) (string, error) {
+	dir, err := p.signerSetDir(kind, keyID, signers)
+	if err != nil {
+		return "", err
+	}
+	if !keyIDPattern.MatchString(presignID) {
+		return "", fmt.Errorf("save: invalid presignature ID %q", presignID)
+	}
+	return filepath.Join(dir, presignID), nil
+}
+
+// stateOf returns the state of the presignature stored at base, and false if there is none.
+func stateOf(base string) (PresignState, bool) {
+	// check the states in the order they are reached, so that a concurrent state change is never seen as missing
+	for _, state := range []PresignState{PresignAvailable, PresignReserved, PresignConsumed} {
+		if _, err := os.Stat(base + presignStateExtensions[state]); err == nil {
+			return state, true
+		}
+	}
+	return 0, false
+}
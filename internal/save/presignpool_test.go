@@ -0,0 +1,120 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package save
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPresignPool walks a presignature through its states, and checks that it can only be used once.
+func TestPresignPool(t *testing.T) {
+	defer func(params KDFParams) { FileKDFParams = params }(FileKDFParams)
+	FileKDFParams = testKDFParams[0]
+
+	pool, err := NewPresignPool(t.TempDir(), []byte("passphrase"))
+	require.NoError(t, err)
+	signers := party.IDSlice{"b", "a"}
+
+	require.NoError(t, pool.Put(Presign3Rounds, "wallet", signers, "1", []byte("first")))
+	require.NoError(t, pool.Put(Presign3Rounds, "wallet", signers, "2", []byte("second")))
+	assert.ErrorIs(t, pool.Put(Presign3Rounds, "wallet", signers, "1", []byte("again")), ErrPresignExists)
+
+	// the pools are per key, kind and signer set, in any order
+	depth, err := pool.Depth(Presign3Rounds, "wallet", party.IDSlice{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, depth)
+	for _, other := range []struct {
+		kind    PresignKind
+		keyID   string
+		signers party.IDSlice
+	}{{Presign6Rounds, "wallet", signers}, {Presign3Rounds, "other", signers}, {Presign3Rounds, "wallet", party.IDSlice{"a", "c"}}} {
+		depth, err = pool.Depth(other.kind, other.keyID, other.signers)
+		require.NoError(t, err)
+		assert.Equal(t, 0, depth)
+	}
+
+	// the oldest presignature is reserved first
+	lease, err := pool.Reserve(Presign3Rounds, "wallet", signers, "")
+	require.NoError(t, err)
+	assert.Equal(t, "1", lease.PresignID)
+	assert.Equal(t, []byte("first"), lease.data)
+	_, err = pool.Reserve(Presign3Rounds, "wallet", signers, "1")
+	assert.ErrorIs(t, err, ErrPresignUnavailable)
+	depth, err = pool.Depth(Presign3Rounds, "wallet", signers)
+	require.NoError(t, err)
+	assert.Equal(t, 1, depth)
+
+	// an abandoned reservation can be used again
+	require.NoError(t, pool.Release(lease))
+	assert.ErrorIs(t, pool.Consume(lease), ErrPresignNotReserved)
+	lease, err = pool.Reserve(Presign3Rounds, "wallet", signers, "1")
+	require.NoError(t, err)
+
+	// a consumed presignature is a tombstone forever
+	require.NoError(t, pool.Consume(lease))
+	state, err := pool.State(Presign3Rounds, "wallet", signers, "1")
+	require.NoError(t, err)
+	assert.Equal(t, PresignConsumed, state)
+	info, err := os.Stat(lease.path + presignStateExtensions[PresignConsumed])
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+	assert.ErrorIs(t, pool.Release(lease), ErrPresignNotReserved)
+	_, err = pool.Reserve(Presign3Rounds, "wallet", signers, "1")
+	assert.ErrorIs(t, err, ErrPresignUnavailable)
+	assert.ErrorIs(t, pool.Put(Presign3Rounds, "wallet", signers, "1", []byte("again")), ErrPresignExists)
+
+	_, err = pool.Reserve(Presign3Rounds, "wallet", signers, "3")
+	assert.ErrorIs(t, err, ErrPresignNotFound)
+
+	// the remaining presignature survives a passphrase rotation
+	require.NoError(t, pool.Rotate([]byte("new passphrase")))
+	lease, err = pool.Reserve(Presign3Rounds, "wallet", signers, "")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), lease.data)
+	require.NoError(t, pool.Consume(lease))
+	_, err = pool.Reserve(Presign3Rounds, "wallet", signers, "")
+	assert.ErrorIs(t, err, ErrPresignNotFound)
+}
+
+// TestPresignPoolConcurrentReserve checks that a presignature reserved by several goroutines at once is only handed out once.
+func TestPresignPoolConcurrentReserve(t *testing.T) {
+	defer func(params KDFParams) { FileKDFParams = params }(FileKDFParams)
+	FileKDFParams = testKDFParams[0]
+
+	dir := t.TempDir()
+	signers := party.IDSlice{"a", "b"}
+	pool, err := NewPresignPool(dir, []byte("passphrase"))
+	require.NoError(t, err)
+	require.NoError(t, pool.Put(Presign6Rounds, "wallet", signers, "1", []byte("presign")))
+
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	reserved := 0
+	for i := 0; i < 8; i++ {
+		// separate pools share no lock, as separate processes
+		other, err := NewPresignPool(dir, []byte("passphrase"))
+		require.NoError(t, err)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := other.Reserve(Presign6Rounds, "wallet", signers, "1"); err == nil {
+				mtx.Lock()
+				reserved++
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, reserved)
+}
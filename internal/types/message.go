@@ -20,3 +20,18 @@ func (t SigningMessage) Domain() string {
 	}
 	return "Signature Message"
 }
+
+// DigestScheme names the function used to compute a SigningMessage from the data being signed.
+// Adding it to a session makes all the parties agree on it.
+type DigestScheme string
+
+// WriteTo implements io.WriterTo interface.
+func (t DigestScheme) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, string(t))
+	return int64(n), err
+}
+
+// Domain implements hash.WriterToWithDomain.
+func (DigestScheme) Domain() string {
+	return "Digest Scheme"
+}
@@ -19,42 +19,142 @@ package main
 import (
 	"MPC_ECDSA/communication"
 	"MPC_ECDSA/internal/save"
-	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/ecdsa"
 	"MPC_ECDSA/pkg/ecdsa3rounds"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
 	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/proposal"
 	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/pkg/taproot"
 	"MPC_ECDSA/protocols"
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"github.com/fxamacker/cbor/v2"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 //var done = make(chan bool)
 
 // printTips() function is responsible for printing a menu of available options for executing different stages of a protocol.
 func printTips() {
-	fmt.Println("\nConnection is completed, please type the name of stage you want to execute:(e.g. PreSign3 10086)")
-	fmt.Println("[-] KeyGen")
-	fmt.Println("[-] KeyRefresh")
-	fmt.Println("[-] KeyReshare")
-	fmt.Println("[-] PreSign3 <presign_id>")
-	fmt.Println("[-] SignAfterPreSign3 <presign_id>")
-	fmt.Println("[-] PreSign6 <presign_id>")
-	fmt.Println("[-] SignAfterPreSign6 <presign_id>")
-	fmt.Println("[-] Sign")
+	fmt.Println("\nConnection is completed, please type the name of stage you want to execute:(e.g. PreSign3 10086 --key-id wallet1)")
+	fmt.Println("[-] KeyGen [--key-id <key_id>]")
+	fmt.Println("[-] KeyImport [--key-id <key_id>]")
+	fmt.Println("[-] KeyRefresh --key-id <key_id>")
+	fmt.Println("[-] KeyRefreshAux --key-id <key_id>")
+	fmt.Println("[-] KeyReshare [--key-id <key_id>]")
+	fmt.Println("[-] KeyRepair <lost_id> --key-id <key_id>")
+	fmt.Println("[-] PreSign3 [<presign_id>] --key-id <key_id>")
+	fmt.Println("[-] SignAfterPreSign3 [<presign_id>] --key-id <key_id>")
+	fmt.Println("[-] PreSign6 [<presign_id>] --key-id <key_id>")
+	fmt.Println("[-] SignAfterPreSign6 [<presign_id>] --key-id <key_id>")
+	fmt.Println("[-] PresignPool --key-id <key_id>")
+	fmt.Println("[-] Sign --key-id <key_id>")
+	fmt.Println("[-] ListKeys")
 	fmt.Println("[-] Ctrl+c to exit")
 	fmt.Printf(">>> ")
 }
 
+const (
+	// passphraseEnv is the environment variable holding the passphrase of the saved key shares and presignatures.
+	passphraseEnv = "MPC_ECDSA_PASSPHRASE"
+	// newPassphraseEnv is the environment variable holding a new passphrase,
+	// if it is set the saved files are encrypted again under it at startup.
+	newPassphraseEnv = "MPC_ECDSA_NEW_PASSPHRASE"
+	// allowPlaintextEnv is the environment variable which, set to 1, lets the files saved in plaintext by earlier versions
+	// be loaded, until they are encrypted by a passphrase rotation.
+	allowPlaintextEnv = "MPC_ECDSA_ALLOW_PLAINTEXT"
+	// importKeyEnv is the environment variable holding the hex encoded private key shared by the dealer of KeyImport.
+	importKeyEnv = "MPC_ECDSA_IMPORT_KEY"
+	// proposalKeyEnv is the environment variable holding the hex encoded BIP-340 secret key
+	// with which the center server signs its session proposals.
+	proposalKeyEnv = "MPC_ECDSA_PROPOSAL_KEY"
+)
+
+// passphrase returns the passphrase used to encrypt the saved key shares and presignatures.
+func passphrase() []byte {
+	return []byte(os.Getenv(passphraseEnv))
+}
+
+// handlerOptions returns the options of the MultiHandlers, built from the connection config.
+func handlerOptions(localConn *communication.LocalConn) []protocol.HandlerOption {
+	return []protocol.HandlerOption{
+		protocol.WithRoundTimeout(time.Duration(localConn.LocalConfig.RoundTimeoutSecond) * time.Second),
+	}
+}
+
+// stageResult is the outcome of a stage for the local party, reported by the API of the daemon.
+type stageResult struct {
+	// KeyID is the key created or used by the stage
+	KeyID string `json:"keyID,omitempty"`
+	// PublicKey is the hex encoded compressed public key of the key
+	PublicKey string `json:"publicKey,omitempty"`
+	// PresignIDs are the presignatures generated by the stage
+	PresignIDs []string `json:"presignIDs,omitempty"`
+	// Signature is the signature computed by the stage
+	Signature *signatureResult `json:"signature,omitempty"`
+	// KeyIDs are the keys of the key store, listed by ListKeys
+	KeyIDs []string `json:"keyIDs,omitempty"`
+	// Available is the number of available presignatures of each kind, counted by PresignPool
+	Available map[save.PresignKind]int `json:"available,omitempty"`
+}
+
+// signatureResult is a low-S signature, with the digest it signs.
+type signatureResult struct {
+	Scheme digest.Scheme `json:"scheme"`
+	// Digest, R and S are hex encoded
+	Digest string `json:"digest"`
+	R      string `json:"r"`
+	S      string `json:"s"`
+	// V is the recovery ID of the signature
+	V byte `json:"v"`
+}
+
+// keyResult returns the result of a stage which creates or updates the key keyID.
+func keyResult(keyID string, config *protocols.Config) (*stageResult, error) {
+	publicKey, err := config.PublicPoint().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &stageResult{KeyID: keyID, PublicKey: hex.EncodeToString(publicKey)}, nil
+}
+
+// signResultOf returns the result of a stage which signs the digest d with the key keyID.
+func signResultOf(keyID string, config *protocols.Config, d digest.Digest, signature interface{ CompactRecoverable() ([]byte, error) }) (*stageResult, error) {
+	result, err := keyResult(keyID, config)
+	if err != nil {
+		return nil, err
+	}
+	// r || s || v
+	compact, err := signature.CompactRecoverable()
+	if err != nil {
+		return nil, err
+	}
+	result.Signature = &signatureResult{
+		Scheme: d.Scheme,
+		Digest: hex.EncodeToString(d.Hash),
+		R:      hex.EncodeToString(compact[:32]),
+		S:      hex.EncodeToString(compact[32:64]),
+		V:      compact[64],
+	}
+	return result, nil
+}
+
 // KeyGen function is responsible for executing the Key Generation stage of the protocol.
 // It takes a local connection (localConn), a network (n), and a pool (pl) as input.
-func KeyGen(localConn *communication.LocalConn, pl *pool.Pool) error {
+func KeyGen(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into KeyGen func")
 	//Retrieve the local ID, party IDs, threshold, and useMnemonic flag from localConn.LocalConfig
 	id := localConn.LocalConfig.LocalID
@@ -62,527 +162,1299 @@ func KeyGen(localConn *communication.LocalConn, pl *pool.Pool) error {
 	threshold := localConn.LocalConfig.Threshold
 	useMnemonic := localConn.LocalConfig.UseMnemonic
 	//Create a new MultiHandler with the Keygen protocol instantiated with the Secp256k1 curve, the local ID, party IDs, threshold, pool, and useMnemonic flag.
-	h, err := protocol.NewMultiHandler(protocols.Keygen(curve.Secp256k1{}, id, ids, threshold, pl, useMnemonic), nil, *localConn)
+	h, err := protocol.NewMultiHandler(protocols.Keygen(curve.Secp256k1{}, id, ids, threshold, pl, useMnemonic), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Handle the protocol execution by calling the Result method on the MultiHandler.
 	//This blocks until the protocol execution is complete and returns the result (r) or an error (if any).
-	r, err := h.Result()
+	r, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Save the protocol configuration (r) to the local connection.
 	config := r.(*protocols.Config)
-	err = save.SaveKeyGenResult(config)
+	//Encrypt the backup mnemonic to the operator, so that the key store never holds it in plaintext.
+	if config.Mnemonic != "" && localConn.LocalConfig.MnemonicRecipient != "" {
+		operator, err := decodePublicKey(localConn.LocalConfig.MnemonicRecipient)
+		if err != nil {
+			log.Errorln("invalid mnemonicRecipient")
+			return nil, err
+		}
+		if err = config.EncryptMnemonic(operator); err != nil {
+			log.Errorln("fail to encrypt the mnemonic")
+			return nil, err
+		}
+	}
+	//Use the public key as key ID if the user did not choose one.
+	if keyID == "" {
+		if keyID, err = save.KeyID(config); err != nil {
+			return nil, err
+		}
+	}
+	err = store.Put(keyID, config)
 	if err != nil {
 		log.Errorln("fail to save keygen result")
-		return err
+		return nil, err
 	}
+	log.Infof("key ID is %s", keyID)
 
 	log.Infoln("successfully key generation")
-	return nil
+	return keyResult(keyID, config)
+}
+
+// KeyImport function shares an existing private key among the parties, instead of generating a new one.
+// The dealer reads the private key from importKeyEnv, and every party checks the shared key against importPublicKey.
+func KeyImport(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, pl *pool.Pool) (*stageResult, error) {
+	log.Infoln("step into KeyImport func")
+	id := localConn.LocalConfig.LocalID
+	ids := party.NewIDSlice(localConn.LocalConfig.PartyIDs)
+	threshold := localConn.LocalConfig.Threshold
+	dealer := localConn.LocalConfig.ImportDealer
+	publicKey, err := decodePublicKey(localConn.LocalConfig.ImportPublicKey)
+	if err != nil {
+		log.Errorln("invalid importPublicKey")
+		return nil, err
+	}
+	//Only the dealer holds the private key, it is erased once shared.
+	var secret curve.Scalar
+	if id == dealer {
+		if secret, err = importKey(); err != nil {
+			log.Errorf("invalid %s", importKeyEnv)
+			return nil, err
+		}
+	}
+	h, err := protocol.NewMultiHandler(protocols.KeyImport(curve.Secp256k1{}, id, ids, threshold, dealer, publicKey, secret, pl), sessionID, mux, handlerOptions(localConn)...)
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	r, err := h.Result(context.Background())
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	config := r.(*protocols.Config)
+	//Use the public key as key ID if the user did not choose one.
+	if keyID == "" {
+		if keyID, err = save.KeyID(config); err != nil {
+			return nil, err
+		}
+	}
+	err = store.Put(keyID, config)
+	if err != nil {
+		log.Errorln("fail to save key import result")
+		return nil, err
+	}
+	log.Infof("key ID is %s", keyID)
+
+	log.Infoln("successfully key import")
+	return keyResult(keyID, config)
+}
+
+// importKey returns the private key of the dealer of KeyImport, hex encoded in importKeyEnv.
+func importKey() (curve.Scalar, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(os.Getenv(importKeyEnv), "0x"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for i := range data {
+			data[i] = 0
+		}
+	}()
+	secret := curve.Secp256k1{}.NewScalar()
+	if err = secret.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if secret.IsZero() {
+		return nil, errors.New("private key is zero")
+	}
+	return secret, nil
 }
 
 // KeyRefresh function is used to perform the key refresh step.
-func KeyRefresh(localConn *communication.LocalConn, pl *pool.Pool) error {
+func KeyRefresh(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into KeyRefresh func")
 	// reload keygen config
 	err := localConn.LoadKeyGenConfig()
 	if err != nil {
 		log.Errorln("fail to load keygen config")
-		return err
+		return nil, err
 	}
 	log.Infoln("reload keygen config success")
 
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previous keygen config success")
 	//Create a new MultiHandler object (hRefresh) with the protocol configuration and the connection pool, to execute the refresh protocol.
-	hRefresh, err := protocol.NewMultiHandler(protocols.Refresh(config, pl), nil, *localConn)
+	hRefresh, err := protocol.NewMultiHandler(protocols.Refresh(config, pl), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Check the result of the refresh protocol execution
-	r, err := hRefresh.Result()
+	r, err := hRefresh.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Save the protocol configuration (r) to the local connection.
 	refreshConfig := r.(*protocols.Config)
-	err = save.SaveKeyGenResult(refreshConfig)
+	err = store.Put(keyID, refreshConfig)
 	if err != nil {
 		log.Errorln("fail to save keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("successfully key refresh")
-	return nil
+	return keyResult(keyID, refreshConfig)
+}
+
+// KeyRefreshAux function rotates the Paillier keys and Pedersen parameters of a key, without changing its shares.
+func KeyRefreshAux(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, pl *pool.Pool) (*stageResult, error) {
+	log.Infoln("step into KeyRefreshAux func")
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
+	if err != nil {
+		log.Errorln("fail to load keygen result")
+		return nil, err
+	}
+	h, err := protocol.NewMultiHandler(protocols.RefreshAuxInfo(config, pl), sessionID, mux, handlerOptions(localConn)...)
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	r, err := h.Result(context.Background())
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	auxConfig := r.(*protocols.Config)
+	err = store.Put(keyID, auxConfig)
+	if err != nil {
+		log.Errorln("fail to save aux info refresh result")
+		return nil, err
+	}
+	log.Infoln("successfully aux info refresh")
+	return keyResult(keyID, auxConfig)
 }
 
 // KeyRefresh function performs the (t,n)key-resharing step for a specific protocol.
-func KeyReshare(localConn *communication.LocalConn, pl *pool.Pool) error {
+func KeyReshare(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into KeyResharing func")
 	err := localConn.LoadRefreshConfig()
 	if err != nil {
 		log.Errorln("fail to load keygen config")
-		//return err
+		//return nil, err
 	}
 	log.Infoln("reload resharing config success")
 	var Myconfig interface{}
 	if localConn.LocalConfig.IsOldCommittee {
-		Myconfig, _ = save.LoadKeyGenResult2()
+		Myconfig, err = store.Get(keyID)
 		if err != nil {
 			log.Errorln("fail to load keygen result")
+			return nil, err
 		}
 		log.Infoln("load previous keygen config success")
 	} else {
 		Myconfig = nil
 	}
-	hReshare, err := protocol.NewMultiHandlerReshare(protocols.Resharing(localConn, pl, Myconfig), nil, *localConn)
-	//hReshare, err := protocol.NewMultiHandlerReshare(nil, nil, *localConn)
+	//The rounds of the resharing declare from which committee they receive messages.
+	hReshare, err := protocol.NewMultiHandler(protocols.Resharing(localConn, pl, Myconfig), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Check the result of the refresh protocol execution
-	r, err := hReshare.Result()
+	r, err := hReshare.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//Save the protocol configuration (r) to the local connection.
 	reshareConfig := r.(*protocols.Config)
-	//user can save reshare result to keygen result file or refresh result file
-	//err = save.SaveKeyGenResult(reshareConfig)
-	err = save.SaveReshareResult(reshareConfig)
-
+	//The new parties do not have the key yet, use the public key as key ID if the user did not choose one.
+	if keyID == "" {
+		if keyID, err = save.KeyID(reshareConfig); err != nil {
+			return nil, err
+		}
+	}
+	err = store.Put(keyID, reshareConfig)
 	if err != nil {
-		log.Errorln("fail to save keygen result")
-		return err
+		log.Errorln("fail to save reshare result")
+		return nil, err
 	}
+	log.Infof("key ID is %s", keyID)
 	log.Infoln("successfully key resharing")
-	return nil
+	return keyResult(keyID, reshareConfig)
+}
+
+// KeyRepair function recovers the key share of the party `lost`, which lost it, from the other parties, which must all be connected.
+// The other parties update the public data of `lost` in their key share, while `lost` gets its key share back,
+// checked against repairPublicKey.
+func KeyRepair(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, lost party.ID, pl *pool.Pool) (*stageResult, error) {
+	log.Infoln("step into KeyRepair func")
+	id := localConn.LocalConfig.LocalID
+	// every party of the key takes part to update the public data of lost, and all but lost help
+	partyIDs := party.NewIDSlice(append([]party.ID{id}, localConn.LocalConfig.OtherPartyIDs...))
+	helpers := partyIDs.Remove(lost)
+	var startFunc protocol.StartFunc
+	if id == lost {
+		// reload keygen config
+		err := localConn.LoadKeyGenConfig()
+		if err != nil {
+			log.Errorln("fail to load keygen config")
+			return nil, err
+		}
+		publicKey, err := decodePublicKey(localConn.LocalConfig.RepairPublicKey)
+		if err != nil {
+			log.Errorln("invalid repairPublicKey")
+			return nil, err
+		}
+		startFunc = protocols.KeyRecover(curve.Secp256k1{}, id, partyIDs, helpers, localConn.LocalConfig.Threshold, publicKey, pl)
+	} else {
+		// load keygen result from the key store
+		config, err := store.Get(keyID)
+		if err != nil {
+			log.Errorln("fail to load keygen result")
+			return nil, err
+		}
+		startFunc = protocols.KeyRepair(config, lost, helpers, pl)
+	}
+	h, err := protocol.NewMultiHandler(startFunc, sessionID, mux, handlerOptions(localConn)...)
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	r, err := h.Result(context.Background())
+	if err != nil {
+		log.Errorln(err)
+		return nil, err
+	}
+	repairConfig := r.(*protocols.Config)
+	err = store.Put(keyID, repairConfig)
+	if err != nil {
+		log.Errorln("fail to save key repair result")
+		return nil, err
+	}
+	log.Infoln("successfully key repair")
+	return keyResult(keyID, repairConfig)
 }
 
 // PreSign3rounds function performs the pre-signing step for a specific protocol.
-func PreSign3rounds(localConn *communication.LocalConn, presignID string, pl *pool.Pool) error {
+func PreSign3rounds(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, presigns *save.PresignPool, keyID string, presignID string, signers party.IDSlice, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into PreSign3rounds func")
+	//All the parties share the session ID, use it to name the presignature if the user did not choose a name.
+	if presignID == "" {
+		presignID = hex.EncodeToString(sessionID[:8])
+	}
 	// reload sign config
 	err := localConn.LoadSignConfig()
 	if err != nil {
 		log.Errorln("fail to load sign config")
-		return err
+		return nil, err
 	}
 	log.Infoln("reload sign config success")
-	log.Infof("presign signers is %+v\n", localConn.LocalConfig.Signers)
-	// Get the signers participating in the signature
-	signers := party.NewIDSlice(localConn.LocalConfig.Signers)
+	log.Infof("presign signers is %+v\n", signers)
 	//retrieve the local ID
 	id := localConn.LocalConfig.LocalID
 	if !signers.Contains(id) {
 		log.Infoln("Not a signatory participant, exit")
-		return nil
+		return &stageResult{KeyID: keyID}, nil
 	}
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previous keygen config success")
 	//Create a new MultiHandler for the Presign protocol
-	h, err := protocol.NewMultiHandler(protocols.Presign3rounds(config, signers, pl), nil, *localConn) //handler表示一个协议的执行
+	h, err := protocol.NewMultiHandler(protocols.Presign3rounds(config, signers, presignCount(localConn), pl), sessionID, mux, handlerOptions(localConn)...) //handler表示一个协议的执行
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	// Get the result of the protocol execution
-	preSignResult, err := h.Result()
+	preSignResult, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//convert the result from the interface type to the specific PreSignature type.
-	preSignature := preSignResult.(*ecdsa3rounds.PreSignature3)
-	// Validate the pre-signature
-	if err = preSignature.Validate(); err != nil {
-		log.Errorln("failed to verify cmp presignature")
-		return err
-	}
-	// save presign result to fixture file
-	err = save.SavePresign3Result(preSignature, presignID)
-	if err != nil {
-		log.Errorln("fail to save presign result")
-		return err
+	preSignatures := preSignResult.([]*ecdsa3rounds.PreSignature3)
+	result := &stageResult{KeyID: keyID}
+	for l, preSignature := range preSignatures {
+		// Validate the pre-signature
+		if err = preSignature.Validate(); err != nil {
+			log.Errorln("failed to verify cmp presignature")
+			return nil, err
+		}
+		// add the presignature to the pool of the key and the signers
+		err = presigns.PutPresign3(keyID, signers, batchPresignID(presignID, l, len(preSignatures)), preSignature)
+		if err != nil {
+			log.Errorln("fail to save presign result")
+			return nil, err
+		}
+		result.PresignIDs = append(result.PresignIDs, batchPresignID(presignID, l, len(preSignatures)))
 	}
+	logPresignDepth(localConn, presigns, save.Presign3Rounds, keyID, signers)
 	log.Infoln("successfully presSign")
-	return nil
+	return result, nil
 }
 
 // SignAfterPreSign3rounds function performs the signing operation after the pre-signing stage.
-func SignAfterPreSign3rounds(localConn *communication.LocalConn, presignID string, pl *pool.Pool) error {
+func SignAfterPreSign3rounds(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, presigns *save.PresignPool, keyID string, presignID string, signers party.IDSlice, d digest.Digest, guard policy.Policy, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into SignAfterPreSign3rounds func, presignID is ", presignID)
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+	if !signers.Contains(localConn.LocalConfig.LocalID) {
+		log.Infoln("Not a signatory participant, exit")
+		return &stageResult{KeyID: keyID}, nil
+	}
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previous keygen config success")
-	// Get the signers participating in the signature
-	signers := party.NewIDSlice(localConn.LocalConfig.Signers)
-	//obtain the message to sign
-	message := []byte(localConn.LocalConfig.MessageToSign)
 	//retrieve the pre-signature
-	preSignature, err := save.LoadPresign3Result(presignID)
+	preSignature, lease, err := presigns.ReservePresign3(keyID, signers, presignID)
 	if err != nil {
 		log.Errorln("fail to load presign result")
-		return err
+		return nil, err
+	}
+	//the presignature is used up before the signature share is released, even if the signature fails afterwards
+	if err = presigns.Consume(lease); err != nil {
+		log.Errorln("fail to consume presign result")
+		_ = presigns.Release(lease)
+		return nil, err
 	}
 	//create a new multihandler (h) using the SignAfterPresign protocol
-	h, err := protocol.NewMultiHandler(protocols.SignAfterPresign3rounds(config, signers, preSignature, message, pl), nil, *localConn)
+	h, err := protocol.NewMultiHandler(protocols.SignAfterPresign3roundsDigest(config, signers, preSignature, d, pl, guard), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//retrieve the sign result
-	signResult, err := h.Result()
+	signResult, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln("SignAfterPreSign: failed to get signResult")
-		return err
+		return nil, err
 	}
 	signature := signResult.(*ecdsa3rounds.Signature)
 	//verify the signature
-	if !signature.Verify(config.PublicPoint(), message) {
+	if !signature.Verify(config.PublicPoint(), d.Hash) {
 		log.Errorln("SignAfterPreSign: failed to verify cmp signature")
-		return errors.New("failed to verify cmp signature")
+		return nil, errors.New("failed to verify cmp signature")
 	}
 
 	log.Infoln("successfully sign message after presign")
-	// delete presign result from fixture file after use
-	err = save.DeletePreSign3Result(presignID)
-	if err != nil {
-		log.Errorf("fail to delete presign result, err is %v", err)
-	}
-	log.Infoln("successfully delete presign result")
-	return nil
+	logPresignDepth(localConn, presigns, save.Presign3Rounds, keyID, signers)
+	return signResultOf(keyID, config, d, signature)
 }
 
 // PreSign6rounds function performs the pre-signing step for a specific protocol.
-func PreSign6rounds(localConn *communication.LocalConn, presignID string, pl *pool.Pool) error {
+func PreSign6rounds(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, presigns *save.PresignPool, keyID string, presignID string, signers party.IDSlice, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into PreSign6rounds func")
+	//All the parties share the session ID, use it to name the presignature if the user did not choose a name.
+	if presignID == "" {
+		presignID = hex.EncodeToString(sessionID[:8])
+	}
 	// reload sign config
 	err := localConn.LoadSignConfig()
 	if err != nil {
 		log.Errorln("fail to load sign config")
-		return err
+		return nil, err
 	}
 	log.Infoln("reload sign config success")
-	log.Infof("presign signers is %+v\n", localConn.LocalConfig.Signers)
-	// Get the signers participating in the signature
-	signers := party.NewIDSlice(localConn.LocalConfig.Signers)
+	log.Infof("presign signers is %+v\n", signers)
 	//retrieve the local ID
 	id := localConn.LocalConfig.LocalID
 	if !signers.Contains(id) {
 		log.Infoln("Not a signatory participant, exit")
-		return nil
+		return &stageResult{KeyID: keyID}, nil
 	}
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previos keygen config success")
 	//Create a new MultiHandler for the Presign protocol
-	h, err := protocol.NewMultiHandler(protocols.Presign(config, signers, pl), nil, *localConn)
+	h, err := protocol.NewMultiHandler(protocols.Presign(config, signers, presignCount(localConn), pl), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	// Get the result of the protocol execution
-	preSignResult, err := h.Result()
+	preSignResult, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//convert the result from the interface type to the specific PreSignature type.
-	preSignature := preSignResult.(*ecdsa.PreSignature)
-	// Validate the pre-signature
-	if err = preSignature.Validate(); err != nil {
-		log.Errorln("failed to verify cmp presignature")
-		return err
-	}
-	// save presign result to fixture file
-	if err = save.SavePresign6Result(preSignature, presignID); err != nil {
-		log.Errorln("fail to save presign result")
-		return err
+	preSignatures := preSignResult.([]*ecdsa.PreSignature)
+	result := &stageResult{KeyID: keyID}
+	for l, preSignature := range preSignatures {
+		// Validate the pre-signature
+		if err = preSignature.Validate(); err != nil {
+			log.Errorln("failed to verify cmp presignature")
+			return nil, err
+		}
+		// add the presignature to the pool of the key and the signers
+		if err = presigns.PutPresign6(keyID, signers, batchPresignID(presignID, l, len(preSignatures)), preSignature); err != nil {
+			log.Errorln("fail to save presign result")
+			return nil, err
+		}
+		result.PresignIDs = append(result.PresignIDs, batchPresignID(presignID, l, len(preSignatures)))
 	}
+	logPresignDepth(localConn, presigns, save.Presign6Rounds, keyID, signers)
 	log.Infoln("successfully presSign")
-	return nil
+	return result, nil
 }
 
 // SignAfterPreSign6rounds function performs the signing operation after the pre-signing stage.
-func SignAfterPreSign6rounds(localConn *communication.LocalConn, presignID string, pl *pool.Pool) error {
-	log.Infoln("step into SignAfterPreSign6rounds func")
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+func SignAfterPreSign6rounds(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, presigns *save.PresignPool, keyID string, presignID string, signers party.IDSlice, d digest.Digest, guard policy.Policy, pl *pool.Pool) (*stageResult, error) {
+	log.Infoln("step into SignAfterPreSign6rounds func, presignID is ", presignID)
+	if !signers.Contains(localConn.LocalConfig.LocalID) {
+		log.Infoln("Not a signatory participant, exit")
+		return &stageResult{KeyID: keyID}, nil
+	}
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previos keygen config success")
-	//obtain the message to sign
-	message := []byte(localConn.LocalConfig.MessageToSign)
 	//retrieve the pre-signature
-	preSignature, err := save.LoadPresign6Result(presignID)
+	preSignature, lease, err := presigns.ReservePresign6(keyID, signers, presignID)
 	if err != nil {
 		log.Errorln("fail to load presign result")
-		return err
+		return nil, err
+	}
+	//the presignature is used up before the signature share is released, even if the signature fails afterwards
+	if err = presigns.Consume(lease); err != nil {
+		log.Errorln("fail to consume presign result")
+		_ = presigns.Release(lease)
+		return nil, err
 	}
 	//create a new multihandler (h) using the SignAfterPresign protocol
-	h, err := protocol.NewMultiHandler(protocols.SignAfterPresign(config, preSignature, message, pl), nil, *localConn)
+	h, err := protocol.NewMultiHandler(protocols.SignAfterPresignDigest(config, preSignature, d, pl, guard), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	//retrieve the sign result
-	signResult, err := h.Result()
+	signResult, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln("SignAfterPreSign: failed to get signResult")
-		return err
+		return nil, err
 	}
 	signature := signResult.(*ecdsa.Signature)
 	//verify the signature
-	if !signature.Verify(config.PublicPoint(), message) {
+	if !signature.Verify(config.PublicPoint(), d.Hash) {
 		log.Errorln("SignAfterPreSign: failed to verify cmp signature")
-		return errors.New("failed to verify cmp signature")
+		return nil, errors.New("failed to verify cmp signature")
 	}
 
 	log.Infoln("successfully sign message after presign")
-	// delete presign result from fixture file after use
-	err = save.DeletePreSign6Result(presignID)
-	if err != nil {
-		log.Errorf("fail to delete presign result, err is %v", err)
-	}
-	log.Infoln("successfully delete presign result")
-	return nil
+	logPresignDepth(localConn, presigns, save.Presign6Rounds, keyID, signers)
+	return signResultOf(keyID, config, d, signature)
 }
 
 // Sign function performs the signing operation
-func Sign(localConn *communication.LocalConn, net *test.Network, pl *pool.Pool) error {
+func Sign(localConn *communication.LocalConn, mux *protocol.Mux, sessionID []byte, store save.KeyStore, keyID string, signers party.IDSlice, d digest.Digest, guard policy.Policy, pl *pool.Pool) (*stageResult, error) {
 	log.Infoln("step into Sign func")
-	// reload sign config
-	err := localConn.LoadSignConfig()
-	if err != nil {
-		return err
+	if !signers.Contains(localConn.LocalConfig.LocalID) {
+		log.Infoln("Not a signatory participant, exit")
+		return &stageResult{KeyID: keyID}, nil
 	}
-	log.Infoln("reload sign config success")
-	// load keygen result from fixture file
-	config, err := save.LoadKeyGenResult()
+	// load keygen result from the key store
+	config, err := store.Get(keyID)
 	if err != nil {
 		log.Errorln("fail to load keygen result")
-		return err
+		return nil, err
 	}
 	log.Infoln("load previos keygen config success")
-	signers := party.NewIDSlice(localConn.LocalConfig.Signers)
-	message := []byte(localConn.LocalConfig.MessageToSign)
 	// create a new multi-handler (h) using the Sign protocol
-	h, err := protocol.NewMultiHandler(protocols.Sign(config, signers, message, pl), nil, *localConn)
+	h, err := protocol.NewMultiHandler(protocols.SignDigest(config, signers, d, pl, guard), sessionID, mux, handlerOptions(localConn)...)
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	//retrieve the sign result from the multi-handler
-	signResult, err := h.Result()
+	signResult, err := h.Result(context.Background())
 	if err != nil {
 		log.Errorln(err)
-		return err
+		return nil, err
 	}
 	signature := signResult.(*ecdsa.Signature)
 	//verify the signature
-	if !signature.Verify(config.PublicPoint(), message) {
+	if !signature.Verify(config.PublicPoint(), d.Hash) {
 		log.Errorln("failed to verify cmp signature")
-		return errors.New("failed to verify cmp signature")
+		return nil, errors.New("failed to verify cmp signature")
 	}
 	log.Infoln("successfully sign message")
+	return signResultOf(keyID, config, d, signature)
+}
+
+// messageDigest hashes message with the digest scheme, sha256 by default,
+// and returns the digest together with the hashed data. A raw digest is read as hex, and must be exactly 32 bytes.
+func messageDigest(scheme digest.Scheme, message string) (digest.Digest, []byte, error) {
+	if scheme == "" {
+		scheme = digest.SHA256
+	}
+	data := []byte(message)
+	if scheme == digest.Raw {
+		var err error
+		data, err = hex.DecodeString(strings.TrimPrefix(message, "0x"))
+		if err != nil {
+			return digest.Digest{}, nil, fmt.Errorf("raw digest is not hex encoded: %w", err)
+		}
+	}
+	d, err := digest.Compute(scheme, data)
+	if err != nil {
+		return digest.Digest{}, nil, err
+	}
+	log.Infof("signing %s digest %s", d.Scheme, hex.EncodeToString(d.Hash))
+	return d, data, nil
+}
+
+// signingPolicy returns the signing rules of the party at policyPath, bound to the key and the message of the proposal,
+// every decision is appended to the record at policyRecordPath. It returns nil if policyPath is not set.
+// The rules are loaded again for each signature, so that new approvals are taken into account.
+func signingPolicy(localConn *communication.LocalConn, p *proposal.SessionProposal) (policy.Policy, error) {
+	if localConn.LocalConfig.PolicyPath == "" {
+		return nil, nil
+	}
+	rules, err := policy.LoadRules(localConn.LocalConfig.PolicyPath)
+	if err != nil {
+		return nil, err
+	}
+	recordPath := localConn.LocalConfig.PolicyRecordPath
+	if recordPath == "" {
+		recordPath = filepath.Join(keyStoreDirOf(localConn), "policy-record.jsonl")
+	}
+	record, err := policy.OpenRecord(recordPath)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Bind(policy.NewEngine(rules, record), p.KeyID, p.Message), nil
+}
+
+// decodePublicKey decodes a hex encoded compressed secp256k1 public key, such as the one of an operator.
+func decodePublicKey(s string) (curve.Point, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	operator := curve.Secp256k1{}.NewPoint()
+	if err = operator.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return operator, nil
+}
+
+// presignCount returns the number of presignatures to generate in a single presign execution, 1 by default.
+func presignCount(localConn *communication.LocalConn) int {
+	if localConn.LocalConfig.PresignCount <= 0 {
+		return 1
+	}
+	return localConn.LocalConfig.PresignCount
+}
+
+// batchPresignID names the l-th of count presignatures generated together,
+// the presignature keeps the chosen name when it is the only one.
+func batchPresignID(presignID string, l, count int) string {
+	if count == 1 {
+		return presignID
+	}
+	return fmt.Sprintf("%s-%d", presignID, l)
+}
+
+// logPresignDepth logs the number of available presignatures of a key and a signer set,
+// and warns when it is below presignPoolMinDepth so that the pool is topped up with PreSign3 or PreSign6.
+func logPresignDepth(localConn *communication.LocalConn, presigns *save.PresignPool, kind save.PresignKind, keyID string, signers party.IDSlice) {
+	depth, err := presigns.Depth(kind, keyID, signers)
+	if err != nil {
+		log.Errorf("fail to get the depth of the %s pool, err is %v", kind, err)
+		return
+	}
+	if depth < localConn.LocalConfig.PresignPoolMinDepth {
+		log.Warnf("the %s pool of key %s for signers %v is low: %d available, %d wanted", kind, keyID, signers, depth, localConn.LocalConfig.PresignPoolMinDepth)
+		return
+	}
+	log.Infof("the %s pool of key %s for signers %v has %d available", kind, keyID, signers, depth)
+}
+
+// choosePresign is called by the center server: if the user did not choose the presignature of a SignAfterPreSign stage,
+// it returns the ID of the oldest available one for the signers, so that all the signers use the same presignature.
+func choosePresign(presigns *save.PresignPool, req *stageRequest, signers party.IDSlice) string {
+	if req.PresignID != "" || req.KeyID == "" {
+		return req.PresignID
+	}
+	kind := save.Presign3Rounds
+	switch req.Stage {
+	case "SignAfterPreSign3":
+	case "SignAfterPreSign6":
+		kind = save.Presign6Rounds
+	default:
+		return ""
+	}
+	available, err := presigns.Available(kind, req.KeyID, signers)
+	if err != nil || len(available) == 0 {
+		return ""
+	}
+	log.Infof("use presignature %s", available[0])
+	return available[0]
+}
+
+// keyIDFlag is the option of a stage selecting the key it uses, e.g. "PreSign3 10086 --key-id wallet1"
+const keyIDFlag = "--key-id"
+
+// stageRequest is a stage for the center server to propose, typed by the user or posted to the API of the daemon.
+type stageRequest struct {
+	Stage string `json:"stage"`
+	// Args are the positional arguments of the stage, e.g. the lost party of KeyRepair
+	Args      []string `json:"args,omitempty"`
+	KeyID     string   `json:"keyID,omitempty"`
+	PresignID string   `json:"presignID,omitempty"`
+	// Signers, Message and Scheme replace the ones of the sign config if they are set
+	Signers []party.ID    `json:"signers,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Scheme  digest.Scheme `json:"scheme,omitempty"`
+}
+
+// parseStage parses the stage typed by the user into the stage name, its positional arguments and the key ID, which may be empty.
+// The only argument of a stage using a presignature is the ID of the presignature.
+func parseStage(stageString string) (*stageRequest, error) {
+	req := &stageRequest{}
+	fields := strings.Fields(stageString)
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == keyIDFlag:
+			if i+1 == len(fields) {
+				return nil, fmt.Errorf("missing value of %s", keyIDFlag)
+			}
+			i++
+			req.KeyID = fields[i]
+		case strings.HasPrefix(fields[i], keyIDFlag+"="):
+			req.KeyID = strings.TrimPrefix(fields[i], keyIDFlag+"=")
+		default:
+			req.Args = append(req.Args, fields[i])
+		}
+	}
+	if len(req.Args) == 0 {
+		return nil, errors.New("empty stage")
+	}
+	req.Stage, req.Args = req.Args[0], req.Args[1:]
+	if len(req.Args) == 0 {
+		req.Args = nil
+	}
+	if usesPresign(req.Stage) && len(req.Args) == 1 {
+		req.PresignID, req.Args = req.Args[0], nil
+	}
+	if req.KeyID != "" {
+		if err := save.ValidateKeyID(req.KeyID); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// stages lists the stages the center server can propose.
+var stages = []string{"KeyGen", "KeyImport", "KeyRefresh", "KeyRefreshAux", "KeyReshare", "KeyRepair",
+	"PreSign3", "SignAfterPreSign3", "PreSign6", "SignAfterPreSign6", "PresignPool", "Sign", "ListKeys"}
+
+// usesPresign returns true if the stage creates or uses a presignature, whose ID is its only argument.
+func usesPresign(stage string) bool {
+	return stage == "PreSign3" || stage == "SignAfterPreSign3" || stage == "PreSign6" || stage == "SignAfterPreSign6"
+}
+
+// usesSigners returns true if the stage runs between the signers of the sign config only.
+func usesSigners(stage string) bool {
+	return usesPresign(stage) || stage == "PresignPool" || stage == "Sign"
+}
+
+// signsMessage returns true if the stage signs the message of the sign config.
+func signsMessage(stage string) bool {
+	return stage == "Sign" || stage == "SignAfterPreSign3" || stage == "SignAfterPreSign6"
+}
+
+// checkStage checks the stage of a proposal and its arguments, before any party executes it.
+func checkStage(p *proposal.SessionProposal) error {
+	known := false
+	for _, stage := range stages {
+		known = known || stage == p.Protocol
+	}
+	if !known {
+		return fmt.Errorf("unknown stage %q", p.Protocol)
+	}
+	// KeyRepair takes the ID of the lost party, the stages using a presignature take its ID, the others take none
+	if (p.Protocol == "KeyRepair" && len(p.Args) != 1) || (p.Protocol != "KeyRepair" && len(p.Args) != 0) {
+		return errors.New("wrong stage arguments")
+	}
+	if p.PresignID != "" && !usesPresign(p.Protocol) {
+		return fmt.Errorf("%s does not use a presignature", p.Protocol)
+	}
+	// all the signers must use the same presignature, the center server chooses it if the user did not
+	if p.PresignID == "" && (p.Protocol == "SignAfterPreSign3" || p.Protocol == "SignAfterPreSign6") {
+		return fmt.Errorf("%s requires a presignature ID, the presignature pool may be empty", p.Protocol)
+	}
+	// only KeyGen, KeyImport and KeyReshare, which create a key share, can choose the key ID after the execution
+	if p.KeyID == "" && p.Protocol != "KeyGen" && p.Protocol != "KeyImport" && p.Protocol != "KeyReshare" && p.Protocol != "ListKeys" {
+		return fmt.Errorf("%s requires %s <key_id>", p.Protocol, keyIDFlag)
+	}
+	if p.KeyID != "" {
+		if err := save.ValidateKeyID(p.KeyID); err != nil {
+			return err
+		}
+	}
+	if usesSigners(p.Protocol) && len(p.Signers) == 0 {
+		return fmt.Errorf("%s requires signers", p.Protocol)
+	}
+	if signsMessage(p.Protocol) && p.Digest == nil {
+		return fmt.Errorf("%s requires a message to sign", p.Protocol)
+	}
 	return nil
 }
 
 // The stepIntoStage function is responsible for executing the specific logic corresponding to the given stage of the protocol.
-// It takes a local connection (localConn), a stage string, a network (net), and a pool (pl) as input.
-func stepIntoStage(localConn *communication.LocalConn, stageString string, net *test.Network, pl *pool.Pool) error {
-	// split stage into []string
-	stageAfterSplit := strings.Split(stageString, " ")
-	if len(stageAfterSplit) == 0 || len(stageAfterSplit) > 2 {
-		// if the length of stageAfterSplit is 0 or greater than 2, it means that the stage is wrong
-		log.Errorln("wrong stage name, please check")
-		return nil
+// It takes a local connection (localConn), the session proposal accepted by all the parties, the mux the protocol messages go through,
+// the key store, the presignature pool and a pool (pl) as input, and returns the result of the stage for the local party.
+func stepIntoStage(localConn *communication.LocalConn, p *proposal.SessionProposal, mux *protocol.Mux, store save.KeyStore, presigns *save.PresignPool, pl *pool.Pool) (*stageResult, error) {
+	stage, keyID, presignID, sessionID := p.Protocol, p.KeyID, p.PresignID, p.SessionID
+	log.Infof("stage is %s, arguments are %+v, key ID is %q\n", stage, p.Args, keyID)
+	//The signing rules of the party are evaluated by the protocol before the signature share is released
+	var guard policy.Policy
+	if signsMessage(stage) {
+		var err error
+		if guard, err = signingPolicy(localConn, p); err != nil {
+			log.Errorln("fail to load the signing policy")
+			return nil, err
+		}
 	}
-	// print stageAfterSplit
-	log.Infof("stageAfterSplit is %+v\n", stageAfterSplit)
-	// stage means the first element of stageAfterSplit
-	stage := stageAfterSplit[0]
+	var result *stageResult
+	var err error
 	//Use a switch statement to determine the stage of the protocol based on the given stage string.
 	switch stage {
 	case "KeyGen":
 		//Call the KeyGen function to execute the protocol logic for KeyGen
-		err := KeyGen(localConn, pl)
+		result, err = KeyGen(localConn, mux, sessionID, store, keyID, pl)
 		if err != nil {
 			log.Errorln("fail KeyGen")
-			return err
 		}
-		break
+	case "KeyImport":
+		//Call the KeyImport function to share an existing private key
+		result, err = KeyImport(localConn, mux, sessionID, store, keyID, pl)
+		if err != nil {
+			log.Errorln("fail KeyImport")
+		}
 	case "KeyRefresh":
 		//Call the KeyRefresh function to execute the protocol logic for KeyRefresh
-		err := KeyRefresh(localConn, pl)
+		result, err = KeyRefresh(localConn, mux, sessionID, store, keyID, pl)
 		if err != nil {
 			log.Errorln("fail KeyRefresh")
-			return err
 		}
-		break
-
+	case "KeyRefreshAux":
+		//Call the KeyRefreshAux function to rotate the Paillier keys and Pedersen parameters only
+		result, err = KeyRefreshAux(localConn, mux, sessionID, store, keyID, pl)
+		if err != nil {
+			log.Errorln("fail KeyRefreshAux")
+		}
 	case "KeyReshare":
-		//Call the KeyRefresh function to execute the protocol logic for KeyRefresh
-		err := KeyReshare(localConn, pl)
+		//Call the KeyReshare function to execute the protocol logic for KeyReshare
+		result, err = KeyReshare(localConn, mux, sessionID, store, keyID, pl)
 		if err != nil {
-			log.Errorln("fail KeyRefresh")
-			return err
+			log.Errorln("fail KeyReshare")
+		}
+	case "KeyRepair":
+		//Call the KeyRepair function to recover the key share of the lost party
+		result, err = KeyRepair(localConn, mux, sessionID, store, keyID, party.ID(p.Args[0]), pl)
+		if err != nil {
+			log.Errorln("fail KeyRepair")
 		}
-		break
 	case "PreSign3":
-		presignID := stageAfterSplit[1]
 		//Call the PreSign function to execute the protocol logic for PreSign
-		err := PreSign3rounds(localConn, presignID, pl)
+		result, err = PreSign3rounds(localConn, mux, sessionID, store, presigns, keyID, presignID, p.Signers, pl)
 		if err != nil {
 			log.Errorln("fail PreSign3rounds")
-			return err
 		}
-		break
 	case "SignAfterPreSign3":
-		presignID := stageAfterSplit[1]
-		//Call the SignAfterPreSign function to execute the protocol logic for SignAfterPreSign
-		err := SignAfterPreSign3rounds(localConn, presignID, pl)
+		//Call the SignAfterPreSign function to sign the digest of the proposal
+		result, err = SignAfterPreSign3rounds(localConn, mux, sessionID, store, presigns, keyID, presignID, p.Signers, *p.Digest, guard, pl)
 		if err != nil {
 			log.Errorln("fail SignAfterPreSign3")
-			return err
 		}
-		break
 	case "PreSign6":
-		presignID := stageAfterSplit[1]
-
 		//Call the PreSign function to execute the protocol logic for PreSign
-		err := PreSign6rounds(localConn, presignID, pl)
+		result, err = PreSign6rounds(localConn, mux, sessionID, store, presigns, keyID, presignID, p.Signers, pl)
 		if err != nil {
-			log.Errorln("fail PreSign3rounds")
-			return err
+			log.Errorln("fail PreSign6rounds")
 		}
-		break
 	case "SignAfterPreSign6":
-		presignID := stageAfterSplit[1]
-		//Call the SignAfterPreSign function to execute the protocol logic for SignAfterPreSign
-		err := SignAfterPreSign6rounds(localConn, presignID, pl)
+		//Call the SignAfterPreSign function to sign the digest of the proposal
+		result, err = SignAfterPreSign6rounds(localConn, mux, sessionID, store, presigns, keyID, presignID, p.Signers, *p.Digest, guard, pl)
 		if err != nil {
-			log.Errorln("fail SignAfterPreSign3")
-			return err
+			log.Errorln("fail SignAfterPreSign6")
 		}
-		break
 	case "Sign":
-		//Call the Sign function to execute the protocol logic for Sign
-		err := Sign(localConn, net, pl)
+		//Call the Sign function to sign the digest of the proposal
+		result, err = Sign(localConn, mux, sessionID, store, keyID, p.Signers, *p.Digest, guard, pl)
 		if err != nil {
 			log.Errorln("fail Sign")
-			return err
 		}
-		break
+	case "PresignPool":
+		//Every party logs the depth of the presignature pools of the key for the proposed signers
+		result = &stageResult{KeyID: keyID, Available: make(map[save.PresignKind]int)}
+		for _, kind := range []save.PresignKind{save.Presign3Rounds, save.Presign6Rounds} {
+			logPresignDepth(localConn, presigns, kind, keyID, p.Signers)
+			if result.Available[kind], err = presigns.Depth(kind, keyID, p.Signers); err != nil {
+				break
+			}
+		}
+	case "ListKeys":
+		//Every party logs the keys it stores, no message is exchanged
+		result = &stageResult{}
+		if result.KeyIDs, err = store.List(); err != nil {
+			log.Errorln("fail ListKeys")
+			break
+		}
+		log.Infof("stored key IDs are %v", result.KeyIDs)
 	default:
 		log.Errorln("wrong stage name, please check")
-		break
+		err = fmt.Errorf("unknown stage %q", stage)
 	}
-	return nil
+	if err != nil {
+		saveAbortReport(localConn, err)
+		return nil, err
+	}
+	return result, nil
 }
 
-// The execute function is responsible for executing the protocol logic based on the stage of the protocol.
-// It takes a local connection (localConn) and a pool (pl) as input.
-func execute(localConn *communication.LocalConn, pl *pool.Pool) error {
-	// Get the center server ID and local ID from the local connection's configuration.
+// saveAbortReport saves the report of an aborted protocol execution next to the key store, if err carries one,
+// so that the misbehaviour of the culprits can be proven to the other parties.
+func saveAbortReport(localConn *communication.LocalConn, err error) {
+	report := abortReport(err)
+	if report == nil {
+		return
+	}
+	path, err := save.SaveAbortReport(filepath.Join(keyStoreDirOf(localConn), "aborts"), report)
+	if err != nil {
+		log.Errorf("fail to save abort report: %v", err)
+		return
+	}
+	log.Errorf("%s aborted in round %d, culprits are %v, report saved to %s", report.Protocol, report.Round, report.Culprits, path)
+}
+
+// keyStoreDirOf returns the key store directory of the party.
+func keyStoreDirOf(localConn *communication.LocalConn) string {
+	if localConn.LocalConfig.KeyStoreDir == "" {
+		return save.DefaultKeyStoreDir
+	}
+	return localConn.LocalConfig.KeyStoreDir
+}
+
+// controlProtocolID identifies the messages of the center server and the answers of the parties over the mux.
+const controlProtocolID = "main/control"
+
+// defaultProposalTTL is the time a session proposal is valid if proposalTTLSecond is not set.
+const defaultProposalTTL = 300 * time.Second
+
+// errRejected is returned when a session proposal is not accepted by all the parties, no protocol is executed.
+var errRejected = errors.New("the session proposal was rejected")
+
+// controlMessage is sent over the control session, exactly one of its fields is set.
+type controlMessage struct {
+	// Proposal is sent by the center server to describe the next execution
+	Proposal *proposal.SessionProposal
+	// Response is the answer of a party to the proposal
+	Response *proposal.Response
+	// Decision tells the parties whether the execution starts
+	Decision *proposal.Decision
+}
+
+// sendControl sends msg to the party to over the control session, or broadcasts it if to is empty.
+func sendControl(ctx context.Context, localConn *communication.LocalConn, control *protocol.MuxSession, to party.ID, msg *controlMessage) error {
+	data, err := cbor.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	wrapped, err := cbor.Marshal(&protocol.Message{
+		From:     localConn.LocalConfig.LocalID,
+		Protocol: controlProtocolID,
+		Data:     data,
+	})
+	if err != nil {
+		return err
+	}
+	if to == "" {
+		return control.Broadcast(ctx, wrapped)
+	}
+	return control.Send(ctx, to, wrapped)
+}
+
+// receiveControl receives the next message of the party from over the control session.
+func receiveControl(ctx context.Context, control *protocol.MuxSession, from party.ID) (*controlMessage, error) {
+	data, err := control.Receive(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := &protocol.Message{}
+	if err = cbor.Unmarshal(data, wrapped); err != nil {
+		return nil, err
+	}
+	msg := &controlMessage{}
+	if err = cbor.Unmarshal(wrapped.Data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// proposalKey returns the secret key with which the center server signs its session proposals.
+func proposalKey() (taproot.SecretKey, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(os.Getenv(proposalKeyEnv), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not hex encoded: %w", proposalKeyEnv, err)
+	}
+	secret := taproot.SecretKey(data)
+	if _, err = secret.Public(); err != nil {
+		return nil, fmt.Errorf("%s: %w", proposalKeyEnv, err)
+	}
+	return secret, nil
+}
+
+// coordinatorKey returns the public key of the center server, which verifies its session proposals.
+func coordinatorKey(localConn *communication.LocalConn) (taproot.PublicKey, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(localConn.LocalConfig.CoordinatorPublicKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("coordinatorPublicKey is not hex encoded: %w", err)
+	}
+	if len(data) != 32 {
+		return nil, fmt.Errorf("coordinatorPublicKey must be 32 bytes, found %d", len(data))
+	}
+	return taproot.PublicKey(data), nil
+}
+
+// proposalTTL returns the time a session proposal is valid.
+func proposalTTL(localConn *communication.LocalConn) time.Duration {
+	if localConn.LocalConfig.ProposalTTLSecond <= 0 {
+		return defaultProposalTTL
+	}
+	return time.Duration(localConn.LocalConfig.ProposalTTLSecond) * time.Second
+}
+
+// proposalPolicy returns the policy hook of the party: the proposal must come from the center server,
+// and describe a valid stage among the allowedStages of the connection config.
+func proposalPolicy(localConn *communication.LocalConn) proposal.Policy {
+	return proposal.All(
+		func(p *proposal.SessionProposal) error {
+			if p.Proposer != localConn.LocalConfig.CenterServerID {
+				return fmt.Errorf("proposed by %v instead of the center server", p.Proposer)
+			}
+			return nil
+		},
+		checkStage,
+		proposal.AllowProtocols(localConn.LocalConfig.AllowedStages...),
+	)
+}
+
+// newProposal is called by the center server: it builds the session proposal of the requested stage, with a fresh session ID.
+// The signers and the message to sign are the ones of its sign config, unless the request sets them.
+func newProposal(localConn *communication.LocalConn, presigns *save.PresignPool, req *stageRequest) (*proposal.SessionProposal, error) {
+	p := &proposal.SessionProposal{
+		Protocol: req.Stage,
+		Args:     req.Args,
+		KeyID:    req.KeyID,
+		Proposer: localConn.LocalConfig.LocalID,
+		Expiry:   time.Now().Add(proposalTTL(localConn)).Unix(),
+	}
+	if usesSigners(p.Protocol) || signsMessage(p.Protocol) {
+		if err := localConn.LoadSignConfig(); err != nil {
+			return nil, err
+		}
+	}
+	if usesSigners(p.Protocol) {
+		p.Signers = party.NewIDSlice(req.Signers)
+		if len(p.Signers) == 0 {
+			p.Signers = party.NewIDSlice(localConn.LocalConfig.Signers)
+		}
+	}
+	p.PresignID = choosePresign(presigns, req, p.Signers)
+	if signsMessage(p.Protocol) {
+		scheme, message := req.Scheme, req.Message
+		if message == "" {
+			scheme, message = digest.Scheme(localConn.LocalConfig.MessageDigest), localConn.LocalConfig.MessageToSign
+		}
+		d, data, err := messageDigest(scheme, message)
+		if err != nil {
+			return nil, err
+		}
+		p.Digest, p.Message = &d, data
+	}
+	if err := checkStage(p); err != nil {
+		return nil, err
+	}
+	//Generate a fresh session ID, so that the executions never share a SSID
+	p.SessionID = make([]byte, 32)
+	if _, err := rand.Read(p.SessionID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// propose is called by the center server: it signs and broadcasts the proposal of the requested stage,
+// collects the responses of the other parties until the proposal expires, and broadcasts its decision.
+func propose(localConn *communication.LocalConn, control *protocol.MuxSession, presigns *save.PresignPool, req *stageRequest) (*proposal.SessionProposal, error) {
+	secret, err := proposalKey()
+	if err != nil {
+		return nil, err
+	}
+	p, err := newProposal(localConn, presigns, req)
+	if err != nil {
+		log.Errorf("wrong stage, please check: %v", err)
+		return nil, fmt.Errorf("%w: %v", errRejected, err)
+	}
+	if err = p.Sign(secret); err != nil {
+		return nil, err
+	}
+	//Broadcast the session proposal to all participants
+	if err = sendControl(context.Background(), localConn, control, "", &controlMessage{Proposal: p}); err != nil {
+		log.Errorln("fail to broadcast the session proposal")
+		return nil, err
+	}
+	log.Infof("propose stage %v, waiting for the responses", p.Protocol)
+
+	ctx, cancel := context.WithDeadline(context.Background(), p.Deadline())
+	defer cancel()
+	others := localConn.LocalConfig.OtherPartyIDs
+	responses := make(map[party.ID]*proposal.Response, len(others))
+	for _, j := range others {
+		// skip the late responses to previous proposals
+		for responses[j] == nil {
+			msg, err := receiveControl(ctx, control, j)
+			if err != nil {
+				log.Errorf("no response of %v to the session proposal: %v", j, err)
+				break
+			}
+			if msg.Response != nil && bytes.Equal(msg.Response.SessionID, p.SessionID) {
+				responses[j] = msg.Response
+			}
+		}
+	}
+	decision := proposal.Decide(p, responses, others)
+	if err = sendControl(context.Background(), localConn, control, "", &controlMessage{Decision: decision}); err != nil {
+		log.Errorln("fail to broadcast the decision on the session proposal")
+		return nil, err
+	}
+	if !decision.Accept {
+		reasons := make([]string, 0, len(decision.Rejections))
+		for j, reason := range decision.Rejections {
+			log.Errorf("%v rejected the session proposal: %s", j, reason)
+			reasons = append(reasons, fmt.Sprintf("%v: %s", j, reason))
+		}
+		return nil, fmt.Errorf("%w: %s", errRejected, strings.Join(reasons, "; "))
+	}
+	return p, nil
+}
+
+// review is called by the other parties: it waits for the proposal of the center server, checks it against
+// the policy hook of the party and the extra policies, answers it, and waits for the decision of the center server.
+func review(localConn *communication.LocalConn, control *protocol.MuxSession, extra ...proposal.Policy) (*proposal.SessionProposal, error) {
 	centerID := localConn.LocalConfig.CenterServerID
-	localID := localConn.LocalConfig.LocalID
+	coordinator, err := coordinatorKey(localConn)
+	if err != nil {
+		return nil, err
+	}
+	// skip the late decisions on previous proposals
+	var p *proposal.SessionProposal
+	for p == nil {
+		msg, err := receiveControl(context.Background(), control, centerID)
+		if err != nil {
+			log.Errorf("fail to receive instruction from center server %v", centerID)
+			return nil, err
+		}
+		p = msg.Proposal
+	}
+
+	response := proposal.Review(p, coordinator, proposal.All(append([]proposal.Policy{proposalPolicy(localConn)}, extra...)...), time.Now())
+	if response.Accept {
+		log.Infof("accept the session proposal of stage %v, key ID %q, signers %v, presignature %q", p.Protocol, p.KeyID, p.Signers, p.PresignID)
+		if p.Digest != nil {
+			log.Infof("the proposal signs %s digest %s", p.Digest.Scheme, hex.EncodeToString(p.Digest.Hash))
+		}
+	} else {
+		log.Warnf("reject the session proposal of stage %v: %s", p.Protocol, response.Reason)
+	}
+	if err = sendControl(context.Background(), localConn, control, centerID, &controlMessage{Response: response}); err != nil {
+		log.Errorln("fail to answer the session proposal")
+		return nil, err
+	}
 
+	for {
+		msg, err := receiveControl(context.Background(), control, centerID)
+		if err != nil {
+			log.Errorf("fail to receive the decision of center server %v", centerID)
+			return nil, err
+		}
+		if msg.Decision == nil || !bytes.Equal(msg.Decision.SessionID, p.SessionID) {
+			continue
+		}
+		if !msg.Decision.Accept || !response.Accept {
+			for j, reason := range msg.Decision.Rejections {
+				log.Warnf("%v rejected the session proposal: %s", j, reason)
+			}
+			return nil, errRejected
+		}
+		return p, nil
+	}
+}
+
+// The execute function is responsible for executing the protocol logic based on the stage of the protocol.
+// It takes a local connection (localConn), the mux, the control session of the mux, the key store, the presignature pool and a pool (pl) as input.
+// No protocol is executed before all the parties accept the session proposal of the center server.
+func execute(localConn *communication.LocalConn, mux *protocol.Mux, control *protocol.MuxSession, store save.KeyStore, presigns *save.PresignPool, pl *pool.Pool) error {
+	var p *proposal.SessionProposal
+	var err error
 	//  Determine the stage of the protocol based on whether the local ID matches the center server ID.
-	//If they match, it means that the local participant is responsible for initiating the protocol.
-	var stage string
-	if centerID == localID {
+	//If they match, it means that the local participant is responsible for proposing the protocol.
+	if localConn.LocalConfig.CenterServerID == localConn.LocalConfig.LocalID {
 		//If the local ID is the center server ID, prompt the user to enter the stage of the protocol.
 		//Print tips:
 		printTips()
 		//Read the input from the command line and store it in the variable "stage".
 		reader := bufio.NewReader(os.Stdin)
-		result, _, err := reader.ReadLine()
-		if err != nil {
+		line, _, readErr := reader.ReadLine()
+		if readErr != nil {
 			log.Errorln("fail to read stage from command line")
 		}
-		stage = string(result)
-
-		//Broadcast the stage instruction to all participants
-		err = localConn.BroadcastSend(result)
-		if err != nil {
-			log.Errorln("fail to BroadcastSend")
-			return err
+		req, parseErr := parseStage(string(line))
+		if parseErr != nil {
+			log.Errorf("wrong stage, please check: %v", parseErr)
+			return nil
 		}
-		log.Infof("step into stage %v", stage)
-
+		p, err = propose(localConn, control, presigns, req)
 	} else {
-		//If the local ID is not the center server ID, listen for instructions from the center server.
-		//Receive the data from the center server using localConn.P2pReceive() and store it in the variable "data".
-		//The received data represents the current stage of the protocol.
-		data, err := localConn.P2pReceive(centerID)
-		if err != nil {
-			log.Errorf("fail to receive instruction from center server %v", centerID)
-			return err
-		}
-
-		stage = string(data[:])
-		log.Infof("step into stage %v", stage)
+		//If the local ID is not the center server ID, review the proposal of the center server.
+		p, err = review(localConn, control)
 	}
-	//Create a new network using test.NewNetwork() with the participant IDs and local connection.
-	ids := party.NewIDSlice(localConn.LocalConfig.PartyIDs)
-	net := test.NewNetwork(ids, localConn)
+	if errors.Is(err, errRejected) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("step into stage %v", p.Protocol)
 	//Call the stepIntoStage function to perform the protocol steps corresponding to the stage
-	// may be KeyGen, KeyRefresh, PreSign3 <id>, SignAfterPreSign3 <id>, Sign etc.
-	stepIntoStage(localConn, stage, net, pl)
+	// may be KeyGen, KeyRefresh, PreSign3 <id>, SignAfterPreSign3 <id>, Sign etc., followed by --key-id <key_id>
+	stepIntoStage(localConn, p, mux, store, presigns, pl)
 	return nil
 }
 
-// The main function is the entry point of the program.
-func main() {
-	//Establish a network connection with other participants.
-	//The returned value localConn represents the local connection to the network.
+// setUp loads the connection config of the party, opens its key store and presignature pool,
+// and establishes the connections with the other parties if connect is true.
+// keyStoreDir replaces the keyStoreDir of the connection config if it is not empty.
+func setUp(paths communication.ConfigPaths, keyStoreDir string, connect bool) (*communication.LocalConn, *save.FileKeyStore, *save.PresignPool, error) {
+	//The key shares and presignatures are encrypted at rest with a passphrase.
+	if len(passphrase()) == 0 {
+		return nil, nil, nil, fmt.Errorf("the environment variable %s must be set to the passphrase of the saved key shares", passphraseEnv)
+	}
+	if os.Getenv(allowPlaintextEnv) == "1" {
+		log.Warnf("%s is set, the key shares saved in plaintext are loaded", allowPlaintextEnv)
+		save.AllowPlaintext = true
+	}
+	localConn := &communication.LocalConn{Paths: paths}
+	if err := localConn.LoadConnConfig(); err != nil {
+		return nil, nil, nil, err
+	}
+	if keyStoreDir != "" {
+		localConn.LocalConfig.KeyStoreDir = keyStoreDir
+	}
+
+	if connect {
+		//The center server signs the session proposals, the other parties check them with its public key.
+		if localConn.LocalConfig.CenterServerID == localConn.LocalConfig.LocalID {
+			secret, err := proposalKey()
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("the environment variable %s must be set to the secret key of the center server: %w", proposalKeyEnv, err)
+			}
+			public, _ := secret.Public()
+			log.Infof("the session proposals are signed by coordinatorPublicKey %s", hex.EncodeToString(public))
+		} else if _, err := coordinatorKey(localConn); err != nil {
+			return nil, nil, nil, fmt.Errorf("the connection config must set coordinatorPublicKey: %w", err)
+		}
+		//Establish a network connection with other participants.
+		if err := localConn.StartServer(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 
-	localConn := communication.SetUpConn()
+	//The key shares of all the wallets of this node are stored in the key store, addressed by key ID.
+	keyStoreDir = keyStoreDirOf(localConn)
+	store, err := save.NewFileKeyStore(keyStoreDir, passphrase())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fail to open key store: %w", err)
+	}
+	//The presignatures are kept next to the key shares, each of them can be used only once.
+	presigns, err := save.NewPresignPool(filepath.Join(keyStoreDir, "presign"), passphrase())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("fail to open presignature pool: %w", err)
+	}
+	//Encrypt the saved files again if a new passphrase is given, and use it from now on.
+	if newPassphrase := os.Getenv(newPassphraseEnv); newPassphrase != "" {
+		if err = save.RotatePassphrase(passphrase(), []byte(newPassphrase)); err != nil {
+			return nil, nil, nil, fmt.Errorf("fail to rotate passphrase: %w", err)
+		}
+		if err = store.Rotate([]byte(newPassphrase)); err != nil {
+			return nil, nil, nil, fmt.Errorf("fail to rotate passphrase: %w", err)
+		}
+		if err = presigns.Rotate([]byte(newPassphrase)); err != nil {
+			return nil, nil, nil, fmt.Errorf("fail to rotate passphrase: %w", err)
+		}
+		if err = os.Setenv(passphraseEnv, newPassphrase); err != nil {
+			return nil, nil, nil, err
+		}
+		log.Infoln("successfully rotate passphrase")
+	}
+	return localConn, store, presigns, nil
+}
 
+// repl runs the stages typed at the terminal of the center server, until an error occurs.
+func repl(localConn *communication.LocalConn, store save.KeyStore, presigns *save.PresignPool) error {
 	//New and old parties establish a key reshare connection.
 	//localConn := communication.SetUpConnReshare()
 
+	//All the messages go through a mux, so that several protocol executions can share the connections.
+	mux := protocol.NewMux(localConn, localConn.LocalConfig.OtherPartyIDs, protocol.DefaultMaxPending, protocol.DefaultPendingTTL)
+	defer mux.Close()
+	//The instructions of the center server have their own session
+	control, err := mux.Open(controlProtocolID, nil, localConn.LocalConfig.OtherPartyIDs)
+	if err != nil {
+		return err
+	}
+
 	//Continuously run the protocol in a loop.
 	for {
 		//Create a new pool pl
 		pl := pool.NewPool(0)
-
-		defer pl.TearDown()
-		//Call the execute function passing the local connection and the pool as arguments
-		err := execute(&localConn, pl)
+		//Call the execute function passing the local connection, the mux and the pool as arguments
+		err = execute(localConn, mux, control, store, presigns, pl)
+		pl.TearDown()
 		if err != nil {
-			log.Errorln(err)
-			return
+			return err
 		}
 	}
 }
+
+// The main function is the entry point of the program, it runs the command given on the command line, see usage.
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
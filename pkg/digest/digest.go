@@ -0,0 +1,111 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package digest computes the 32 byte hash that an ECDSA signature is produced for,
+// from the data the user actually wants to sign.
+//
+// The signing protocols interpret their message as a hash, and truncate it to the size of the curve order.
+// A Digest records the Scheme it was computed with, so that all the signers agree on how the data was hashed.
+package digest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Size is the length in bytes of a digest.
+const Size = 32
+
+// Scheme is the function used to compute a digest.
+type Scheme string
+
+const (
+	// Raw is a digest computed by the caller, the data must be exactly 32 bytes.
+	Raw Scheme = "raw"
+	// SHA256 is SHA-256(data).
+	SHA256 Scheme = "sha256"
+	// DoubleSHA256 is SHA-256(SHA-256(data)), as used by Bitcoin.
+	DoubleSHA256 Scheme = "sha256d"
+	// Keccak256 is the original Keccak-256(data), as used by Ethereum.
+	Keccak256 Scheme = "keccak256"
+	// EIP191 is Keccak-256("\x19Ethereum Signed Message:\n" || len(data) || data), as used by personal_sign.
+	EIP191 Scheme = "eip191"
+	// EIP712 is the hash of JSON encoded typed data, as used by eth_signTypedData_v4.
+	EIP712 Scheme = "eip712"
+)
+
+// Schemes lists all the supported schemes.
+var Schemes = []Scheme{Raw, SHA256, DoubleSHA256, Keccak256, EIP191, EIP712}
+
+// Valid returns true if s is a supported scheme.
+func (s Scheme) Valid() bool {
+	for _, scheme := range Schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest is the hash of some data, together with the scheme used to compute it.
+type Digest struct {
+	Scheme Scheme
+	Hash   []byte
+}
+
+// Compute hashes data according to scheme.
+func Compute(scheme Scheme, data []byte) (Digest, error) {
+	var h []byte
+	switch scheme {
+	case Raw:
+		if len(data) != Size {
+			return Digest{}, fmt.Errorf("digest: expected %d bytes for a raw digest, found %d", Size, len(data))
+		}
+		h = append([]byte{}, data...)
+	case SHA256:
+		sum := sha256.Sum256(data)
+		h = sum[:]
+	case DoubleSHA256:
+		first := sha256.Sum256(data)
+		sum := sha256.Sum256(first[:])
+		h = sum[:]
+	case Keccak256:
+		h = keccak256(data)
+	case EIP191:
+		h = keccak256([]byte("\x19Ethereum Signed Message:\n"+strconv.Itoa(len(data))), data)
+	case EIP712:
+		var err error
+		if h, err = HashTypedData(data); err != nil {
+			return Digest{}, err
+		}
+	default:
+		return Digest{}, fmt.Errorf("digest: unknown scheme %q", scheme)
+	}
+	return Digest{Scheme: scheme, Hash: h}, nil
+}
+
+// Validate checks that the digest has a known scheme and the right length.
+func (d Digest) Validate() error {
+	if !d.Scheme.Valid() {
+		return fmt.Errorf("digest: unknown scheme %q", d.Scheme)
+	}
+	if len(d.Hash) != Size {
+		return fmt.Errorf("digest: expected %d bytes, found %d", Size, len(d.Hash))
+	}
+	return nil
+}
+
+// keccak256 returns the legacy Keccak-256 hash of the concatenation of datas.
+func keccak256(datas ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, data := range datas {
+		h.Write(data)
+	}
+	return h.Sum(nil)
+}
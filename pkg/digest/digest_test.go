@@ -0,0 +1,112 @@
+package digest
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mailTypedData is the example of the EIP-712 specification.
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		scheme Scheme
+		data   string
+		hash   string
+	}{
+		{SHA256, "", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{DoubleSHA256, "hello", "9595c9df90075148eb06860365df33584b75bff782a510c6cd4883a419833d50"},
+		{Keccak256, "", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{EIP191, "hello", "50b2c43fd39106bafbba0da34fc430e1f91e3c96ea2acee2bc34119f92b37750"},
+		{EIP712, mailTypedData, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	}
+	for _, tt := range tests {
+		d, err := Compute(tt.scheme, []byte(tt.data))
+		require.NoError(t, err, tt.scheme)
+		assert.Equal(t, tt.hash, hex.EncodeToString(d.Hash), tt.scheme)
+		assert.Equal(t, tt.scheme, d.Scheme)
+		assert.NoError(t, d.Validate())
+	}
+}
+
+func TestComputeRaw(t *testing.T) {
+	prehash := make([]byte, Size)
+	prehash[0] = 1
+	d, err := Compute(Raw, prehash)
+	require.NoError(t, err)
+	assert.Equal(t, prehash, d.Hash)
+
+	// a raw digest is never truncated or padded
+	_, err = Compute(Raw, []byte("a message longer than thirty two bytes"))
+	assert.Error(t, err)
+	_, err = Compute(Raw, []byte("hello"))
+	assert.Error(t, err)
+	_, err = Compute("md5", prehash)
+	assert.Error(t, err)
+}
+
+func TestTypedData(t *testing.T) {
+	var td TypedData
+	_, err := HashTypedData([]byte(mailTypedData))
+	require.NoError(t, err)
+
+	td.Types = map[string][]TypedDataField{
+		"Mail":   {{"from", "Person"}, {"to", "Person"}, {"contents", "string"}},
+		"Person": {{"name", "string"}, {"wallet", "address"}},
+	}
+	encodedType, err := td.EncodeType("Mail")
+	require.NoError(t, err)
+	assert.Equal(t, "Mail(Person from,Person to,string contents)Person(string name,address wallet)", encodedType)
+	assert.Equal(t, "a0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2", hex.EncodeToString(keccak256([]byte(encodedType))))
+
+	for _, invalid := range []struct {
+		typ   string
+		value interface{}
+	}{
+		{"uint8", "256"},
+		{"int8", "-129"},
+		{"address", "0x1234"},
+		{"bytes2", "0x123456"},
+		{"uint256[2]", []interface{}{"1"}},
+		{"Unknown", "1"},
+	} {
+		_, err = td.encodeValue(invalid.typ, invalid.value)
+		assert.Error(t, err, invalid.typ)
+	}
+	encoded, err := td.encodeValue("int8", "-1")
+	require.NoError(t, err)
+	assert.Equal(t, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", hex.EncodeToString(encoded))
+}
@@ -0,0 +1,314 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package digest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// domainType is the name of the type of the EIP-712 domain.
+const domainType = "EIP712Domain"
+
+// TypedDataField is a member of an EIP-712 struct type.
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is the JSON object signed with eth_signTypedData_v4.
+//
+// See: https://eips.ethereum.org/EIPS/eip-712
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// HashTypedData parses JSON encoded typed data, and returns its EIP-712 hash.
+func HashTypedData(data []byte) ([]byte, error) {
+	var typedData TypedData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep integers exact, they may not fit in a float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&typedData); err != nil {
+		return nil, fmt.Errorf("eip712: %w", err)
+	}
+	return typedData.Hash()
+}
+
+// Hash returns keccak256("\x19\x01" || hashStruct(domain) || hashStruct(message)).
+//
+// The message hash is omitted when the primary type is the domain itself.
+func (td *TypedData) Hash() ([]byte, error) {
+	if _, ok := td.Types[domainType]; !ok {
+		return nil, fmt.Errorf("eip712: missing %s type", domainType)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, fmt.Errorf("eip712: unknown primary type %q", td.PrimaryType)
+	}
+	domainHash, err := td.HashStruct(domainType, td.Domain)
+	if err != nil {
+		return nil, err
+	}
+	if td.PrimaryType == domainType {
+		return keccak256([]byte{0x19, 0x01}, domainHash), nil
+	}
+	messageHash, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, err
+	}
+	return keccak256([]byte{0x19, 0x01}, domainHash, messageHash), nil
+}
+
+// HashStruct returns keccak256(typeHash || encodeData(data)).
+func (td *TypedData) HashStruct(typeName string, data map[string]interface{}) ([]byte, error) {
+	encodedType, err := td.EncodeType(typeName)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(keccak256([]byte(encodedType)))
+	for _, field := range td.Types[typeName] {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("eip712: missing field %s.%s", typeName, field.Name)
+		}
+		encoded, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("eip712: field %s.%s: %w", typeName, field.Name, err)
+		}
+		buf.Write(encoded)
+	}
+	return keccak256(buf.Bytes()), nil
+}
+
+// EncodeType returns the type followed by all the struct types it references, sorted by name,
+// for example "Mail(Person from,Person to,string contents)Person(string name,address wallet)".
+func (td *TypedData) EncodeType(typeName string) (string, error) {
+	found := map[string]bool{}
+	if err := td.dependencies(typeName, found); err != nil {
+		return "", err
+	}
+	delete(found, typeName)
+	deps := make([]string, 0, len(found))
+	for dep := range found {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+
+	var b strings.Builder
+	for _, name := range append([]string{typeName}, deps...) {
+		b.WriteString(name)
+		b.WriteByte('(')
+		for i, field := range td.Types[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(field.Type)
+			b.WriteByte(' ')
+			b.WriteString(field.Name)
+		}
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// dependencies adds typeName and all the struct types it references to found.
+func (td *TypedData) dependencies(typeName string, found map[string]bool) error {
+	if found[typeName] {
+		return nil
+	}
+	fields, ok := td.Types[typeName]
+	if !ok {
+		return fmt.Errorf("eip712: unknown type %q", typeName)
+	}
+	found[typeName] = true
+	for _, field := range fields {
+		base, _, _ := splitArray(field.Type)
+		if _, ok = td.Types[base]; ok {
+			if err := td.dependencies(base, found); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// encodeValue returns the 32 byte encoding of value as a member of a struct.
+func (td *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if base, length, ok := splitArray(typ); ok {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an array for %s", typ)
+		}
+		if length >= 0 && len(items) != length {
+			return nil, fmt.Errorf("expected %d items for %s, found %d", length, typ, len(items))
+		}
+		buf := new(bytes.Buffer)
+		for _, item := range items {
+			encoded, err := td.encodeValue(base, item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(encoded)
+		}
+		return keccak256(buf.Bytes()), nil
+	}
+
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object for %s", typ)
+		}
+		return td.HashStruct(typ, data)
+	}
+
+	switch {
+	case typ == "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a string")
+		}
+		return keccak256([]byte(s)), nil
+	case typ == "bytes":
+		b, err := hexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return keccak256(b), nil
+	case typ == "bool":
+		var b bool
+		switch v := value.(type) {
+		case bool:
+			b = v
+		case string:
+			var err error
+			if b, err = strconv.ParseBool(v); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errors.New("expected a boolean")
+		}
+		out := make([]byte, 32)
+		if b {
+			out[31] = 1
+		}
+		return out, nil
+	case typ == "address":
+		b, err := hexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != 20 {
+			return nil, fmt.Errorf("expected 20 bytes for an address, found %d", len(b))
+		}
+		return leftPad(b), nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(strings.TrimPrefix(typ, "bytes"))
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unknown type %q", typ)
+		}
+		b, err := hexValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > size {
+			return nil, fmt.Errorf("expected at most %d bytes for %s, found %d", size, typ, len(b))
+		}
+		out := make([]byte, 32)
+		copy(out, b)
+		return out, nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		return encodeInteger(typ, value)
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// encodeInteger returns the 32 byte two's complement encoding of an intN or uintN value.
+func encodeInteger(typ string, value interface{}) ([]byte, error) {
+	signed := strings.HasPrefix(typ, "int")
+	bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"))
+	if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return nil, fmt.Errorf("expected an integer for %s", typ)
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q for %s", s, typ)
+	}
+
+	min, max := new(big.Int), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	if signed {
+		max.Rsh(max, 1)
+		min.Neg(max)
+	}
+	if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+		return nil, fmt.Errorf("integer %s out of range for %s", s, typ)
+	}
+	if n.Sign() < 0 {
+		n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return n.FillBytes(make([]byte, 32)), nil
+}
+
+// splitArray returns the item type and length of an array type T[n] or T[],
+// with a length of -1 for dynamic arrays.
+func splitArray(typ string) (string, int, bool) {
+	if !strings.HasSuffix(typ, "]") {
+		return typ, 0, false
+	}
+	i := strings.LastIndexByte(typ, '[')
+	if i < 0 {
+		return typ, 0, false
+	}
+	if i+2 == len(typ) {
+		return typ[:i], -1, true
+	}
+	length, err := strconv.Atoi(typ[i+1 : len(typ)-1])
+	if err != nil || length < 0 {
+		return typ, 0, false
+	}
+	return typ[:i], length, true
+}
+
+// hexValue decodes a 0x prefixed hex string.
+func hexValue(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("expected a hex string")
+	}
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return nil, fmt.Errorf("expected a 0x prefixed hex string, found %q", s)
+	}
+	return hex.DecodeString(s[2:])
+}
+
+// leftPad returns b left padded with zeros to 32 bytes.
+func leftPad(b []byte) []byte {
+	out := make([]byte, 32)
+	copy(out[32-len(b):], b)
+	return out
+}
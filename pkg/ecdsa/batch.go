@@ -0,0 +1,52 @@
+package ecdsa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"MPC_ECDSA/pkg/party"
+)
+
+// BatchError is returned when some signatures of a batch fail to verify.
+//
+// Culprits maps the index of each failed message to the parties whose signature shares are invalid for it.
+// The list is empty for a message whose culprits could not be identified from the shares alone.
+type BatchError struct {
+	Culprits map[int][]party.ID
+}
+
+// Error implements error.
+func (e *BatchError) Error() string {
+	indices := e.Failed()
+	parts := make([]string, 0, len(indices))
+	for _, i := range indices {
+		parts = append(parts, fmt.Sprintf("%d: %v", i, e.Culprits[i]))
+	}
+	return fmt.Sprintf("batch signature: %d signatures failed to verify, culprits per message {%s}", len(indices), strings.Join(parts, ", "))
+}
+
+// Failed returns the sorted indices of the messages whose signature failed to verify.
+func (e *BatchError) Failed() []int {
+	indices := make([]int, 0, len(e.Culprits))
+	for i := range e.Culprits {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// AllCulprits returns the parties that sent an invalid share for at least one message.
+func (e *BatchError) AllCulprits() []party.ID {
+	found := map[party.ID]bool{}
+	var culprits []party.ID
+	for _, i := range e.Failed() {
+		for _, j := range e.Culprits[i] {
+			if !found[j] {
+				found[j] = true
+				culprits = append(culprits, j)
+			}
+		}
+	}
+	return party.NewIDSlice(culprits)
+}
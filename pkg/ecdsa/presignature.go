@@ -29,6 +29,11 @@ func (sig *PreSignature) Group() curve.Curve {
 	return sig.R.Curve()
 }
 
+// Format implements fmt.Formatter, the shares of a presignature are never printed.
+func (sig *PreSignature) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprint(f, "[redacted presignature]")
+}
+
 // EmptyPreSignature returns a PreSignature with a given group, ready for unmarshalling.
 func EmptyPreSignature(group curve.Curve) *PreSignature {
 	return &PreSignature{
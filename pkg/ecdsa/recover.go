@@ -0,0 +1,177 @@
+package ecdsa
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"MPC_ECDSA/pkg/math/curve"
+)
+
+// CompactLen is the length of a signature in compact r || s form.
+const CompactLen = 64
+
+// RecoverableLen is the length of a signature in recoverable r || s || v form, as used by Ethereum.
+const RecoverableLen = 65
+
+// RecoveryID returns v ∈ {0,1,2,3}, where bit 0 is the parity of R's y coordinate,
+// and bit 1 is set if R's x coordinate overflowed the curve order when reduced to r.
+//
+// The id matches the signature as is, call Normalize first to get the id of the low-S form.
+func (sig Signature) RecoveryID() (byte, error) {
+	R, ok := sig.R.(*curve.Secp256k1Point)
+	if !ok {
+		return 0, errors.New("recovery id is only defined for secp256k1")
+	}
+	if R.IsIdentity() {
+		return 0, errors.New("signature has R at the identity")
+	}
+	rBytes, err := sig.R.XScalar().MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	var v byte
+	if !R.HasEvenY() {
+		v |= 1
+	}
+	if !bytes.Equal(rBytes, R.XBytes()) {
+		v |= 2
+	}
+	return v, nil
+}
+
+// IsLowS returns true if S is in the lower half of the curve order, s ⩽ n/2.
+func (sig Signature) IsLowS() bool {
+	s, err := sig.S.MarshalBinary()
+	if err != nil {
+		return false
+	}
+	negS, err := sig.S.Curve().NewScalar().Set(sig.S).Negate().MarshalBinary()
+	if err != nil {
+		return false
+	}
+	// s ⩽ n/2 ⇔ s ⩽ n - s
+	return bytes.Compare(s, negS) <= 0
+}
+
+// Normalize returns the equivalent signature with S in the lower half of the curve order.
+//
+// (R, s) and (-R, -s) are both valid for the same message, so R is negated along with S,
+// which flips the parity bit of the recovery id and keeps Verify working.
+func (sig Signature) Normalize() Signature {
+	if sig.IsLowS() {
+		return sig
+	}
+	return Signature{
+		R: sig.R.Negate(),
+		S: sig.S.Curve().NewScalar().Set(sig.S).Negate(),
+	}
+}
+
+// Compact returns the low-S signature as r || s, with each value encoded in 32 bytes.
+func (sig Signature) Compact() ([]byte, error) {
+	if _, ok := sig.R.(*curve.Secp256k1Point); !ok {
+		return nil, errors.New("compact signatures are only defined for secp256k1")
+	}
+	normalized := sig.Normalize()
+	rBytes, err := normalized.R.XScalar().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sBytes, err := normalized.S.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, RecoverableLen)
+	out = append(out, rBytes...)
+	out = append(out, sBytes...)
+	return out, nil
+}
+
+// CompactRecoverable returns the low-S signature as r || s || v, with v ∈ {0,1,2,3} the recovery id.
+//
+// This is the format of go-ethereum's crypto.Sign, EIP-191 personal_sign expects v + 27 instead.
+func (sig Signature) CompactRecoverable() ([]byte, error) {
+	out, err := sig.Compact()
+	if err != nil {
+		return nil, err
+	}
+	v, err := sig.Normalize().RecoveryID()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, v), nil
+}
+
+// DER returns the low-S signature encoded as an ASN.1 DER sequence of the integers r and s.
+func (sig Signature) DER() ([]byte, error) {
+	compact, err := sig.Compact()
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(compact[:32]),
+		S: new(big.Int).SetBytes(compact[32:]),
+	})
+}
+
+// RecoverPublicKey returns the public key X such that the recoverable signature sig = r || s || v is valid for hash.
+//
+// v may be given as a recovery id in {0,1,2,3}, or with the offset 27 used by Ethereum.
+// The hash is converted to a scalar in the same way as in Verify,
+// so the result can be compared with Config.PublicPoint().
+func RecoverPublicKey(hash, sig []byte) (curve.Point, error) {
+	if len(sig) != RecoverableLen {
+		return nil, fmt.Errorf("expected %d bytes for recoverable signature, found %d", RecoverableLen, len(sig))
+	}
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 3 {
+		return nil, fmt.Errorf("invalid recovery id %d", sig[64])
+	}
+	group := curve.Secp256k1{}
+
+	r := group.NewScalar()
+	if err := r.UnmarshalBinary(sig[:32]); err != nil {
+		return nil, fmt.Errorf("invalid r: %w", err)
+	}
+	s := group.NewScalar()
+	if err := s.UnmarshalBinary(sig[32:64]); err != nil {
+		return nil, fmt.Errorf("invalid s: %w", err)
+	}
+	if r.IsZero() || s.IsZero() {
+		return nil, errors.New("signature has a zero value")
+	}
+
+	// R|ₓ = r, or r + n if the x coordinate overflowed
+	x := new(big.Int).SetBytes(sig[:32])
+	if v&2 != 0 {
+		x.Add(x, group.Order().Big())
+		if x.BitLen() > 256 {
+			return nil, errors.New("invalid recovery id, r + n is not a valid x coordinate")
+		}
+	}
+	R, err := group.LiftX(x.FillBytes(make([]byte, 32)))
+	if err != nil {
+		return nil, err
+	}
+	var RPoint curve.Point = R
+	if v&1 != 0 {
+		RPoint = R.Negate()
+	}
+
+	// X = r⁻¹⋅(s⋅R - m⋅G)
+	m := curve.FromHash(group, hash)
+	rInv := group.NewScalar().Set(r).Invert()
+	X := rInv.Act(s.Act(RPoint).Sub(m.ActOnBase()))
+	if X.IsIdentity() {
+		return nil, errors.New("recovered public key is the identity")
+	}
+	return X, nil
+}
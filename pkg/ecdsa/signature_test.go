@@ -2,10 +2,17 @@ package ecdsa
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
 	"testing"
 
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/sample"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	dcrecdsa "github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func NewSignature(x curve.Scalar, hash []byte, k curve.Scalar) *Signature {
@@ -36,3 +43,76 @@ func TestSignature_Verify(t *testing.T) {
 		t.Error("verify failed")
 	}
 }
+
+func TestSignature_Recover(t *testing.T) {
+	group := curve.Secp256k1{}
+	hash := sha256.Sum256([]byte("hello"))
+
+	seenHighS := false
+	for i := 0; i < 16; i++ {
+		x := sample.Scalar(rand.Reader, group)
+		X := x.ActOnBase()
+		sig := NewSignature(x, hash[:], nil)
+		seenHighS = seenHighS || !sig.IsLowS()
+
+		normalized := sig.Normalize()
+		require.True(t, normalized.IsLowS())
+		require.True(t, normalized.Verify(X, hash[:]), "expected normalized signature to be valid")
+
+		recoverable, err := sig.CompactRecoverable()
+		require.NoError(t, err)
+		require.Len(t, recoverable, RecoverableLen)
+		recovered, err := RecoverPublicKey(hash[:], recoverable)
+		require.NoError(t, err)
+		assert.True(t, X.Equal(recovered), "expected to recover the public key")
+
+		// cross check against the secp256k1 library, which puts 27 + v first
+		XBytes, err := X.MarshalBinary()
+		require.NoError(t, err)
+		dcrCompact := append([]byte{27 + recoverable[64]}, recoverable[:64]...)
+		dcrKey, _, err := dcrecdsa.RecoverCompact(dcrCompact, hash[:])
+		require.NoError(t, err)
+		assert.Equal(t, XBytes, dcrKey.SerializeCompressed())
+
+		der, err := sig.DER()
+		require.NoError(t, err)
+		dcrSig, err := dcrecdsa.ParseDERSignature(der)
+		require.NoError(t, err)
+		pk, err := secp256k1.ParsePubKey(XBytes)
+		require.NoError(t, err)
+		assert.True(t, dcrSig.Verify(hash[:], pk), "expected DER signature to be valid")
+	}
+	assert.True(t, seenHighS, "expected some signatures to be normalized")
+
+	_, err := RecoverPublicKey(hash[:], make([]byte, CompactLen))
+	assert.Error(t, err)
+}
+
+// TestSignature_RecoverOverflow checks the recovery id of an R whose x coordinate is larger than the curve order.
+func TestSignature_RecoverOverflow(t *testing.T) {
+	group := curve.Secp256k1{}
+	hash := sha256.Sum256([]byte("hello"))
+
+	x := new(big.Int).Add(group.Order().Big(), big.NewInt(1))
+	var R *curve.Secp256k1Point
+	for R == nil {
+		x.Add(x, big.NewInt(1))
+		R, _ = group.LiftX(x.FillBytes(make([]byte, 32)))
+	}
+	// any s gives a valid signature for X = r⁻¹⋅(s⋅R - m⋅G)
+	s := sample.Scalar(rand.Reader, group)
+	m := curve.FromHash(group, hash[:])
+	X := group.NewScalar().Set(R.XScalar()).Invert().Act(s.Act(R).Sub(m.ActOnBase()))
+	sig := Signature{R: R, S: s}
+	require.True(t, sig.Verify(X, hash[:]))
+
+	v, err := sig.Normalize().RecoveryID()
+	require.NoError(t, err)
+	assert.Equal(t, byte(2), v&2, "expected overflow bit to be set")
+
+	recoverable, err := sig.CompactRecoverable()
+	require.NoError(t, err)
+	recovered, err := RecoverPublicKey(hash[:], recoverable)
+	require.NoError(t, err)
+	assert.True(t, X.Equal(recovered), "expected to recover the public key")
+}
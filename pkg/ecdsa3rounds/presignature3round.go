@@ -60,6 +60,11 @@ func (sig *PreSignature3) Group() curve.Curve {
 	return sig.R.Curve()
 }
 
+// Format implements fmt.Formatter, the shares of a presignature are never printed.
+func (sig *PreSignature3) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprint(f, "[redacted presignature]")
+}
+
 // EmptyPreSignature returns a PreSignature3 with a given group, ready for unmarshalling.
 func EmptyPreSignature(group curve.Curve) *PreSignature3 {
 	return &PreSignature3{
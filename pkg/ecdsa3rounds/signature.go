@@ -5,7 +5,10 @@
 // file LICENSE at the root of the source code distribution tree.
 package ecdsa3rounds
 
-import "MPC_ECDSA/pkg/math/curve"
+import (
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/math/curve"
+)
 
 type Signature struct {
 	R curve.Point
@@ -30,3 +33,44 @@ func (sig Signature) Verify(X curve.Point, hash []byte) bool {
 	R2 = sInv.Act(R2)
 	return R2.Equal(sig.R)
 }
+
+// toECDSA returns the same signature as an ecdsa.Signature, which shares its serialization.
+func (sig Signature) toECDSA() ecdsa.Signature {
+	return ecdsa.Signature{R: sig.R, S: sig.S}
+}
+
+// RecoveryID returns v ∈ {0,1,2,3}, see ecdsa.Signature.RecoveryID.
+func (sig Signature) RecoveryID() (byte, error) {
+	return sig.toECDSA().RecoveryID()
+}
+
+// IsLowS returns true if S is in the lower half of the curve order.
+func (sig Signature) IsLowS() bool {
+	return sig.toECDSA().IsLowS()
+}
+
+// Normalize returns the equivalent signature with S in the lower half of the curve order.
+func (sig Signature) Normalize() Signature {
+	normalized := sig.toECDSA().Normalize()
+	return Signature{R: normalized.R, S: normalized.S}
+}
+
+// Compact returns the low-S signature as r || s.
+func (sig Signature) Compact() ([]byte, error) {
+	return sig.toECDSA().Compact()
+}
+
+// CompactRecoverable returns the low-S signature as r || s || v.
+func (sig Signature) CompactRecoverable() ([]byte, error) {
+	return sig.toECDSA().CompactRecoverable()
+}
+
+// DER returns the low-S signature encoded in ASN.1 DER.
+func (sig Signature) DER() ([]byte, error) {
+	return sig.toECDSA().DER()
+}
+
+// RecoverPublicKey returns the public key for which the recoverable signature sig = r || s || v is valid.
+func RecoverPublicKey(hash, sig []byte) (curve.Point, error) {
+	return ecdsa.RecoverPublicKey(hash, sig)
+}
@@ -45,6 +45,11 @@ type SecretKey struct {
 	pinv, pinvsquared  *BigInt.Nat
 }
 
+// Format implements fmt.Formatter, the factors of a secret key are never printed.
+func (sk *SecretKey) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprint(f, "[redacted paillier secret key]")
+}
+
 // P returns the first of the two factors composing this key.
 func (sk *SecretKey) P() *BigInt.Nat {
 	return sk.p
@@ -0,0 +1,104 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package logging is the structured logging of the protocols, the protocol handlers and the communication layer.
+// The entries carry the protocol, SSID, round and party they belong to as fields.
+// Message payloads are only logged as their size and hash, never as their content,
+// and secret values such as scalars, Paillier secret keys and presignatures must not be passed to a logger.
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logger used by the package of this module, a *logrus.Logger or a *logrus.Entry can be injected.
+type Logger = logrus.FieldLogger
+
+// Fields is a set of fields attached to a log entry.
+type Fields = logrus.Fields
+
+// The names of the fields attached to the log entries.
+const (
+	// FieldProtocol is the ID of the protocol
+	FieldProtocol = "protocol"
+	// FieldSSID is the short hex encoded SSID of the protocol execution
+	FieldSSID = "ssid"
+	// FieldRound is the number of the round
+	FieldRound = "round"
+	// FieldParty is the ID of the local party
+	FieldParty = "party"
+	// FieldPeer is the ID of the other party a message is sent to or received from
+	FieldPeer = "peer"
+	// FieldSize is the size of a message payload in bytes
+	FieldSize = "size"
+	// FieldHash is the short hex encoded SHA-256 hash of a message payload
+	FieldHash = "hash"
+)
+
+// shortLength is the number of bytes of the SSID and hashes kept in the fields.
+const shortLength = 8
+
+var (
+	mtx sync.RWMutex
+	std Logger = logrus.StandardLogger()
+)
+
+// SetDefault replaces the logger used when none is injected, nil restores the standard logrus logger.
+func SetDefault(l Logger) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	if l == nil {
+		l = logrus.StandardLogger()
+	}
+	std = l
+}
+
+// Default returns the logger used when none is injected.
+func Default() Logger {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	return std
+}
+
+// Or returns l, or the default logger if l is nil.
+func Or(l Logger) Logger {
+	if l == nil {
+		return Default()
+	}
+	return l
+}
+
+// Session returns l with the fields of a protocol execution, the default logger is used if l is nil.
+func Session(l Logger, protocol string, ssid []byte, self party.ID) Logger {
+	return Or(l).WithFields(Fields{
+		FieldProtocol: protocol,
+		FieldSSID:     Short(ssid),
+		FieldParty:    self,
+	})
+}
+
+// Payload returns the fields describing a message payload without revealing it: its size and hash.
+func Payload(data []byte) Fields {
+	sum := sha256.Sum256(data)
+	return Fields{
+		FieldSize: len(data),
+		FieldHash: Short(sum[:]),
+	}
+}
+
+// Short hex encodes the first bytes of an identifier or a hash.
+func Short(data []byte) string {
+	if len(data) > shortLength {
+		data = data[:shortLength]
+	}
+	return hex.EncodeToString(data)
+}
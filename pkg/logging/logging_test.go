@@ -0,0 +1,73 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package logging_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/logging"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/pool"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logging.SetDefault(logger)
+	defer logging.SetDefault(nil)
+
+	ssid := []byte("0123456789abcdef")
+	payload := []byte("payload")
+	logging.Session(nil, "cmp/sign", ssid, "a").WithFields(logging.Payload(payload)).Info("sent message")
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "cmp/sign", entry.Data[logging.FieldProtocol])
+	assert.Equal(t, hex.EncodeToString(ssid[:8]), entry.Data[logging.FieldSSID])
+	assert.EqualValues(t, "a", entry.Data[logging.FieldParty])
+	assert.Equal(t, len(payload), entry.Data[logging.FieldSize])
+	assert.Len(t, entry.Data[logging.FieldHash], 16)
+
+	logging.SetDefault(nil)
+	assert.Equal(t, logrus.StandardLogger(), logging.Default())
+}
+
+// TestRedacted checks that the secret values are not printed, whatever the verb.
+func TestRedacted(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+	configs, partyIDs := test.GenerateConfig(group, 2, 1, rand.Reader, pl)
+	c := configs[partyIDs[0]]
+
+	share, err := c.ECDSA.MarshalBinary()
+	require.NoError(t, err)
+	presignature := ecdsa.EmptyPreSignature(group)
+	presignature.KShare = sample.Scalar(rand.Reader, group)
+	k, err := presignature.KShare.MarshalBinary()
+	require.NoError(t, err)
+
+	secrets := []string{hex.EncodeToString(share), hex.EncodeToString(k), c.Paillier.P().String(), c.Paillier.Phi().String()}
+	for _, verb := range []string{"%v", "%+v", "%#v", "%s", "%x"} {
+		for _, v := range []interface{}{c.ECDSA, c, c.Paillier, presignature, struct{ Share curve.Scalar }{c.ECDSA}} {
+			printed := fmt.Sprintf(verb, v)
+			for _, secret := range secrets {
+				assert.NotContains(t, printed, secret, verb)
+			}
+		}
+	}
+}
@@ -83,6 +83,11 @@ func (*Secp256k1Scalar) Curve() Curve {
 	return Secp256k1{}
 }
 
+// Format implements fmt.Formatter, the value of a scalar is never printed so that secret shares do not reach the logs.
+func (*Secp256k1Scalar) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprint(f, "[redacted scalar]")
+}
+
 func (s *Secp256k1Scalar) MarshalBinary() ([]byte, error) {
 	data := s.value.Bytes()
 	return data[:], nil
@@ -96,22 +96,29 @@ func (p *Exponent) Degree() int {
 }
 
 func (p *Exponent) add(q *Exponent) error {
-	if len(p.coefficients) != len(q.coefficients) {
-		return errors.New("q is not the same length as p")
+	if p.Degree() != q.Degree() {
+		return errors.New("q does not have the same degree as p")
 	}
 
-	if p.IsConstant != q.IsConstant {
-		return errors.New("p and q differ in 'IsConstant'")
+	// the constant coefficient of a constant polynomial is the identity, and is not stored
+	if p.IsConstant && !q.IsConstant {
+		p.coefficients = append([]curve.Point{p.group.NewPoint()}, p.coefficients...)
+		p.IsConstant = false
+	}
+	offset := 0
+	if q.IsConstant && !p.IsConstant {
+		offset = 1
 	}
 
-	for i := 0; i < len(p.coefficients); i++ {
-		p.coefficients[i] = p.coefficients[i].Add(q.coefficients[i])
+	for i := 0; i < len(q.coefficients); i++ {
+		p.coefficients[i+offset] = p.coefficients[i+offset].Add(q.coefficients[i])
 	}
 
 	return nil
 }
 
 // Sum creates a new Polynomial in the Exponent, by summing a slice of existing ones.
+// The polynomials may differ in IsConstant, as when a key is imported by a single dealer.
 func Sum(polynomials []*Exponent) (*Exponent, error) {
 	var err error
 
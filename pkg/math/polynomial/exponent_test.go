@@ -72,6 +72,35 @@ func TestSum(t *testing.T) {
 	assert.True(t, evaluationSum.Equal(evaluationPartial))
 }
 
+func TestSum_Constant(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	Deg := 5
+	randomIndex := sample.Scalar(rand.Reader, group)
+
+	// only the second polynomial has a non zero constant
+	polys := []*Polynomial{
+		NewPolynomial(group, Deg, group.NewScalar()),
+		NewPolynomial(group, Deg, sample.Scalar(rand.Reader, group)),
+		NewPolynomial(group, Deg, group.NewScalar()),
+	}
+	evaluationScalar := group.NewScalar()
+	constant := group.NewScalar()
+	polysExp := make([]*Exponent, len(polys))
+	for i := range polys {
+		polysExp[i] = NewPolynomialExponent(polys[i])
+		evaluationScalar.Add(polys[i].Evaluate(randomIndex))
+		constant.Add(polys[i].Constant())
+	}
+
+	summedExp, err := Sum(polysExp)
+	require.NoError(t, err)
+	assert.False(t, summedExp.IsConstant)
+	assert.Equal(t, Deg, summedExp.Degree())
+	assert.True(t, summedExp.Evaluate(randomIndex).Equal(evaluationScalar.ActOnBase()))
+	assert.True(t, summedExp.Constant().Equal(constant.ActOnBase()))
+}
+
 func TestMarshall(t *testing.T) {
 	group := curve.Secp256k1{}
 
@@ -29,6 +29,33 @@ func LagrangeSingle(group curve.Curve, interpolationDomain []party.ID, j party.I
 	return LagrangeFor(group, interpolationDomain, j)[j]
 }
 
+// LagrangeAt returns the Lagrange coefficients lⱼ(x) for all parties in the interpolation domain,
+// so that f(x) = ∑ⱼ lⱼ(x)⋅f(j) for a polynomial f of degree less than the size of the domain.
+//
+// lⱼ(x) = ∏ᵢ≠ⱼ (x - xᵢ)/(xⱼ - xᵢ).
+func LagrangeAt(group curve.Curve, interpolationDomain []party.ID, x party.ID) map[party.ID]curve.Scalar {
+	scalars, _ := getScalarsAndNumerator(group, interpolationDomain)
+	xScalar := x.Scalar(group)
+	tmp := group.NewScalar()
+
+	coefficients := make(map[party.ID]curve.Scalar, len(scalars))
+	for j, xJ := range scalars {
+		numerator := group.NewScalar().SetNat(new(BigInt.Nat).SetUint64(1))
+		denominator := group.NewScalar().SetNat(new(BigInt.Nat).SetUint64(1))
+		for i, xI := range scalars {
+			if i == j {
+				continue
+			}
+			// numerator *= x - xᵢ
+			numerator.Mul(tmp.Set(xScalar).Sub(xI))
+			// denominator *= xⱼ - xᵢ
+			denominator.Mul(tmp.Set(xJ).Sub(xI))
+		}
+		coefficients[j] = denominator.Invert().Mul(numerator)
+	}
+	return coefficients
+}
+
 // getScalarsAndNumerator 获取[1,2,3,4...], 1*2*3*4... returns the Scalars associated to the list of party.IDs.
 func getScalarsAndNumerator(group curve.Curve, interpolationDomain []party.ID) (map[party.ID]curve.Scalar, curve.Scalar) {
 	// numerator = x₀ * … * xₖ
@@ -1,11 +1,13 @@
 package polynomial_test
 
 import (
+	"crypto/rand"
 	"testing"
 
 	"MPC_ECDSA/internal/test"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/math/sample"
 
 	"MPC_ECDSA/pkg/BigInt"
 	"github.com/stretchr/testify/assert"
@@ -30,3 +32,21 @@ func TestLagrange(t *testing.T) {
 	assert.True(t, sumEven.Equal(one))
 	assert.True(t, sumOdd.Equal(one))
 }
+
+func TestLagrangeAt(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	N := 6
+	allIDs := test.PartyIDs(N)
+	f := polynomial.NewPolynomial(group, N-2, sample.Scalar(rand.Reader, group))
+
+	// f(x) is interpolated from the evaluations of the other parties
+	x := allIDs[0]
+	domain := allIDs[1:]
+	coefs := polynomial.LagrangeAt(group, domain, x)
+	sum := group.NewScalar()
+	for _, j := range domain {
+		sum.Add(group.NewScalar().Set(coefs[j]).Mul(f.Evaluate(j.Scalar(group))))
+	}
+	assert.True(t, sum.Equal(f.Evaluate(x.Scalar(group))))
+}
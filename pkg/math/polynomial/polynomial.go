@@ -55,6 +55,14 @@ func (p *Polynomial) Constant() curve.Scalar {
 	return p.group.NewScalar().Set(p.coefficients[0])
 }
 
+// Erase sets every coefficient to zero, once the shares have been computed.
+// The constant given to NewPolynomial is erased with it.
+func (p *Polynomial) Erase() {
+	for _, c := range p.coefficients {
+		c.Set(p.group.NewScalar())
+	}
+}
+
 // Degree is the highest power of the Polynomial.
 func (p *Polynomial) Degree() uint32 {
 	return uint32(len(p.coefficients)) - 1
@@ -24,6 +24,17 @@ func TestPolynomial_Constant(t *testing.T) {
 	require.True(t, poly.Constant().Equal(secret))
 }
 
+func TestPolynomial_Erase(t *testing.T) {
+	group := curve.Secp256k1{}
+
+	secret := sample.Scalar(rand.Reader, group)
+	poly := NewPolynomial(group, 10, secret)
+	poly.Erase()
+	require.True(t, poly.Constant().IsZero())
+	require.True(t, secret.IsZero())
+	require.True(t, poly.Evaluate(sample.Scalar(rand.Reader, group)).IsZero())
+}
+
 func TestPolynomial_Evaluate(t *testing.T) {
 	group := curve.Secp256k1{}
 
@@ -0,0 +1,149 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package mnemonic backs up the contribution of a party to a key generated with useMnemonic.
+//
+// During keygen, the constant of the VSS polynomial of each party is derived from a 24 word BIP-39 mnemonic.
+// The secret ECDSA key is the sum of the constants of all the parties,
+// so the mnemonics of all the parties are a paper backup of the key.
+package mnemonic
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"MPC_ECDSA/pkg/BigInt"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// EntropyBits is the entropy of a generated mnemonic, which then has 24 words.
+const EntropyBits = 256
+
+// encryptionInfo binds the key derived for Encrypt to its use.
+const encryptionInfo = "MPC_ECDSA mnemonic backup"
+
+// ErrDecrypt is returned when a backup was not encrypted to the given key, or has been tampered with.
+var ErrDecrypt = errors.New("mnemonic: wrong key or corrupted backup")
+
+// Generate returns a new 24 word BIP-39 mnemonic.
+func Generate() (string, error) {
+	entropy, err := bip39.NewEntropy(EntropyBits)
+	if err != nil {
+		return "", fmt.Errorf("mnemonic: %w", err)
+	}
+	words, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", fmt.Errorf("mnemonic: %w", err)
+	}
+	return words, nil
+}
+
+// VSSConstant recomputes the constant of the VSS polynomial derived from the mnemonic during keygen,
+// as the key of the BIP-32 master key of the BIP-39 seed, with an empty password.
+func VSSConstant(group curve.Curve, words string) (curve.Scalar, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(words, "")
+	if err != nil {
+		return nil, fmt.Errorf("mnemonic: %w", err)
+	}
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, fmt.Errorf("mnemonic: %w", err)
+	}
+	return group.NewScalar().SetNat(new(BigInt.Nat).SetBytes(masterKey.Key)), nil
+}
+
+// Combine returns the secret key of a key generated with the mnemonics of all its parties,
+// which is the sum of their VSS constants.
+func Combine(group curve.Curve, mnemonics []string) (curve.Scalar, error) {
+	if len(mnemonics) == 0 {
+		return nil, errors.New("mnemonic: no mnemonic to combine")
+	}
+	secret := group.NewScalar()
+	for i, words := range mnemonics {
+		constant, err := VSSConstant(group, words)
+		if err != nil {
+			return nil, fmt.Errorf("mnemonic %d: %w", i, err)
+		}
+		secret.Add(constant)
+	}
+	return secret, nil
+}
+
+// Encrypt encrypts the mnemonic to the public key of an operator, with ECIES.
+// The result is the encoding of an ephemeral public key E = e⋅G, followed by a nonce
+// and the XChaCha20-Poly1305 encryption of the mnemonic with a key derived from e⋅recipient.
+func Encrypt(words string, recipient curve.Point) ([]byte, error) {
+	if recipient == nil || recipient.IsIdentity() {
+		return nil, errors.New("mnemonic: invalid recipient")
+	}
+	group := recipient.Curve()
+	e, E := sample.ScalarPointPair(rand.Reader, group)
+	ephemeral, err := E.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := encryptionKey(ephemeral, e.Act(recipient))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("mnemonic: failed to generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, ephemeral...), nonce...)
+	return aead.Seal(out, nonce, []byte(words), ephemeral), nil
+}
+
+// Decrypt recovers a mnemonic encrypted with Encrypt, using the secret key of the operator.
+func Decrypt(backup []byte, secret curve.Scalar) (string, error) {
+	group := secret.Curve()
+	generator, err := group.NewBasePoint().MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	ephemeralLen := len(generator)
+	if len(backup) < ephemeralLen+chacha20poly1305.NonceSizeX+chacha20poly1305.Overhead {
+		return "", ErrDecrypt
+	}
+	ephemeral := backup[:ephemeralLen]
+	E := group.NewPoint()
+	if err := E.UnmarshalBinary(ephemeral); err != nil || E.IsIdentity() {
+		return "", ErrDecrypt
+	}
+	aead, err := encryptionKey(ephemeral, secret.Act(E))
+	if err != nil {
+		return "", err
+	}
+	nonce := backup[ephemeralLen : ephemeralLen+chacha20poly1305.NonceSizeX]
+	words, err := aead.Open(nil, nonce, backup[ephemeralLen+chacha20poly1305.NonceSizeX:], ephemeral)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(words), nil
+}
+
+// encryptionKey derives the key of Encrypt from the shared point, with HKDF-SHA256 salted by the ephemeral key.
+func encryptionKey(ephemeral []byte, shared curve.Point) (cipher.AEAD, error) {
+	sharedBytes, err := shared.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, sharedBytes, ephemeral, []byte(encryptionInfo)), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
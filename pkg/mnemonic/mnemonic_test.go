@@ -0,0 +1,89 @@
+package mnemonic
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"strings"
+	"testing"
+
+	"MPC_ECDSA/pkg/BigInt"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tyler-smith/go-bip39"
+)
+
+var group = curve.Secp256k1{}
+
+func TestVSSConstant(t *testing.T) {
+	words, err := Generate()
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(words), 24)
+
+	constant, err := VSSConstant(group, words)
+	require.NoError(t, err)
+	again, err := VSSConstant(group, words)
+	require.NoError(t, err)
+	assert.True(t, constant.Equal(again))
+
+	// the constant is the key of the BIP-32 master key of the seed
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(bip39.NewSeed(words, ""))
+	expected := group.NewScalar().SetNat(new(BigInt.Nat).SetBytes(mac.Sum(nil)[:32]))
+	assert.True(t, expected.Equal(constant))
+
+	// a word is missing, or the checksum is wrong
+	_, err = VSSConstant(group, strings.Join(strings.Fields(words)[1:], " "))
+	assert.Error(t, err)
+	_, err = VSSConstant(group, strings.Repeat("abandon ", 23)+"abandon")
+	assert.Error(t, err)
+	_, err = VSSConstant(group, strings.Repeat("abandon ", 23)+"art")
+	assert.NoError(t, err)
+}
+
+func TestCombine(t *testing.T) {
+	var mnemonics []string
+	sum := group.NewScalar()
+	for i := 0; i < 3; i++ {
+		words, err := Generate()
+		require.NoError(t, err)
+		constant, err := VSSConstant(group, words)
+		require.NoError(t, err)
+		sum.Add(constant)
+		mnemonics = append(mnemonics, words)
+	}
+	secret, err := Combine(group, mnemonics)
+	require.NoError(t, err)
+	assert.True(t, sum.Equal(secret))
+
+	_, err = Combine(group, nil)
+	assert.Error(t, err)
+}
+
+func TestEncrypt(t *testing.T) {
+	words, err := Generate()
+	require.NoError(t, err)
+	secret, operator := sample.ScalarPointPair(rand.Reader, group)
+
+	backup, err := Encrypt(words, operator)
+	require.NoError(t, err)
+	assert.NotContains(t, string(backup), words)
+	decrypted, err := Decrypt(backup, secret)
+	require.NoError(t, err)
+	assert.Equal(t, words, decrypted)
+
+	// only the operator can decrypt, and the backup cannot be modified
+	_, err = Decrypt(backup, sample.Scalar(rand.Reader, group))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	backup[len(backup)-1] ^= 1
+	_, err = Decrypt(backup, secret)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = Decrypt(backup[:40], secret)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = Encrypt(words, group.NewPoint())
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package policy decides whether a party releases its signature share for a signing request.
+//
+// The signing protocols evaluate a Policy right before they compute the local signature share,
+// so that each cosigner enforces its own rules whatever the other parties agreed to.
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+)
+
+// Decision is the outcome of a policy for a signing request.
+type Decision int
+
+const (
+	// Allow releases the signature share.
+	Allow Decision = iota
+	// Deny refuses the signing request.
+	Deny
+	// Defer refuses the signing request until it is approved, e.g. by a human.
+	Defer
+)
+
+// String implements fmt.Stringer.
+func (d Decision) String() string {
+	switch d {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	case Defer:
+		return "defer"
+	default:
+		return fmt.Sprintf("decision(%d)", int(d))
+	}
+}
+
+var (
+	// ErrDenied is returned by Enforce when the policy denies a request.
+	ErrDenied = errors.New("policy: signing request denied")
+	// ErrDeferred is returned by Enforce when the policy defers a request.
+	ErrDeferred = errors.New("policy: signing request deferred")
+)
+
+// Request is a decoded signing request.
+//
+// The signing protocol fills in the session data, the caller may bind the key ID and the signed message with Bind.
+type Request struct {
+	// Protocol is the ID of the signing protocol
+	Protocol string
+	// SelfID is the ID of the party evaluating the policy
+	SelfID party.ID
+	// Signers are the parties of the signature
+	Signers party.IDSlice
+	// PublicKey is the public key of the signature
+	PublicKey curve.Point
+	// Digest is the digest signed
+	Digest digest.Digest
+	// PresignatureID is the ID of the presignature used, nil for a signature without presignature
+	PresignatureID []byte
+	// Time is the time of the request
+	Time time.Time
+
+	// KeyID is the ID of the key in the key store, empty if unknown
+	KeyID string
+	// Message is the data hashed into Digest, nil if unknown
+	Message []byte
+	// Destination is the recipient decoded from Message, empty if unknown
+	Destination string
+	// Value is the amount decoded from Message, nil if unknown
+	Value *big.Int
+}
+
+// Verdict is the decision of a policy, with the rule which decided and its reason.
+type Verdict struct {
+	Decision Decision
+	Rule     string
+	Reason   string
+}
+
+// Policy decides on the signing requests of a party.
+type Policy interface {
+	Evaluate(req *Request) Verdict
+}
+
+// Func is a Policy defined by a function.
+type Func func(req *Request) Verdict
+
+// Evaluate implements Policy.
+func (f Func) Evaluate(req *Request) Verdict { return f(req) }
+
+// All returns a policy allowing a request only if all the policies allow it.
+// The first verdict which is not Allow is returned.
+func All(policies ...Policy) Policy {
+	return Func(func(req *Request) Verdict {
+		for _, p := range policies {
+			if p == nil {
+				continue
+			}
+			if v := p.Evaluate(req); v.Decision != Allow {
+				return v
+			}
+		}
+		return Verdict{Decision: Allow}
+	})
+}
+
+// Enforce evaluates p on req, and returns nil only if p allows it.
+// A nil policy allows every request.
+func Enforce(p Policy, req *Request) error {
+	if p == nil {
+		return nil
+	}
+	v := p.Evaluate(req)
+	switch v.Decision {
+	case Allow:
+		return nil
+	case Defer:
+		return fmt.Errorf("%w by rule %s: %s", ErrDeferred, v.Rule, v.Reason)
+	default:
+		return fmt.Errorf("%w by rule %s: %s", ErrDenied, v.Rule, v.Reason)
+	}
+}
+
+// Bind returns a policy completing each request with keyID and message before evaluating p.
+// The message must hash to the digest of the request, its destination and value are decoded when the format is known:
+// the "to" and "value" fields of EIP-712 typed data.
+func Bind(p Policy, keyID string, message []byte) Policy {
+	return Func(func(req *Request) Verdict {
+		bound := *req
+		bound.KeyID = keyID
+		if message != nil {
+			d, err := digest.Compute(req.Digest.Scheme, message)
+			if err != nil || !bytes.Equal(d.Hash, req.Digest.Hash) {
+				return Verdict{Decision: Deny, Rule: "message", Reason: "the message does not match the signed digest"}
+			}
+			bound.Message = message
+			if req.Digest.Scheme == digest.EIP712 {
+				bound.Destination, bound.Value = decodeTypedData(message)
+			}
+		}
+		return p.Evaluate(&bound)
+	})
+}
+
+// decodeTypedData returns the "to" and "value" fields of the message of JSON encoded typed data, if they are present.
+func decodeTypedData(data []byte) (destination string, value *big.Int) {
+	var typedData digest.TypedData
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// keep integers exact, they may not fit in a float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&typedData); err != nil {
+		return "", nil
+	}
+	if to, ok := typedData.Message["to"].(string); ok {
+		destination = to
+	}
+	switch v := typedData.Message["value"].(type) {
+	case json.Number:
+		value, _ = new(big.Int).SetString(v.String(), 10)
+	case string:
+		value, _ = new(big.Int).SetString(v, 0)
+	}
+	return destination, value
+}
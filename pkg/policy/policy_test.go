@@ -0,0 +1,154 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package policy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const transferTypedData = `{
+  "types": {
+    "EIP712Domain": [{"name": "name", "type": "string"}, {"name": "chainId", "type": "uint256"}],
+    "Transfer": [{"name": "to", "type": "address"}, {"name": "value", "type": "uint256"}]
+  },
+  "primaryType": "Transfer",
+  "domain": {"name": "Wallet", "chainId": 1},
+  "message": {"to": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "value": %s}
+}`
+
+func transfer(t *testing.T, value string) ([]byte, digest.Digest) {
+	message := []byte(strings.Replace(transferTypedData, "%s", value, 1))
+	d, err := digest.Compute(digest.EIP712, message)
+	require.NoError(t, err)
+	return message, d
+}
+
+func newRequest(d digest.Digest, at time.Time) *Request {
+	_, publicKey := sample.ScalarPointPair(rand.Reader, curve.Secp256k1{})
+	return &Request{Protocol: "cmp/sign", SelfID: "a", Signers: []party.ID{"a", "b"}, PublicKey: publicKey, Digest: d, Time: at}
+}
+
+func TestRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+window:
+  start: "09:00"
+  end: "18:00"
+  days: [Monday, Tuesday, Wednesday, Thursday, Friday]
+destinations:
+  - "0xcd2a3d9f938e13cd947ec05abc7fe734df8dd826"
+maxValue: "1000"
+dailyLimit: "1500"
+approvalAbove: "500"
+`), 0600))
+	rules, err := LoadRules(path)
+	require.NoError(t, err)
+	record, err := OpenRecord(filepath.Join(dir, "record.jsonl"))
+	require.NoError(t, err)
+	engine := NewEngine(rules, record)
+
+	// a Wednesday at noon
+	noon := time.Date(2023, 6, 7, 12, 0, 0, 0, time.UTC)
+	evaluate := func(value string, at time.Time) (Verdict, *Request) {
+		message, d := transfer(t, value)
+		req := newRequest(d, at)
+		return Bind(engine, "wallet1", message).Evaluate(req), req
+	}
+
+	v, _ := evaluate("400", noon)
+	assert.Equal(t, Allow, v.Decision, v.Reason)
+	v, _ = evaluate("400", noon.Add(-4*time.Hour))
+	assert.Equal(t, "window", v.Rule)
+	v, _ = evaluate("400", noon.Add(3*24*time.Hour))
+	assert.Equal(t, "window", v.Rule, "a Saturday")
+	v, _ = evaluate("2000", noon)
+	assert.Equal(t, "maxValue", v.Rule)
+
+	v, req := evaluate("800", noon)
+	assert.Equal(t, Defer, v.Decision, "a large value requires an approval")
+	assert.True(t, errors.Is(Enforce(Bind(engine, "wallet1", nil), req), ErrDenied), "the value is unknown without the message")
+
+	rules.Approved = []string{"0x" + hex.EncodeToString(req.Digest.Hash)}
+	message, _ := transfer(t, "800")
+	v = Bind(engine, "wallet1", message).Evaluate(req)
+	assert.Equal(t, Allow, v.Decision, "the approved request is allowed")
+
+	// the public key differs between requests, the daily limit is per key
+	v = Bind(engine, "wallet1", message).Evaluate(&Request{PublicKey: req.PublicKey, Digest: req.Digest, Time: noon})
+	assert.Equal(t, "dailyLimit", v.Rule, "800 + 800 is above the daily limit")
+
+	wrong, _ := transfer(t, "1")
+	v = Bind(engine, "wallet1", wrong).Evaluate(req)
+	assert.Equal(t, "message", v.Rule, "the message must match the digest")
+
+	other := strings.Replace(string(message), "CD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826", "0000000000000000000000000000000000000001", 1)
+	d, err := digest.Compute(digest.EIP712, []byte(other))
+	require.NoError(t, err)
+	v = Bind(engine, "wallet1", []byte(other)).Evaluate(newRequest(d, noon))
+	assert.Equal(t, "destinations", v.Rule)
+
+	_, err = LoadRules(writeFile(t, dir, "bad.json", `{"maxValue": "-1"}`))
+	assert.Error(t, err)
+	_, err = LoadRules(writeFile(t, dir, "bad.yml", "window: {start: \"25:00\", end: \"18:00\"}"))
+	assert.Error(t, err)
+}
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	record, err := OpenRecord(path)
+	require.NoError(t, err)
+
+	_, d := transfer(t, "1")
+	for i := 0; i < 3; i++ {
+		req := newRequest(d, time.Now())
+		req.Value = big.NewInt(int64(i))
+		require.NoError(t, record.Append(req, Verdict{Decision: Allow}))
+	}
+	head := record.Head()
+
+	reopened, err := OpenRecord(path)
+	require.NoError(t, err)
+	assert.Equal(t, head, reopened.Head())
+	assert.Len(t, reopened.Entries(), 3)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	tampered := strings.Replace(string(data), `"decision":"allow"`, `"decision":"deny"`, 1)
+	require.NoError(t, os.WriteFile(path, []byte(tampered), 0600))
+	_, err = OpenRecord(path)
+	assert.True(t, errors.Is(err, ErrTampered), "modified entry")
+
+	removed := lines[0] + "\n" + lines[2] + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(removed), 0600))
+	_, err = OpenRecord(path)
+	assert.True(t, errors.Is(err, ErrTampered), "removed entry")
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
@@ -0,0 +1,239 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"MPC_ECDSA/pkg/party"
+)
+
+// ErrTampered is returned when the hash chain of a record is broken.
+var ErrTampered = errors.New("policy: decision record was tampered with")
+
+// Entry is a decision kept in a Record.
+type Entry struct {
+	// Seq is the index of the entry, starting from 0
+	Seq uint64 `json:"seq"`
+	// Time is the time of the request
+	Time time.Time `json:"time"`
+
+	Protocol       string     `json:"protocol"`
+	SelfID         party.ID   `json:"selfID"`
+	Signers        []party.ID `json:"signers"`
+	PublicKey      string     `json:"publicKey"`
+	KeyID          string     `json:"keyID,omitempty"`
+	Scheme         string     `json:"scheme"`
+	Digest         string     `json:"digest"`
+	PresignatureID string     `json:"presignatureID,omitempty"`
+	Destination    string     `json:"destination,omitempty"`
+	Value          string     `json:"value,omitempty"`
+
+	Decision string `json:"decision"`
+	Rule     string `json:"rule,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+
+	// Prev is the hash of the previous entry, empty for the first one
+	Prev string `json:"prev"`
+	// Hash = SHA-256(Prev || entry without Hash)
+	Hash string `json:"hash"`
+}
+
+// hash returns the hash of the entry, computed over all its fields but Hash.
+func (e Entry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(e.Prev))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// value returns the value of the entry, nil if it is unknown.
+func (e Entry) value() *big.Int {
+	if e.Value == "" {
+		return nil
+	}
+	v, _ := new(big.Int).SetString(e.Value, 10)
+	return v
+}
+
+// Record is a tamper-evident append-only file of policy decisions, one JSON entry per line.
+//
+// Each entry contains the hash of the previous one, so that modifying, removing or reordering
+// entries breaks the chain. The hash of the last entry should be kept elsewhere to detect truncation.
+type Record struct {
+	mtx     sync.Mutex
+	path    string
+	entries []Entry
+}
+
+// OpenRecord loads the record at path, creating it if it does not exist, and verifies its hash chain.
+func OpenRecord(path string) (*Record, error) {
+	r := &Record{path: path}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err = json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrTampered, len(r.entries), err)
+		}
+		r.entries = append(r.entries, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err = r.Verify(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Verify checks the hash chain of the record.
+func (r *Record) Verify() error {
+	prev := ""
+	for i, e := range r.entries {
+		if e.Seq != uint64(i) || e.Prev != prev {
+			return fmt.Errorf("%w: entry %d is out of sequence", ErrTampered, i)
+		}
+		h, err := e.hash()
+		if err != nil {
+			return err
+		}
+		if h != e.Hash {
+			return fmt.Errorf("%w: entry %d was modified", ErrTampered, i)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// Head returns the hash of the last entry, empty if the record is empty.
+func (r *Record) Head() string {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.entries) == 0 {
+		return ""
+	}
+	return r.entries[len(r.entries)-1].Hash
+}
+
+// Entries returns a copy of the entries of the record.
+func (r *Record) Entries() []Entry {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]Entry{}, r.entries...)
+}
+
+// Append adds the verdict on req to the record, and syncs it to disk.
+func (r *Record) Append(req *Request, v Verdict) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	e := Entry{
+		Seq:       uint64(len(r.entries)),
+		Time:      req.Time.UTC(),
+		Protocol:  req.Protocol,
+		SelfID:    req.SelfID,
+		Signers:   req.Signers,
+		KeyID:     req.KeyID,
+		Scheme:    string(req.Digest.Scheme),
+		Digest:    hex.EncodeToString(req.Digest.Hash),
+		Decision:  v.Decision.String(),
+		Rule:      v.Rule,
+		Reason:    v.Reason,
+		PublicKey: publicKeyHex(req),
+	}
+	if req.PresignatureID != nil {
+		e.PresignatureID = hex.EncodeToString(req.PresignatureID)
+	}
+	e.Destination = req.Destination
+	if req.Value != nil {
+		e.Value = req.Value.String()
+	}
+	if len(r.entries) > 0 {
+		e.Prev = r.entries[len(r.entries)-1].Hash
+	}
+	h, err := e.hash()
+	if err != nil {
+		return err
+	}
+	e.Hash = h
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+// allowedValue returns the sum of the values allowed for the key of req on the same UTC day as req.
+func (r *Record) allowedValue(req *Request) *big.Int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	sum := new(big.Int)
+	key := publicKeyHex(req)
+	day := req.Time.UTC().Format("2006-01-02")
+	for _, e := range r.entries {
+		if e.Decision != Allow.String() || e.PublicKey != key || e.Time.UTC().Format("2006-01-02") != day {
+			continue
+		}
+		if v := e.value(); v != nil {
+			sum.Add(sum, v)
+		}
+	}
+	return sum
+}
+
+// publicKeyHex returns the hex encoding of the public key of req, empty if it is missing.
+func publicKeyHex(req *Request) string {
+	if req.PublicKey == nil {
+		return ""
+	}
+	data, err := req.PublicKey.MarshalBinary()
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(data)
+}
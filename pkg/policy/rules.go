@@ -0,0 +1,239 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package policy
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules is a rule-based policy, loaded from JSON or YAML. The zero Rules allows every request.
+//
+// The rules are checked in order: time window, destinations, maximum value, daily limit and approval.
+// When a value rule is set, a request whose value is unknown is denied.
+type Rules struct {
+	// Window restricts the time of the requests, any time is allowed if it is nil
+	Window *Window `json:"window,omitempty" yaml:"window,omitempty"`
+	// Destinations is the allowlist of destinations, compared without case, any destination is allowed if it is empty
+	Destinations []string `json:"destinations,omitempty" yaml:"destinations,omitempty"`
+	// MaxValue is the maximum value of a single request, as a decimal integer
+	MaxValue string `json:"maxValue,omitempty" yaml:"maxValue,omitempty"`
+	// DailyLimit is the maximum sum of the values allowed for a key in a UTC day, as a decimal integer
+	DailyLimit string `json:"dailyLimit,omitempty" yaml:"dailyLimit,omitempty"`
+	// ApprovalAbove defers the requests with a greater value until their digest is in Approved, as a decimal integer
+	ApprovalAbove string `json:"approvalAbove,omitempty" yaml:"approvalAbove,omitempty"`
+	// Approved lists the hex encoded digests approved by a human
+	Approved []string `json:"approved,omitempty" yaml:"approved,omitempty"`
+}
+
+// Window is a daily time window.
+type Window struct {
+	// Start and End are formatted as "15:04", the window wraps past midnight if End is before Start
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+	// Days are the allowed days of the week, e.g. "Monday", every day if it is empty
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+	// Location is the IANA time zone of the window, UTC if it is empty
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// LoadRules reads the rules at path, as YAML if its extension is .yaml or .yml and as JSON otherwise.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, rules)
+	default:
+		err = json.Unmarshal(data, rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("policy: %s: %w", path, err)
+	}
+	if err = rules.Validate(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Validate checks the format of the rules.
+func (r *Rules) Validate() error {
+	for name, amount := range map[string]string{"maxValue": r.MaxValue, "dailyLimit": r.DailyLimit, "approvalAbove": r.ApprovalAbove} {
+		if _, err := parseAmount(amount); err != nil {
+			return fmt.Errorf("policy: %s: %w", name, err)
+		}
+	}
+	if r.Window != nil {
+		if _, _, _, err := r.Window.parse(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseAmount parses a non-negative decimal integer, nil if s is empty.
+func parseAmount(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return v, nil
+}
+
+// parse returns the location of the window, and its start and end in minutes after midnight.
+func (w *Window) parse() (location *time.Location, start, end int, err error) {
+	location = time.UTC
+	if w.Location != "" {
+		if location, err = time.LoadLocation(w.Location); err != nil {
+			return nil, 0, 0, fmt.Errorf("policy: window: %w", err)
+		}
+	}
+	minutes := func(s string) (int, error) {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return 0, fmt.Errorf("policy: window: %w", err)
+		}
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if start, err = minutes(w.Start); err != nil {
+		return nil, 0, 0, err
+	}
+	if end, err = minutes(w.End); err != nil {
+		return nil, 0, 0, err
+	}
+	for _, day := range w.Days {
+		if !validDay(day) {
+			return nil, 0, 0, fmt.Errorf("policy: window: unknown day %q", day)
+		}
+	}
+	return location, start, end, nil
+}
+
+// validDay returns true if day is the name of a day of the week.
+func validDay(day string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), day) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains returns true if t is in the window.
+func (w *Window) contains(t time.Time) bool {
+	location, start, end, err := w.parse()
+	if err != nil {
+		return false
+	}
+	t = t.In(location)
+	if len(w.Days) > 0 {
+		found := false
+		for _, day := range w.Days {
+			found = found || strings.EqualFold(t.Weekday().String(), day)
+		}
+		if !found {
+			return false
+		}
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return start <= now && now < end
+	}
+	return now >= start || now < end
+}
+
+// evaluate applies the rules to req, allowed is the sum of the values already allowed today for the key.
+func (r *Rules) evaluate(req *Request, allowed func() *big.Int) Verdict {
+	if r.Window != nil && !r.Window.contains(req.Time) {
+		return Verdict{Decision: Deny, Rule: "window", Reason: "outside of the signing window"}
+	}
+	if len(r.Destinations) > 0 {
+		found := false
+		for _, destination := range r.Destinations {
+			found = found || (req.Destination != "" && strings.EqualFold(destination, req.Destination))
+		}
+		if !found {
+			return Verdict{Decision: Deny, Rule: "destinations", Reason: fmt.Sprintf("destination %q is not allowed", req.Destination)}
+		}
+	}
+	maxValue, _ := parseAmount(r.MaxValue)
+	dailyLimit, _ := parseAmount(r.DailyLimit)
+	approvalAbove, _ := parseAmount(r.ApprovalAbove)
+	if (maxValue != nil || dailyLimit != nil || approvalAbove != nil) && req.Value == nil {
+		return Verdict{Decision: Deny, Rule: "value", Reason: "the value of the request is unknown"}
+	}
+	if maxValue != nil && req.Value.Cmp(maxValue) > 0 {
+		return Verdict{Decision: Deny, Rule: "maxValue", Reason: fmt.Sprintf("value %s is above %s", req.Value, maxValue)}
+	}
+	if dailyLimit != nil {
+		total := new(big.Int).Add(allowed(), req.Value)
+		if total.Cmp(dailyLimit) > 0 {
+			return Verdict{Decision: Deny, Rule: "dailyLimit", Reason: fmt.Sprintf("daily total %s is above %s", total, dailyLimit)}
+		}
+	}
+	if approvalAbove != nil && req.Value.Cmp(approvalAbove) > 0 {
+		approved := false
+		for _, h := range r.Approved {
+			approved = approved || strings.EqualFold(strings.TrimPrefix(h, "0x"), hex.EncodeToString(req.Digest.Hash))
+		}
+		if !approved {
+			return Verdict{Decision: Defer, Rule: "approvalAbove", Reason: fmt.Sprintf("value %s requires the approval of digest %x", req.Value, req.Digest.Hash)}
+		}
+	}
+	return Verdict{Decision: Allow}
+}
+
+// Engine evaluates rules, and keeps every decision in a record.
+type Engine struct {
+	mtx    sync.Mutex
+	rules  *Rules
+	record *Record
+}
+
+// NewEngine returns a policy evaluating rules. If record is not nil, every decision is appended to it,
+// and a request is denied if its decision cannot be recorded. The daily limit is computed from the record.
+func NewEngine(rules *Rules, record *Record) *Engine {
+	if rules == nil {
+		rules = &Rules{}
+	}
+	return &Engine{rules: rules, record: record}
+}
+
+// Evaluate implements Policy.
+func (e *Engine) Evaluate(req *Request) Verdict {
+	// decisions are serialized, so that the daily limit accounts for the previous ones
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	allowed := func() *big.Int {
+		if e.record == nil {
+			return new(big.Int)
+		}
+		return e.record.allowedValue(req)
+	}
+	v := e.rules.evaluate(req, allowed)
+	if e.record != nil {
+		if err := e.record.Append(req, v); err != nil {
+			return Verdict{Decision: Deny, Rule: "record", Reason: fmt.Sprintf("failed to record the decision: %v", err)}
+		}
+	}
+	return v
+}
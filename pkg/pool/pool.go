@@ -169,7 +169,9 @@ func (p *Pool) Parallelize(count int, f func(int) interface{}) []interface{} {
 	results := make([]interface{}, count)
 
 	ctr := int64(count)
-	ctrChanged := make(chan struct{})
+	// Each command signals once, after decrementing the counter, so we may stop listening before the last signal.
+	// The buffer makes sure that a worker never blocks on a signal nobody reads.
+	ctrChanged := make(chan struct{}, count)
 	cmdI := 0
 	for cmdI < count {
 		cmd := command{
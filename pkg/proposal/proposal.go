@@ -0,0 +1,211 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package proposal defines the session proposals signed by the coordinator before each protocol execution.
+// Every other party checks a proposal against its own policy, and explicitly accepts or rejects it,
+// before it takes part in the execution.
+package proposal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/taproot"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// signatureTag separates the hash signed by the coordinator from the other BIP-340 signatures.
+const signatureTag = "MPC_ECDSA/SessionProposal"
+
+var (
+	// ErrExpired is returned when a proposal is checked after its expiry.
+	ErrExpired = errors.New("proposal: expired")
+	// ErrInvalidSignature is returned when a proposal is not signed by the coordinator.
+	ErrInvalidSignature = errors.New("proposal: invalid signature")
+)
+
+// SessionProposal describes a protocol execution proposed by the coordinator.
+type SessionProposal struct {
+	// Protocol is the stage to execute, e.g. "SignAfterPreSign3"
+	Protocol string
+	// Args are the other positional arguments of the stage, e.g. the ID of the lost party of KeyRepair
+	Args []string
+	// KeyID is the key used by the stage, it may be empty for the stages creating a key
+	KeyID string
+	// Signers is the signer set of the presign and sign stages
+	Signers party.IDSlice
+	// PresignID is the presignature created or used by the stage
+	PresignID string
+	// Digest is the digest to sign, nil if the stage does not sign
+	Digest *digest.Digest
+	// Message is the data hashed into Digest, so that every party can check what it signs
+	Message []byte
+	// SessionID is the random session ID of the execution
+	SessionID []byte
+	// Proposer is the ID of the coordinator
+	Proposer party.ID
+	// Expiry is the time in Unix seconds after which the proposal must be rejected
+	Expiry int64
+	// Signature is the BIP-340 signature of the proposal by the coordinator
+	Signature taproot.Signature
+}
+
+// Hash returns the tagged hash of the proposal, without its signature.
+func (p *SessionProposal) Hash() ([]byte, error) {
+	unsigned := *p
+	unsigned.Signature = nil
+	data, err := cbor.Marshal(&unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("proposal: %w", err)
+	}
+	return taproot.TaggedHash(signatureTag, data), nil
+}
+
+// Sign sets the signature of the proposal by the coordinator's secret key.
+func (p *SessionProposal) Sign(secret taproot.SecretKey) error {
+	h, err := p.Hash()
+	if err != nil {
+		return err
+	}
+	signature, err := secret.Sign(nil, h)
+	if err != nil {
+		return fmt.Errorf("proposal: %w", err)
+	}
+	p.Signature = signature
+	return nil
+}
+
+// Verify checks that the proposal is well formed, has not expired at now, and is signed by public.
+func (p *SessionProposal) Verify(public taproot.PublicKey, now time.Time) error {
+	if p.Protocol == "" {
+		return errors.New("proposal: missing protocol")
+	}
+	if len(p.SessionID) == 0 {
+		return errors.New("proposal: missing session ID")
+	}
+	if p.Digest != nil {
+		if err := p.Digest.Validate(); err != nil {
+			return err
+		}
+		if p.Message != nil {
+			d, err := digest.Compute(p.Digest.Scheme, p.Message)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(d.Hash, p.Digest.Hash) {
+				return errors.New("proposal: digest does not match the message")
+			}
+		}
+	}
+	if now.After(p.Deadline()) {
+		return ErrExpired
+	}
+	h, err := p.Hash()
+	if err != nil {
+		return err
+	}
+	if !public.Verify(p.Signature, h) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Deadline returns the expiry of the proposal.
+func (p *SessionProposal) Deadline() time.Time {
+	return time.Unix(p.Expiry, 0)
+}
+
+// Policy decides whether a party takes part in the execution described by a verified proposal,
+// it returns the reason of the rejection as an error.
+type Policy func(p *SessionProposal) error
+
+// All returns a policy accepting a proposal only if all the policies accept it.
+func All(policies ...Policy) Policy {
+	return func(p *SessionProposal) error {
+		for _, policy := range policies {
+			if err := policy(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// AllowProtocols returns a policy accepting only the given protocols, or all of them if none is given.
+func AllowProtocols(protocols ...string) Policy {
+	return func(p *SessionProposal) error {
+		if len(protocols) == 0 {
+			return nil
+		}
+		for _, protocol := range protocols {
+			if p.Protocol == protocol {
+				return nil
+			}
+		}
+		return fmt.Errorf("proposal: protocol %s is not allowed", p.Protocol)
+	}
+}
+
+// Response is the answer of a party to a proposal.
+type Response struct {
+	// SessionID is the session ID of the proposal
+	SessionID []byte
+	// Accept is true if the party takes part in the execution
+	Accept bool
+	// Reason explains a rejection
+	Reason string
+}
+
+// Decision is sent by the coordinator once all the parties answered a proposal.
+// The execution starts only if all of them accepted it.
+type Decision struct {
+	// SessionID is the session ID of the proposal
+	SessionID []byte
+	// Accept is true if all the parties accepted the proposal
+	Accept bool
+	// Rejections[j] is the reason given by Pⱼ for rejecting the proposal
+	Rejections map[party.ID]string
+}
+
+// Review verifies p and checks it against policy, returning the response of the party.
+func Review(p *SessionProposal, coordinator taproot.PublicKey, policy Policy, now time.Time) *Response {
+	response := &Response{SessionID: p.SessionID, Accept: true}
+	err := p.Verify(coordinator, now)
+	if err == nil && policy != nil {
+		err = policy(p)
+	}
+	if err != nil {
+		response.Accept = false
+		response.Reason = err.Error()
+	}
+	return response
+}
+
+// Decide combines the responses of the other parties to p into the decision of the coordinator,
+// a missing response or a response to another proposal counts as a rejection.
+func Decide(p *SessionProposal, responses map[party.ID]*Response, parties []party.ID) *Decision {
+	decision := &Decision{SessionID: p.SessionID, Accept: true, Rejections: map[party.ID]string{}}
+	for _, j := range parties {
+		response, ok := responses[j]
+		switch {
+		case !ok || response == nil:
+			decision.Rejections[j] = "no response"
+		case !bytes.Equal(response.SessionID, p.SessionID):
+			decision.Rejections[j] = "response to another proposal"
+		case !response.Accept:
+			decision.Rejections[j] = response.Reason
+		}
+	}
+	if len(decision.Rejections) > 0 {
+		decision.Accept = false
+	}
+	return decision
+}
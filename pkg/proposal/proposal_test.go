@@ -0,0 +1,97 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package proposal
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/taproot"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProposal(t *testing.T, secret taproot.SecretKey, now time.Time) *SessionProposal {
+	message := []byte("hello, world!")
+	d, err := digest.Compute(digest.SHA256, message)
+	require.NoError(t, err)
+	p := &SessionProposal{
+		Protocol:  "SignAfterPreSign3",
+		KeyID:     "wallet1",
+		Signers:   party.NewIDSlice([]party.ID{"a", "b", "c"}),
+		PresignID: "10086",
+		Digest:    &d,
+		Message:   message,
+		SessionID: []byte("session"),
+		Proposer:  "a",
+		Expiry:    now.Add(time.Minute).Unix(),
+	}
+	require.NoError(t, p.Sign(secret))
+	return p
+}
+
+func TestVerify(t *testing.T) {
+	secret, public, err := taproot.GenKey(rand.Reader)
+	require.NoError(t, err)
+	_, other, err := taproot.GenKey(rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+
+	p := newProposal(t, secret, now)
+	assert.NoError(t, p.Verify(public, now))
+
+	data, err := cbor.Marshal(p)
+	require.NoError(t, err)
+	received := &SessionProposal{}
+	require.NoError(t, cbor.Unmarshal(data, received))
+	assert.NoError(t, received.Verify(public, now), "the proposal should survive the transport")
+
+	assert.True(t, errors.Is(p.Verify(other, now), ErrInvalidSignature), "signed by another key")
+	assert.True(t, errors.Is(p.Verify(public, now.Add(2*time.Minute)), ErrExpired))
+
+	tampered := newProposal(t, secret, now)
+	tampered.Signers = append(tampered.Signers, "d")
+	assert.True(t, errors.Is(tampered.Verify(public, now), ErrInvalidSignature), "signer set changed")
+
+	tampered = newProposal(t, secret, now)
+	tampered.Message = []byte("goodbye, world!")
+	assert.Error(t, tampered.Verify(public, now), "message does not match the digest")
+}
+
+func TestReview(t *testing.T) {
+	secret, public, err := taproot.GenKey(rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+	p := newProposal(t, secret, now)
+
+	accepted := Review(p, public, AllowProtocols(), now)
+	assert.True(t, accepted.Accept)
+	assert.Equal(t, p.SessionID, accepted.SessionID)
+
+	rejected := Review(p, public, All(AllowProtocols("KeyGen", "Sign")), now)
+	assert.False(t, rejected.Accept)
+	assert.NotEmpty(t, rejected.Reason)
+
+	parties := []party.ID{"b", "c"}
+	decision := Decide(p, map[party.ID]*Response{"b": accepted, "c": accepted}, parties)
+	assert.True(t, decision.Accept)
+	assert.Empty(t, decision.Rejections)
+
+	decision = Decide(p, map[party.ID]*Response{"b": accepted, "c": rejected}, parties)
+	assert.False(t, decision.Accept)
+	assert.Equal(t, rejected.Reason, decision.Rejections["c"])
+
+	decision = Decide(p, map[party.ID]*Response{"b": accepted}, parties)
+	assert.False(t, decision.Accept)
+	assert.Contains(t, decision.Rejections, party.ID("c"))
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"fmt"
 
+	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/pkg/party"
 )
 
@@ -28,3 +29,24 @@ func (e Error) Error() string {
 func (e Error) Unwrap() error {
 	return e.Err
 }
+
+// RoundTimeoutError is returned when the messages of a round did not all arrive,
+// either because the round timeout passed or because the execution was stopped.
+type RoundTimeoutError struct {
+	// Round is the round whose messages were expected.
+	Round round.Number
+	// Missing are the parties whose messages for Round never arrived.
+	Missing []party.ID
+	// Err is the reason the handler stopped waiting, e.g. context.DeadlineExceeded.
+	Err error
+}
+
+// Error implement error.
+func (e *RoundTimeoutError) Error() string {
+	return fmt.Sprintf("round %d: no message from %v: %s", e.Round, e.Missing, e.Err)
+}
+
+// Unwrap implement errors.Wrapper.
+func (e *RoundTimeoutError) Unwrap() error {
+	return e.Err
+}
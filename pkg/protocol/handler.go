@@ -8,14 +8,15 @@ package protocol
 import (
 	"MPC_ECDSA/communication"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sync"
-
-	log "github.com/sirupsen/logrus"
+	"time"
 
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/logging"
 	"MPC_ECDSA/pkg/party"
 
 	"github.com/fxamacker/cbor/v2"
@@ -30,8 +31,9 @@ type StartFunc func(sessionID []byte) (round.Session, error)
 
 // Handler represents some kind of handler for a protocol.
 type Handler interface {
-	// Result should return the result of running the protocol, or an error
-	Result() (interface{}, error)
+	// Result should return the result of running the protocol, or an error.
+	// If ctx is done before the protocol completes, the execution is aborted.
+	Result(ctx context.Context) (interface{}, error)
 	// Listen returns a channel which will receive new messages
 	Listen() <-chan *Message
 	// Stop should abort the protocol execution.
@@ -48,23 +50,70 @@ type MultiHandler struct {
 	currentRound    round.Session
 	rounds          map[round.Number]round.Session
 	err             *Error
+	report          *AbortReport
 	result          interface{}
 	messages        map[round.Number]map[party.ID]*Message
 	broadcast       map[round.Number]map[party.ID]*Message
 	broadcastHashes map[round.Number][]byte
 	out             chan *Message
 	mtx             sync.Mutex
-	localConn       communication.LocalConn //通信
+	transport       communication.Transport //通信
+	session         *MuxSession
+	//the maximum time to wait for the messages of a round, no limit if zero
+	roundTimeout time.Duration
+	//logger is given to the rounds of the execution, logging.Default() is used if it is nil
+	logger logging.Logger
+	//ctx is cancelled to stop the execution, stopErr records why
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopErr error
+	//done is closed once the execution has either produced a result or aborted
+	done    chan struct{}
+	aborted bool
+	//relayedBy is the party whose abort ended the execution, if it was not aborted by this party
+	relayedBy party.ID
+}
+
+// abortNoticeTimeout bounds the time spent notifying the other parties of an abort.
+const abortNoticeTimeout = 10 * time.Second
+
+// HandlerOption configures a MultiHandler.
+type HandlerOption func(h *MultiHandler)
+
+// WithRoundTimeout sets the maximum time the handler waits for the messages of a single round.
+// When it passes, the execution aborts with a RoundTimeoutError naming the parties whose messages are missing.
+// A zero timeout means the handler waits until the context given to Result is done.
+func WithRoundTimeout(timeout time.Duration) HandlerOption {
+	return func(h *MultiHandler) {
+		h.roundTimeout = timeout
+	}
+}
+
+// WithLogger sets the logger of the execution, the protocol, SSID, round and party fields are added to its entries.
+// If it is not set, logging.Default() is used.
+func WithLogger(logger logging.Logger) HandlerOption {
+	return func(h *MultiHandler) {
+		h.logger = logger
+	}
 }
 
 // NewMultiHandler creates a handler for a protocol based on the provided StartFunc.
-// It takes a StartFunc, sessionID, and localConn parameters and returns a pointer to a MultiHandler and an error.
-func NewMultiHandler(create StartFunc, sessionID []byte, localConn communication.LocalConn) (*MultiHandler, error) {
+// It takes a StartFunc, sessionID, and the transport used to reach the other parties,
+// and returns a pointer to a MultiHandler and an error.
+// When transport is a *Mux, several handlers can run concurrently over it,
+// as long as each of them is given a different sessionID.
+// The protocol runs in the background, its outcome is obtained with Result.
+func NewMultiHandler(create StartFunc, sessionID []byte, transport communication.Transport, opts ...HandlerOption) (*MultiHandler, error) {
 	//Use the create function with the sessionID to create the initial round of the protocol.
 	r, err := create(sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("protocol: failed to create round: %w", err)
 	}
+	//If the transport is a Mux, only receive the messages of this execution
+	transport, session, err := openSession(transport, r)
+	if err != nil {
+		return nil, err
+	}
 	// Creates a MultiHandler object h
 	h := &MultiHandler{
 		currentRound: r,
@@ -78,51 +127,54 @@ func NewMultiHandler(create StartFunc, sessionID []byte, localConn communication
 		broadcastHashes: map[round.Number][]byte{},
 		//A channel of Message type with a buffer size of 2 times the total number of parties.
 		out:       make(chan *Message, 2*r.N()),
-		localConn: localConn,
+		transport: transport,
+		session:   session,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.logger != nil {
+		r.SetLogger(h.logger)
 	}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
 	//call the finalize  method to execute the current round of the protocol
-	h.finalize()
+	go h.run()
 	return h, nil
 }
 
-// NewMultiHandlerReshare creates a handler for a protocol based on the provided StartFunc.
-// It takes a StartFunc, sessionID, and localConn parameters and returns a pointer to a MultiHandler and an error.
-func NewMultiHandlerReshare(create StartFunc, sessionID []byte, localConn communication.LocalConn) (*MultiHandler, error) {
-	//Use the create function with the sessionID to create the initial round of the protocol.
-	r, err := create(sessionID)
-	if err != nil {
-		return nil, fmt.Errorf("protocol: failed to create round: %w", err)
-	}
-	// Creates a MultiHandler object h
-	h := &MultiHandler{
-		currentRound: r,
-		// A map that tracks all rounds of the protocol, with the initial round added.
-		rounds: map[round.Number]round.Session{r.Number(): r},
-		//A queue to store received messages, initialized with the IDs of other parties and the final round number.
-		messages: newQueue(r.OtherPartyIDs(), r.FinalRoundNumber()),
-		//A queue to store broadcast messages, initialized with the IDs of other parties and the final round number.
-		broadcast: newQueue(r.OtherPartyIDs(), r.FinalRoundNumber()),
-		//A map to store the hashes of broadcast messages, initially empty.
-		broadcastHashes: map[round.Number][]byte{},
-		//A channel of Message type with a buffer size of 2 times the total number of parties.
-		out:       make(chan *Message, 2*r.N()),
-		localConn: localConn,
-	}
-	//call the finalizeReshare  method to execute the current round of the protocol
-	h.finalizeReshare()
+// run executes the rounds of the protocol with finalize, and marks the handler as done when it returns.
+func (h *MultiHandler) run() {
+	defer close(h.done)
+	defer h.cancel()
+	h.finalize()
 
-	return h, nil
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	//the rounds stopped without reaching the output or an abort
+	if h.result == nil && h.err == nil {
+		h.abort(errors.New("protocol: execution stopped before the output round"))
+	}
 }
 
-// Result returns the protocol result if the protocol completed successfully.
-func (h *MultiHandler) Result() (interface{}, error) {
+// Result waits for the protocol to complete, and returns its result if it completed successfully.
+// Otherwise the error is an *AbortReport, which wraps an Error naming the culprits.
+// If ctx is done first, the execution is aborted and the returned error names the parties
+// whose messages for the current round have not arrived.
+func (h *MultiHandler) Result(ctx context.Context) (interface{}, error) {
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		h.stop(ctx.Err())
+		<-h.done
+	}
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
 	if h.result != nil {
 		return h.result, nil
 	}
 	if h.err != nil {
-		return nil, *h.err
+		return nil, h.report
 	}
 	return nil, errors.New("protocol: not finished")
 }
@@ -189,9 +241,11 @@ func (h *MultiHandler) Accept(msg *Message) {
 		return
 	}
 
-	// a msg with roundNumber 0 is considered an abort from another party
+	// a msg with roundNumber 0 is considered an abort from another party,
+	// which made its own report: it is not blamed, and its messages are not evidence
 	if msg.RoundNumber == 0 {
-		h.abort(fmt.Errorf("aborted by other party with error: \"%s\"", msg.Data), msg.From)
+		h.relayedBy = msg.From
+		h.abort(fmt.Errorf("aborted by other party with error: \"%s\"", msg.Data))
 		return
 	}
 
@@ -202,12 +256,12 @@ func (h *MultiHandler) Accept(msg *Message) {
 
 	if msg.Broadcast {
 		if err := h.verifyBroadcastMessage(msg); err != nil {
-			h.abort(err, msg.From)
+			h.abortIn(msg.RoundNumber, err, msg.From)
 			return
 		}
 	} else {
 		if err := h.verifyMessage(msg); err != nil {
-			h.abort(err, msg.From)
+			h.abortIn(msg.RoundNumber, err, msg.From)
 			return
 		}
 	}
@@ -281,7 +335,7 @@ func (h *MultiHandler) verifyMessage(msg *Message) error {
 // The finalize method is responsible for executing the current round of the protocol
 // and switching the execution state based on the result
 func (h *MultiHandler) finalize() {
-	log.Infoln("begin finalize")
+	round.Logger(h.currentRound).Debugln("begin finalize")
 	//Verify if all messages have been received
 	if !h.receivedAll() {
 		return
@@ -303,14 +357,9 @@ func (h *MultiHandler) finalize() {
 		h.abort(err, h.currentRound.SelfID())
 		return
 	}
-	// Declare and initialize auxiliary variables to calculate the number of messages to be received
-	numMsg := 0
-	numBroadcast := 0
-	numP2p := 0
-
+	logger := round.Logger(r)
 	// Send messages to other participants
 	// Iterate over messages in the 'out' channel, serialize them, and handle them based on their nature (broadcast or p2p)
-	// Calculate the number of messages to be received in the process
 	for roundMsg := range out {
 		//serialize the roundMsg.Content
 		data, err := cbor.Marshal(roundMsg.Content)
@@ -336,28 +385,25 @@ func (h *MultiHandler) finalize() {
 
 		//If the message is a broadcast message
 		if msg.Broadcast {
-			// Increment the numBroadcast counter,
-			numBroadcast += 1
-			log.Infof("broadcast message %+v", msg)
-			//broadcasts 'byteMsg' using the localConn.BroadcastSend method
-			err := h.localConn.BroadcastSend(byteMsg)
+			messageLogger(logger, msg).Debugln("broadcast message")
+			//broadcasts 'byteMsg' using the transport
+			err := h.transport.Broadcast(h.ctx, byteMsg)
 			if err != nil {
-				log.Errorf("fail broadcast message")
+				messageLogger(logger, msg).Errorf("fail broadcast message: %v", err)
 			}
 			// Store the broadcast message in the local handler
 			h.store(msg)
 		} else {
-			//If the message is a point-to-point message increment the numP2p counter
-			numP2p += 1
-			log.Infof("p2p send message to %v", roundMsg.To)
-			//send message(byteMsg) to the recipient(roundMsg.To) using the localConn.P2pSend method.
-			err := h.localConn.P2pSend(roundMsg.To, byteMsg)
+			//If the message is a point-to-point message
+			messageLogger(logger.WithField(logging.FieldPeer, roundMsg.To), msg).Debugln("p2p send message")
+			//send message(byteMsg) to the recipient(roundMsg.To) using the transport.
+			err := h.transport.Send(h.ctx, roundMsg.To, byteMsg)
 			if err != nil {
-				log.Errorf("fail p2p send to  %v", roundMsg.To)
+				messageLogger(logger.WithField(logging.FieldPeer, roundMsg.To), msg).Errorf("fail p2p send message: %v", err)
 			}
 		}
 	}
-	log.Infof("switch to new round %v", r.Number())
+	round.Logger(r).Infoln("switch to new round")
 	roundNumber := r.Number()
 	//check if the new round already exists in the h.rounds map
 	//If the round exists, it returns without further processing.
@@ -367,248 +413,215 @@ func (h *MultiHandler) finalize() {
 	h.rounds[roundNumber] = r
 	h.currentRound = r
 
-	//It creates a channel named done to mark the completion of message reception.
-	done := make(chan bool)
-	//calculate the number of otherParties
-	otherPartyNum := h.localConn.LocalConfig.TotalPartyCount - 1
-	//calculate the total number of messages to be received
-	//based on the number of broadcast and point-to-point messages.
-	numMsg = numBroadcast*otherPartyNum + numP2p
-	//calculate the number of rounds the local participant needs to receive messages,
-	//assuming each round receives otherPartyNum messages.
-	numReceiveRound := numMsg / otherPartyNum
-
-	//use a goroutine to asynchronously handle message reception.
-	go func(numReceiveRound int) {
-		log.Infof("numMsg is %v\n", numReceiveRound)
-		var msgMap map[party.ID][]byte
-		for i := 0; i < numReceiveRound; i++ {
-			// call the BroadcastReceive method to receive messages from other participants
-			msgMap, err = h.localConn.BroadcastReceive()
-			if err != nil {
-				log.Errorln("fail BroadcastReceive")
-				return
-			}
-			wg := sync.WaitGroup{}
-			wg.Add(len(msgMap))
-			//iterate over the received messages
-			for id, msgByte := range msgMap {
-				//process each message in parallel using goroutines.
-				go func(id party.ID, msgByte []byte) {
-					defer wg.Done()
-					tmpMsg := &Message{}
-					//unmarshal them into Message structs(tmpMsg)
-					err = cbor.Unmarshal(msgByte, tmpMsg)
-					if err != nil {
-						log.Errorf("fail unmarshal %v", err)
-					}
-					log.Infof("received from party %v, message is %+v", id, tmpMsg)
-					//call the Accept method of the handler to handle the message.
-					h.Accept(tmpMsg)
-				}(id, msgByte)
-			}
-			wg.Wait()
-
-		}
-		//After processing all received messages, it signals the completion of message reception by sending a value to the done channel.
-		done <- true
-	}(numReceiveRound)
-
-	//wait for the completion signal from the done channel using a select statement
-	select {
-	case <-done:
-		// either we get the current round, the next one, or one of the two final ones
-		switch R := r.(type) {
-		//If it is an Abort round, indicating an error occurred,
-		//it calls the abort method with the error and culprits (if any) and returns.
-		case *round.Abort:
-			h.abort(R.Err, R.Culprits...)
-			return
-			//it is an Output round, indicating the protocol has produced a result,
-			//it assigns the result to the h.result field
-			//and calls the abort method without an error to terminate the protocol execution.
-		case *round.Output:
-			h.result = R.Result
-			h.abort(nil)
-			return
-		default:
-		}
-		// Recursively call finalize to enter the next round
-		h.finalize()
+	//the new round declares which parties send it messages, and how many each of them sends
+	senders := r.ExpectedSenders()
+	perSender := expectedMessages(r)
+	round.Logger(r).Debugf("expect %v messages from each of %v", perSender, senders)
+	// receive the messages of the new round from each of the expected senders
+	if !h.receive(r, senders, perSender) {
+		return
 	}
-}
 
-// For new party,set a map, the key is roundNumber,
-// the value indicates whether the message comes from the new participant or the old participant,
-var numReceiveRoundMapNewParty = map[int]int{
-	1: 0,  // round1 does not receive messages
-	2: -1, // round2 receives messages from old party
-	3: 1,  // round3 receives messages from new party
-	4: -1, // round4 receives messages from old party
-	5: 1,  // round5 receives messages from new party
-	6: 1,  // round6 receives messages from new party
-	7: 1,  // round7 receives messages from new party
+	// either we get the current round, the next one, or one of the two final ones
+	switch R := r.(type) {
+	//If it is an Abort round, indicating an error occurred,
+	//it calls the abort method with the error and culprits (if any) and returns.
+	case *round.Abort:
+		h.abort(R.Err, R.Culprits...)
+		return
+		//it is an Output round, indicating the protocol has produced a result,
+		//it assigns the result to the h.result field
+		//and calls the abort method without an error to terminate the protocol execution.
+	case *round.Output:
+		h.result = R.Result
+		h.abort(nil)
+		return
+	default:
+	}
+	//stop if one of the received messages made the protocol abort
+	if h.err != nil {
+		return
+	}
+	// Recursively call finalize to enter the next round
+	h.finalize()
 }
 
-// The finalizeReshare method is responsible for executing the current round of the protocol
-// and switching the execution state based on the result
-func (h *MultiHandler) finalizeReshare() {
-	log.Infoln("begin finalizeReshare")
-	// Create a channel for communication in the current round and collect messages to be sent by the local participant
-	out := make(chan *round.Message, h.currentRound.N()+1)
-	// Call the Finalize method of the current round to calculate and send messages, and obtain the next round
-	r, err := h.currentRound.Finalize(out)
-	//Close the out channel to indicate that no more messages will be sent.
-	close(out)
-	//If there is an error during the finalization process or the next round is not obtained
-	if err != nil || r == nil {
-		//abort the protocol execution by calling the abort method with the error and the self ID of the current round.
-		//h.abort(err, h.currentRound.SelfID())
-		log.Errorln("fail to finalize Reshare")
-		return
+// receive reads perSender messages from each of the senders for the round r, and hands them to Accept.
+// The messages of different senders are read concurrently, the first failure stops all of them.
+// If a sender does not deliver its messages before the round timeout passes or the execution is stopped,
+// the protocol is aborted with a RoundTimeoutError naming the missing senders, and false is returned.
+// A sender whose message cannot be decoded is blamed for the abort.
+func (h *MultiHandler) receive(r round.Session, senders []party.ID, perSender int) bool {
+	number := r.Number()
+	roundCtx := h.ctx
+	if h.roundTimeout > 0 {
+		var cancelRound context.CancelFunc
+		roundCtx, cancelRound = context.WithTimeout(roundCtx, h.roundTimeout)
+		defer cancelRound()
 	}
-	// Send messages to other participants
-	// Iterate over messages in the 'out' channel, serialize them, and handle them
-	for roundMsg := range out {
-		//serialize the roundMsg.Content
-		data, err := cbor.Marshal(roundMsg.Content)
-		if err != nil {
-			panic(fmt.Errorf("failed to marshal round message: %w", err))
-		}
-		//creates a Message struct
-		msg := &Message{
-			SSID:        r.SSID(),
-			From:        r.SelfID(),
-			To:          roundMsg.To,
-			Protocol:    r.ProtocolID(),
-			RoundNumber: roundMsg.Content.RoundNumber(),
-			Data:        data,
-		}
-		//serialize the msg
-		byteMsg, err := cbor.Marshal(msg)
-		if err != nil {
-			panic(fmt.Errorf("failed to marshal handler message: %w", err))
-		}
-		//send message(byteMsg) to the recipient(roundMsg.To) using the localConn.P2pSend method.
-		err = h.localConn.P2pSend(roundMsg.To, byteMsg)
-		if err != nil {
-			log.Errorf("fail p2p send to  %v", roundMsg.To)
+	ctx, cancel := context.WithCancel(roundCtx)
+	defer cancel()
+
+	//the goroutines only log through this logger, the round itself is modified by Accept
+	h.mtx.Lock()
+	logger := round.Logger(r)
+	h.mtx.Unlock()
+
+	var mtx sync.Mutex
+	var missing []party.ID
+	var firstErr error
+	var firstID party.ID
+	var decodeErr error
+	fail := func(id party.ID, err error, decode bool) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		missing = append(missing, id)
+		if firstErr == nil {
+			firstErr, firstID = err, id
+			if decode {
+				decodeErr = err
+			}
+			cancel()
 		}
 	}
-	log.Infof("switch to new round %v", r.Number())
-	roundNumber := r.Number()
-	//check if the new round already exists in the h.rounds map
-	//If the round exists, it returns without further processing.
-	if _, ok := h.rounds[roundNumber]; ok {
-		return
-	}
-	h.rounds[roundNumber] = r
-	h.currentRound = r
-	done := make(chan bool)
-	//var numReceiveRound int
 	wg := sync.WaitGroup{}
-	//if party is new party	and the current round is not the output round
-	if h.localConn.LocalConfig.IsNewCommittee && r.Number() != 0 {
-		//judge if the current round is receiving messages from new party or old party
-		MessageFrom := numReceiveRoundMapNewParty[int(r.Number())]
-		go func() {
-			var msgMap map[party.ID][]byte
-			//if MessageFrom == -1, it means that the current round is receiving messages from old party
-			if MessageFrom == -1 {
-				//call the BroadcastReceiveFromOldparty method to receive messages from old party
-				msgMap, err = h.localConn.BroadcastReceiveFromOldparty()
-			} else { //if MessageFrom == 1, it means that the current round is receiving messages from new party
-				//call the BroadcastReceiveFromNewparty method to receive messages from new party
-				msgMap, err = h.localConn.BroadcastReceiveFromNewparty()
+	wg.Add(len(senders))
+	for _, id := range senders {
+		go func(id party.ID) {
+			defer wg.Done()
+			peerLogger := logger.WithField(logging.FieldPeer, id)
+			for i := 0; i < perSender; i++ {
+				msgByte, err := h.transport.Receive(ctx, id)
+				if err != nil {
+					fail(id, err, false)
+					return
+				}
+				tmpMsg := &Message{}
+				//unmarshal them into Message structs(tmpMsg)
+				if err = cbor.Unmarshal(msgByte, tmpMsg); err != nil {
+					peerLogger.WithFields(logging.Payload(msgByte)).Errorf("fail unmarshal message: %v", err)
+					fail(id, fmt.Errorf("round %d: failed to decode message from %v: %w", number, id, err), true)
+					return
+				}
+				messageLogger(peerLogger, tmpMsg).Debugln("received message")
+				//call the Accept method of the handler to handle the message.
+				h.Accept(tmpMsg)
 			}
-			if err != nil {
-				log.Errorln("fail BroadcastReceive")
-				return
-			}
-			wg.Add(len(msgMap))
-			//iterate over the received messages
-			for id, msgByte := range msgMap {
-				//process each message in parallel using goroutines.
-				go func(id party.ID, msgByte []byte) {
-					defer wg.Done()
-					tmpMsg := &Message{}
-					//unmarshal them into Message structs(tmpMsg)
-					err = cbor.Unmarshal(msgByte, tmpMsg)
-					if err != nil {
-						log.Errorf("fail unmarshal %v", err)
-					}
-					log.Infof("received from party %v, message is %+v", id, tmpMsg)
-					//call the Accept method of the handler to handle the message.
-					h.Accept(tmpMsg)
-				}(id, msgByte)
-			}
-			wg.Wait()
-			done <- true
-		}()
-	} else {
-		//if party is old party, old party does not need to receive messages.
-		go func() {
-			done <- true
-		}()
+		}(id)
 	}
-reshare:
-	for {
-		select {
-		case <-done:
-			// either we get the current round, the next one, or one of the two final ones
-			switch R := r.(type) {
-			//If it is an Abort round, indicating an error occurred,
-			//it calls the abort method with the error and culprits (if any) and returns.
-			case *round.Abort:
-				h.abort(R.Err, R.Culprits...)
-				break reshare
-				//it is an Output round, indicating the protocol has produced a result,
-				//it assigns the result to the h.result field
-				//and calls the abort method without an error to terminate the protocol execution.
-			case *round.Output:
-				println("output in handler")
-				h.result = R.Result
-				h.abort(nil)
-				break reshare
-			default:
-			}
-			// Recursively call finalize to enter the next round
-			h.finalizeReshare()
-			break reshare //break the for loop
-		}
+	wg.Wait()
+
+	if firstErr == nil {
+		return true
 	}
-	return
+	missing = party.NewIDSlice(missing)
+	switch {
+	case h.ctx.Err() != nil:
+		//the execution was stopped, by Stop or by the context given to Result
+		h.mtx.Lock()
+		cause := h.stopErr
+		h.mtx.Unlock()
+		if cause == nil {
+			cause = h.ctx.Err()
+		}
+		h.abort(&RoundTimeoutError{Round: number, Missing: missing, Err: cause})
+	case decodeErr != nil:
+		//the sender of a message which cannot be decoded is to blame
+		h.abort(decodeErr, firstID)
+	case roundCtx.Err() != nil:
+		//the round deadline passed, the missing senders are to blame
+		h.abort(&RoundTimeoutError{Round: number, Missing: missing, Err: roundCtx.Err()}, missing...)
+	default:
+		h.abort(fmt.Errorf("round %d: failed to receive from %v: %w", number, firstID, firstErr))
+	}
+	return false
 }
 
-// abort function is used to handle the case when an error occurs.
+// abort function is used to handle the case when an error occurs in the last round reached.
+// It only has an effect the first time it is called.
 func (h *MultiHandler) abort(err error, culprits ...party.ID) {
+	h.abortIn(h.lastRound(), err, culprits...)
+}
+
+// abortIn aborts the execution because of an error in the given round, and reports it.
+// It only has an effect the first time it is called.
+func (h *MultiHandler) abortIn(number round.Number, err error, culprits ...party.ID) {
+	if h.aborted {
+		return
+	}
+	h.aborted = true
 	if err != nil {
 		//create an Error object in the handler's err field, which includes the culprits and the err itself.
 		h.err = &Error{
 			Culprits: culprits,
 			Err:      err,
 		}
-		select {
-		//send an error message through the out channel.
-		case h.out <- &Message{
+		//the report keeps the messages of the culprits as evidence
+		h.report = h.newReport(number, *h.err)
+		notice := &Message{
 			SSID:     h.currentRound.SSID(),
 			From:     h.currentRound.SelfID(),
 			Protocol: h.currentRound.ProtocolID(),
 			Data:     []byte(h.err.Error()),
-		}:
+		}
+		select {
+		//send an error message through the out channel.
+		case h.out <- notice:
 		default:
 		}
-
+		//the other parties are notified of the abort, unless it was relayed from one of them
+		if h.transport != nil && h.relayedBy == "" {
+			go notifyAbort(h.transport, notice)
+		}
 	}
+	//stop receiving the messages of the execution
+	h.cancel()
 	//close the out channel to indicate that no more messages will be sent.
 	close(h.out)
+	//release the session of the Mux, if any
+	if h.session != nil {
+		h.session.Close()
+	}
+}
+
+// notifyAbort broadcasts the abort notice msg through transport, without waiting for more than abortNoticeTimeout.
+func notifyAbort(transport communication.Transport, msg *Message) {
+	data, err := cbor.Marshal(msg)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), abortNoticeTimeout)
+	defer cancel()
+	_ = transport.Broadcast(ctx, data)
+}
+
+// openSession registers the protocol execution of r if transport is a Mux,
+// and returns the transport the handler should use.
+func openSession(transport communication.Transport, r round.Session) (communication.Transport, *MuxSession, error) {
+	mux, ok := transport.(*Mux)
+	if !ok {
+		return transport, nil, nil
+	}
+	session, err := mux.Open(r.ProtocolID(), r.SSID(), r.OtherPartyIDs())
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, session, nil
 }
 
 // Stop cancels the current execution of the protocol, and alerts the other users.
 func (h *MultiHandler) Stop() {
-	if h.err != nil || h.result != nil {
-		h.abort(errors.New("aborted by user"), h.currentRound.SelfID())
+	h.stop(errors.New("aborted by user"))
+}
+
+// stop records the reason for stopping the execution and cancels it, the first reason is kept.
+func (h *MultiHandler) stop(err error) {
+	h.mtx.Lock()
+	if h.stopErr == nil {
+		h.stopErr = err
 	}
+	h.mtx.Unlock()
+	h.cancel()
 }
 
 // expectsNormalMessage checks if the given round is expected to have normal messages.
@@ -618,19 +631,38 @@ func expectsNormalMessage(r round.Session) bool {
 	return r.MessageContent() != nil
 }
 
+// expectedMessages returns the number of messages the round r expects from each of its expected senders:
+// a broadcast message if it is a broadcast round, and a normal message if it expects normal messages.
+// The output and abort rounds expect none.
+func expectedMessages(r round.Session) int {
+	n := 0
+	if b, ok := r.(round.BroadcastRound); ok && b.BroadcastContent() != nil {
+		n++
+	}
+	if expectsNormalMessage(r) {
+		n++
+	}
+	return n
+}
+
+// broadcasters returns the parties whose broadcast messages a broadcast round r needs: its expected senders and itself.
+func broadcasters(r round.Session) party.IDSlice {
+	return party.NewIDSlice(append([]party.ID{r.SelfID()}, r.ExpectedSenders()...))
+}
+
 // receivedAll to verify if all messages have been received
 func (h *MultiHandler) receivedAll() bool {
 	r := h.currentRound
 	number := r.Number()
 	// Check if all broadcast messages from each party have been received
 	if _, ok := r.(round.BroadcastRound); ok {
-		log.Infof("round is BroadcastRound" + r.ProtocolID() + string(r.Number()))
+		round.Logger(r).Debugln("round is BroadcastRound")
 		//If h.broadcast[number] is nil, it means that no broadcast messages have been received in the current round
 		if h.broadcast[number] == nil {
 			return false
 		}
-		//Iterate over each party id in the current round
-		for _, id := range r.PartyIDs() {
+		//Iterate over each party id broadcasting in the current round
+		for _, id := range broadcasters(r) {
 			msg := h.broadcast[number][id]
 			//If msg is nil, it means that a broadcast message from the corresponding party ID has not been received yet
 			if msg == nil {
@@ -643,7 +675,7 @@ func (h *MultiHandler) receivedAll() bool {
 		if h.broadcastHashes[number] == nil {
 			//Create a hash state
 			hashState := r.Hash()
-			for _, id := range r.PartyIDs() {
+			for _, id := range broadcasters(r) {
 				//retrieve the corresponding broadcast message for each party id
 				msg := h.broadcast[number][id]
 				// write the hash.BytesWithDomain struct into the hash state
@@ -663,7 +695,7 @@ func (h *MultiHandler) receivedAll() bool {
 		if h.messages[number] == nil {
 			return false
 		}
-		for _, id := range r.OtherPartyIDs() {
+		for _, id := range r.ExpectedSenders() {
 			// h.messages[number][id] == nil means the message from that party has not been received yet, and the code returns false
 			if h.messages[number][id] == nil {
 				return false
@@ -791,6 +823,11 @@ func newQueue(senders []party.ID, rounds round.Number) map[round.Number]map[part
 	return q
 }
 
+// messageLogger returns logger with the fields of msg, the payload of msg is only described by its size and hash.
+func messageLogger(logger logging.Logger, msg *Message) logging.Logger {
+	return logger.WithFields(logging.Payload(msg.Data)).WithField(logging.FieldRound, msg.RoundNumber)
+}
+
 // The String method is defined on the MultiHandler struct and returns a string
 func (h *MultiHandler) String() string {
 	return fmt.Sprintf("party: %s, protocol: %s", h.currentRound.SelfID(), h.currentRound.ProtocolID())
@@ -0,0 +1,469 @@
+package protocol_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	mrand "math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/logging"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/config"
+	"MPC_ECDSA/protocols/resharing"
+	"MPC_ECDSA/protocols/sign"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMultiHandlerMemoryTransport runs the sign protocol through MultiHandler over an in-memory network.
+func TestMultiHandlerMemoryTransport(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	publicPoint := configs[partyIDs[0]].PublicPoint()
+	messageHash := []byte("hello")
+
+	network := communication.NewMemoryNetwork(partyIDs)
+
+	var mtx sync.Mutex
+	results := make(map[party.ID]interface{}, N)
+	wg := sync.WaitGroup{}
+	wg.Add(N)
+	for _, id := range partyIDs {
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, messageHash, pl), nil, network.Transport(id))
+			require.NoError(t, err)
+			result, err := h.Result(context.Background())
+			require.NoError(t, err)
+			mtx.Lock()
+			results[id] = result
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	require.Len(t, results, N)
+	for _, result := range results {
+		require.IsType(t, &ecdsa.Signature{}, result)
+		signature := result.(*ecdsa.Signature)
+		assert.True(t, signature.Verify(publicPoint, messageHash), "expected valid signature")
+	}
+}
+
+// TestMultiHandlerRoundTimeout checks that the parties abort and name the party which never sends its messages.
+func TestMultiHandlerRoundTimeout(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	messageHash := []byte("hello")
+	silent := partyIDs[N-1]
+
+	network := communication.NewMemoryNetwork(partyIDs)
+
+	wg := sync.WaitGroup{}
+	for _, id := range partyIDs.Remove(silent) {
+		wg.Add(1)
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, messageHash, pl), nil, network.Transport(id),
+				protocol.WithRoundTimeout(time.Second))
+			require.NoError(t, err)
+			_, err = h.Result(context.Background())
+			var timeoutErr *protocol.RoundTimeoutError
+			require.True(t, errors.As(err, &timeoutErr), "expected a timeout, got %v", err)
+			assert.Equal(t, []party.ID{silent}, timeoutErr.Missing)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			var protocolErr protocol.Error
+			require.True(t, errors.As(err, &protocolErr))
+			assert.Equal(t, []party.ID{silent}, protocolErr.Culprits)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// TestMultiHandlerResultContext checks that the execution is aborted when the context given to Result is done.
+func TestMultiHandlerResultContext(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 2
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	id := partyIDs[0]
+
+	network := communication.NewMemoryNetwork(partyIDs)
+	h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, []byte("hello"), pl), nil, network.Transport(id))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = h.Result(ctx)
+	var timeoutErr *protocol.RoundTimeoutError
+	require.True(t, errors.As(err, &timeoutErr), "expected a timeout, got %v", err)
+	assert.Equal(t, []party.ID{partyIDs[1]}, timeoutErr.Missing)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// failingTransport returns data, or err if data is nil, as the first message received from the party from.
+type failingTransport struct {
+	communication.Transport
+	from party.ID
+	data []byte
+	err  error
+}
+
+func (t *failingTransport) Receive(ctx context.Context, from party.ID) ([]byte, error) {
+	if from != t.from {
+		return t.Transport.Receive(ctx, from)
+	}
+	if t.data == nil {
+		return nil, t.err
+	}
+	data := t.data
+	t.data, t.err = nil, nil
+	return data, nil
+}
+
+// TestMultiHandlerReceiveFailure checks that a failure to receive from one party stops the round
+// without waiting for the other parties, and that a party sending an undecodable message is blamed.
+func TestMultiHandlerReceiveFailure(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	id, faulty := partyIDs[0], partyIDs[1]
+	connErr := errors.New("connection reset")
+
+	for name, transport := range map[string]*failingTransport{
+		"transport error": {from: faulty, err: connErr},
+		"garbage":         {from: faulty, data: []byte{0xff}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// the other parties never run, only the faulty one makes the round stop
+			transport.Transport = communication.NewMemoryNetwork(partyIDs).Transport(id)
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, []byte("hello"), pl), nil, transport)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			_, err = h.Result(ctx)
+			var timeoutErr *protocol.RoundTimeoutError
+			require.False(t, errors.As(err, &timeoutErr), "expected the round to stop on the failure, got %v", err)
+			var protocolErr protocol.Error
+			require.True(t, errors.As(err, &protocolErr))
+			if name == "transport error" {
+				assert.ErrorIs(t, err, connErr)
+				assert.Empty(t, protocolErr.Culprits)
+			} else {
+				assert.Equal(t, []party.ID{faulty}, protocolErr.Culprits)
+			}
+		})
+	}
+}
+
+// TestMultiHandlerLogger checks that the entries of an injected logger carry the fields of the execution,
+// and that neither the messages nor the secret shares reach the output.
+func TestMultiHandlerLogger(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 2
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	network := communication.NewMemoryNetwork(partyIDs)
+	wg := sync.WaitGroup{}
+	wg.Add(N)
+	for _, id := range partyIDs {
+		go func(id party.ID) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, []byte("hello"), pl), nil, network.Transport(id),
+				protocol.WithLogger(logger))
+			require.NoError(t, err)
+			_, err = h.Result(context.Background())
+			require.NoError(t, err)
+		}(id)
+	}
+	wg.Wait()
+
+	var secrets []string
+	for _, c := range configs {
+		share, err := c.ECDSA.MarshalBinary()
+		require.NoError(t, err)
+		secrets = append(secrets, hex.EncodeToString(share), c.Paillier.P().String())
+	}
+	entries := hook.AllEntries()
+	require.NotEmpty(t, entries)
+	hashed := 0
+	for _, entry := range entries {
+		for _, field := range []string{logging.FieldProtocol, logging.FieldSSID, logging.FieldRound, logging.FieldParty} {
+			assert.Contains(t, entry.Data, field, entry.Message)
+		}
+		if _, ok := entry.Data[logging.FieldHash]; ok {
+			hashed++
+		}
+		line, err := entry.String()
+		require.NoError(t, err)
+		for _, secret := range secrets {
+			assert.NotContains(t, line, secret)
+		}
+	}
+	assert.NotZero(t, hashed, "the messages are logged with their hash")
+}
+
+// forgingTransport sends the proof of round 2 addressed to the first recipient to every recipient,
+// the proof is only valid for the first of them.
+type forgingTransport struct {
+	communication.Transport
+	data []byte
+}
+
+func (t *forgingTransport) Send(ctx context.Context, to party.ID, data []byte) error {
+	msg := &protocol.Message{}
+	if err := cbor.Unmarshal(data, msg); err == nil && msg.RoundNumber == 2 && !msg.Broadcast {
+		if t.data == nil {
+			t.data = msg.Data
+		} else {
+			msg.Data = t.data
+			if data, err = cbor.Marshal(msg); err != nil {
+				return err
+			}
+		}
+	}
+	return t.Transport.Send(ctx, to, data)
+}
+
+// TestMultiHandlerAbortReport checks that the party receiving a forged proof reports the culprit with the failed check,
+// and that the report keeps the messages of the culprit once saved.
+func TestMultiHandlerAbortReport(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	messageHash := []byte("hello")
+	culprit := partyIDs[N-1]
+
+	network := communication.NewMemoryNetwork(partyIDs)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	reports := make(chan *protocol.AbortReport, N)
+	wg := sync.WaitGroup{}
+	wg.Add(N)
+	for _, id := range partyIDs {
+		go func(id party.ID) {
+			defer wg.Done()
+			var transport communication.Transport = network.Transport(id)
+			if id == culprit {
+				transport = &forgingTransport{Transport: transport}
+			}
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, messageHash, pl), nil, transport)
+			require.NoError(t, err)
+			_, err = h.Result(ctx)
+			var report *protocol.AbortReport
+			require.True(t, errors.As(err, &report), "expected an abort report, got %v", err)
+			// the other parties are stopped once the forged proof is detected
+			if report.Check != "" {
+				reports <- report
+				cancel()
+			}
+		}(id)
+	}
+	wg.Wait()
+	close(reports)
+	require.Len(t, reports, 1)
+	report := <-reports
+
+	assert.Equal(t, "enc", report.Check)
+	assert.Equal(t, round.Number(2), report.Round)
+	assert.Equal(t, []party.ID{culprit}, report.Culprits)
+	assert.NotEqual(t, culprit, report.SelfID)
+	var protocolErr protocol.Error
+	require.True(t, errors.As(report, &protocolErr))
+	assert.Equal(t, []party.ID{culprit}, protocolErr.Culprits)
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	saved := &protocol.AbortReport{}
+	require.NoError(t, json.Unmarshal(data, saved))
+	assert.Equal(t, report.Error(), saved.Error())
+	msgs, err := saved.Messages()
+	require.NoError(t, err)
+	require.NotEmpty(t, msgs)
+	for _, msg := range msgs {
+		assert.Equal(t, culprit, msg.From)
+		assert.Equal(t, report.SSID, msg.SSID)
+	}
+}
+
+// TestMultiHandlerRelayedAbort checks that the parties which only learn of the abort from the party detecting
+// the forged proof record who reported it, without blaming it or keeping its messages as evidence.
+func TestMultiHandlerRelayedAbort(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	messageHash := []byte("hello")
+	culprit := partyIDs[N-1]
+
+	network := communication.NewMemoryNetwork(partyIDs)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var mtx sync.Mutex
+	reports := make(map[party.ID]*protocol.AbortReport, N)
+	wg := sync.WaitGroup{}
+	wg.Add(N)
+	for _, id := range partyIDs {
+		go func(id party.ID) {
+			defer wg.Done()
+			var transport communication.Transport = network.Transport(id)
+			if id == culprit {
+				transport = &forgingTransport{Transport: transport}
+			}
+			h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, messageHash, pl), nil, transport)
+			require.NoError(t, err)
+			_, err = h.Result(ctx)
+			var report *protocol.AbortReport
+			require.True(t, errors.As(err, &report), "expected an abort report, got %v", err)
+			mtx.Lock()
+			reports[id] = report
+			mtx.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	require.Len(t, reports, N)
+
+	var detector *protocol.AbortReport
+	for _, report := range reports {
+		if report.Check != "" {
+			require.Nil(t, detector, "a single party receives the forged proof")
+			detector = report
+		}
+	}
+	require.NotNil(t, detector)
+	assert.Equal(t, []party.ID{culprit}, detector.Culprits)
+	assert.Empty(t, detector.ReportedBy)
+
+	for id, report := range reports {
+		if report == detector {
+			continue
+		}
+		assert.Empty(t, report.Culprits, id)
+		assert.Empty(t, report.Evidence, id)
+		assert.NotEmpty(t, report.ReportedBy, id)
+		assert.NotEqual(t, id, report.ReportedBy)
+		assert.Contains(t, report.Reason, detector.Reason, id)
+		var protocolErr protocol.Error
+		require.True(t, errors.As(report, &protocolErr))
+		assert.Empty(t, protocolErr.Culprits, id)
+	}
+}
+
+// runHandlers runs the protocol started by starts[id] for each party id through MultiHandlers over an in-memory network,
+// and returns the results of all the parties.
+func runHandlers(t *testing.T, partyIDs party.IDSlice, starts map[party.ID]protocol.StartFunc) map[party.ID]interface{} {
+	network := communication.NewMemoryNetwork(partyIDs)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var mtx sync.Mutex
+	results := make(map[party.ID]interface{}, len(starts))
+	wg := sync.WaitGroup{}
+	for id, start := range starts {
+		wg.Add(1)
+		go func(id party.ID, start protocol.StartFunc) {
+			defer wg.Done()
+			h, err := protocol.NewMultiHandler(start, nil, network.Transport(id))
+			require.NoError(t, err)
+			result, err := h.Result(ctx)
+			require.NoError(t, err, id)
+			mtx.Lock()
+			results[id] = result
+			mtx.Unlock()
+		}(id, start)
+	}
+	wg.Wait()
+	return results
+}
+
+// TestMultiHandlerResharing runs the resharing through the same engine as the other protocols,
+// where the old parties only send, and the new parties receive from either committee depending on the round.
+func TestMultiHandlerResharing(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	configs, oldPartyIDs := test.GenerateConfig(group, 3, 1, mrand.New(mrand.NewSource(1)), pl)
+	publicPoint := configs[oldPartyIDs[0]].PublicPoint()
+	newPartyIDs := test.ResharingPartyIDs(3)
+	partyIDs := party.NewIDSlice(append(append([]party.ID{}, oldPartyIDs...), newPartyIDs...))
+
+	starts := make(map[party.ID]protocol.StartFunc, len(partyIDs))
+	for _, id := range partyIDs {
+		info := round.Info{
+			ProtocolID:       "cmp/resharing-test",
+			FinalRoundNumber: resharing.Rounds,
+			SelfID:           id,
+			PartyIDs:         partyIDs,
+			Threshold:        1,
+			Group:            group,
+			NewPartyIDs:      newPartyIDs,
+			OldPartyIDs:      oldPartyIDs,
+			NewThreshold:     1,
+			IsNewCommittee:   newPartyIDs.Contains(id),
+			IsOldCommittee:   oldPartyIDs.Contains(id),
+			OldOK:            make(map[party.ID]bool, len(oldPartyIDs)),
+			NewOK:            make(map[party.ID]bool, len(newPartyIDs)),
+		}
+		if c, ok := configs[id]; ok {
+			info.KeyGenConfig = c
+		}
+		starts[id] = resharing.Start(info, pl)
+	}
+
+	results := runHandlers(t, partyIDs, starts)
+	for _, id := range newPartyIDs {
+		require.IsType(t, &config.Config{}, results[id])
+		c := results[id].(*config.Config)
+		assert.True(t, publicPoint.Equal(c.PublicPoint()), "the public key should not change", id)
+		assert.Len(t, c.Public, len(newPartyIDs))
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+package protocol
+
+import (
+	"MPC_ECDSA/communication"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"MPC_ECDSA/pkg/logging"
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	// DefaultMaxPending is the default number of messages a Mux keeps for each peer, for sessions which have not been opened yet.
+	DefaultMaxPending = 1024
+	// DefaultPendingTTL is the default duration after which a message for a session which was never opened is dropped.
+	DefaultPendingTTL = time.Minute
+)
+
+var (
+	// ErrMuxClosed is returned by the sessions of a Mux after it has been closed.
+	ErrMuxClosed = errors.New("protocol: mux closed")
+	// ErrSessionClosed is returned by a MuxSession after it has been closed.
+	ErrSessionClosed = errors.New("protocol: session closed")
+)
+
+// Mux demultiplexes the messages arriving on a single communication.Transport,
+// so that several protocol executions can run concurrently between the same parties.
+// A Mux can be given to NewMultiHandler directly, the handler then opens its own session.
+//
+// Every incoming Message is routed to the session with the same Protocol and SSID.
+// Messages for a session which has not been opened yet are kept in a buffer bounded for each peer,
+// and are delivered once the session is opened, unless they have expired in the meantime.
+// A peer sending messages for sessions which are never opened only pushes out its own messages.
+type Mux struct {
+	transport communication.Transport
+	peers     party.IDSlice
+	//the maximum number of messages of a peer kept for sessions which have not been opened
+	maxPending int
+	//the duration after which a pending message is dropped
+	pendingTTL time.Duration
+
+	mtx      sync.Mutex
+	sessions map[muxKey]*MuxSession
+	//pending messages of each peer, ordered by arrival time
+	pending map[party.ID][]*pendingMessage
+	//the error which stopped the reader of a peer
+	peerErr map[party.ID]error
+	closed  bool
+	cancel  context.CancelFunc
+}
+
+// muxKey identifies a protocol execution.
+type muxKey struct {
+	protocol string
+	ssid     string
+}
+
+// pendingMessage is a message received before its session was opened.
+type pendingMessage struct {
+	key      muxKey
+	from     party.ID
+	data     []byte
+	received time.Time
+}
+
+// MuxSession is the communication.Transport of a single protocol execution running over a Mux.
+type MuxSession struct {
+	mux     *Mux
+	key     muxKey
+	parties party.IDSlice
+	queues  map[party.ID]*muxQueue
+}
+
+// muxQueue is an unbounded FIFO of the raw messages of one peer for one session.
+type muxQueue struct {
+	mtx    sync.Mutex
+	items  [][]byte
+	err    error
+	notify chan struct{}
+}
+
+// NewMux starts reading from each of the given peers over transport, and returns the Mux routing the messages.
+// maxPending bounds the number of messages of each peer received before their session is opened,
+// and pendingTTL the time they are kept,
+// if they are not positive DefaultMaxPending and DefaultPendingTTL are used.
+func NewMux(transport communication.Transport, peers []party.ID, maxPending int, pendingTTL time.Duration) *Mux {
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPending
+	}
+	if pendingTTL <= 0 {
+		pendingTTL = DefaultPendingTTL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mux{
+		transport:  transport,
+		peers:      party.NewIDSlice(peers),
+		maxPending: maxPending,
+		pendingTTL: pendingTTL,
+		sessions:   make(map[muxKey]*MuxSession),
+		pending:    make(map[party.ID][]*pendingMessage),
+		peerErr:    make(map[party.ID]error),
+		cancel:     cancel,
+	}
+	//start one reader per peer
+	for _, id := range m.peers {
+		go m.read(ctx, id)
+	}
+	return m
+}
+
+// Open registers the protocol execution identified by protocolID and ssid, and returns its transport.
+// parties are the other parties of the execution, they are the recipients of a broadcast.
+// Messages which arrived for this execution before it was opened are delivered first.
+func (m *Mux) Open(protocolID string, ssid []byte, parties []party.ID) (*MuxSession, error) {
+	key := muxKey{protocol: protocolID, ssid: string(ssid)}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.closed {
+		return nil, ErrMuxClosed
+	}
+	if _, ok := m.sessions[key]; ok {
+		return nil, fmt.Errorf("protocol: session %s %x is already open", protocolID, ssid)
+	}
+
+	s := &MuxSession{
+		mux:     m,
+		key:     key,
+		parties: party.NewIDSlice(parties),
+		queues:  make(map[party.ID]*muxQueue, len(m.peers)),
+	}
+	for _, id := range m.peers {
+		q := &muxQueue{notify: make(chan struct{}, 1)}
+		//a peer whose reader already stopped will never send anything to this session
+		if err := m.peerErr[id]; err != nil {
+			q.fail(err)
+		}
+		s.queues[id] = q
+	}
+
+	//move the pending messages of this session into its queues
+	m.expire(time.Now())
+	for from, pending := range m.pending {
+		remaining := pending[:0]
+		for _, p := range pending {
+			if p.key == key {
+				s.queues[from].push(p.data)
+				continue
+			}
+			remaining = append(remaining, p)
+		}
+		m.pending[from] = remaining
+	}
+
+	m.sessions[key] = s
+	return s, nil
+}
+
+// Close stops the readers and fails all open sessions with ErrMuxClosed.
+func (m *Mux) Close() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	m.cancel()
+	for _, s := range m.sessions {
+		for _, q := range s.queues {
+			q.fail(ErrMuxClosed)
+		}
+	}
+	m.sessions = map[muxKey]*MuxSession{}
+	m.pending = map[party.ID][]*pendingMessage{}
+}
+
+// Pending returns the number of messages waiting for their session to be opened.
+func (m *Mux) Pending() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.expire(time.Now())
+	n := 0
+	for _, pending := range m.pending {
+		n += len(pending)
+	}
+	return n
+}
+
+// read receives the messages of a single peer until the transport fails or the mux is closed.
+func (m *Mux) read(ctx context.Context, from party.ID) {
+	for {
+		data, err := m.transport.Receive(ctx, from)
+		if err != nil {
+			if ctx.Err() == nil {
+				logging.Default().WithField(logging.FieldPeer, from).Errorf("mux: fail to receive: %v", err)
+			}
+			m.fail(from, err)
+			return
+		}
+		msg := &Message{}
+		if err = cbor.Unmarshal(data, msg); err != nil {
+			logging.Default().WithField(logging.FieldPeer, from).WithFields(logging.Payload(data)).Errorf("mux: fail unmarshal message: %v", err)
+			continue
+		}
+		//a party can only send messages on its own behalf
+		if msg.From != from {
+			muxLogger(msg.Protocol, msg.SSID, from, data).Errorf("mux: message claims to be sent by %v, dropped", msg.From)
+			continue
+		}
+		m.route(muxKey{protocol: msg.Protocol, ssid: string(msg.SSID)}, from, data)
+	}
+}
+
+// route delivers data to its session, or buffers it until the session is opened.
+func (m *Mux) route(key muxKey, from party.ID, data []byte) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if s, ok := m.sessions[key]; ok {
+		s.queues[from].push(data)
+		return
+	}
+
+	now := time.Now()
+	m.expire(now)
+	//the buffer of the sender is full, drop its oldest message
+	pending := m.pending[from]
+	if len(pending) >= m.maxPending {
+		dropped := pending[0]
+		muxLogger(dropped.key.protocol, []byte(dropped.key.ssid), dropped.from, dropped.data).Warnln("mux: pending buffer of the peer full, drop message")
+		pending = pending[1:]
+	}
+	m.pending[from] = append(pending, &pendingMessage{
+		key:      key,
+		from:     from,
+		data:     data,
+		received: now,
+	})
+}
+
+// muxLogger returns the default logger with the fields of a message of the execution identified by protocolID and ssid.
+// The Mux has no logger of its own, it is replaced with logging.SetDefault.
+func muxLogger(protocolID string, ssid []byte, from party.ID, data []byte) logging.Logger {
+	return logging.Default().WithFields(logging.Fields{
+		logging.FieldProtocol: protocolID,
+		logging.FieldSSID:     logging.Short(ssid),
+		logging.FieldPeer:     from,
+	}).WithFields(logging.Payload(data))
+}
+
+// fail records the error which stopped the reader of a peer, and fails the sessions waiting on it.
+func (m *Mux) fail(from party.ID, err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.peerErr[from] = err
+	for _, s := range m.sessions {
+		s.queues[from].fail(err)
+	}
+}
+
+// expire drops the pending messages older than the TTL, m.mtx must be held.
+func (m *Mux) expire(now time.Time) {
+	for from, pending := range m.pending {
+		i := 0
+		for i < len(pending) && now.Sub(pending[i].received) > m.pendingTTL {
+			dropped := pending[i]
+			muxLogger(dropped.key.protocol, []byte(dropped.key.ssid), dropped.from, dropped.data).Warnln("mux: drop expired message")
+			i++
+		}
+		m.pending[from] = pending[i:]
+	}
+}
+
+// Send implements communication.Transport.
+func (m *Mux) Send(ctx context.Context, to party.ID, data []byte) error {
+	return m.transport.Send(ctx, to, data)
+}
+
+// Broadcast implements communication.Transport.
+func (m *Mux) Broadcast(ctx context.Context, data []byte) error {
+	return m.transport.Broadcast(ctx, data)
+}
+
+// Receive implements communication.Transport.
+// Messages are only delivered to the sessions, so it always fails, the handlers receive through a MuxSession.
+func (m *Mux) Receive(context.Context, party.ID) ([]byte, error) {
+	return nil, errors.New("protocol: receive from a mux requires an open session")
+}
+
+// Send implements communication.Transport.
+func (s *MuxSession) Send(ctx context.Context, to party.ID, data []byte) error {
+	return s.mux.transport.Send(ctx, to, data)
+}
+
+// Broadcast implements communication.Transport, the message is sent to the parties of the session only.
+func (s *MuxSession) Broadcast(ctx context.Context, data []byte) error {
+	for _, id := range s.parties {
+		if err := s.mux.transport.Send(ctx, id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive implements communication.Transport.
+func (s *MuxSession) Receive(ctx context.Context, from party.ID) ([]byte, error) {
+	q, ok := s.queues[from]
+	if !ok {
+		return nil, fmt.Errorf("protocol: party %v is not a peer of the mux", from)
+	}
+	return q.pop(ctx)
+}
+
+// Close removes the session from its Mux, later messages for it are buffered as pending again.
+func (s *MuxSession) Close() {
+	m := s.mux
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.sessions[s.key] == s {
+		delete(m.sessions, s.key)
+	}
+	for _, q := range s.queues {
+		q.fail(ErrSessionClosed)
+	}
+}
+
+// push appends data to the queue and wakes up a pending pop.
+func (q *muxQueue) push(data []byte) {
+	q.mtx.Lock()
+	q.items = append(q.items, data)
+	q.mtx.Unlock()
+	q.wake()
+}
+
+// fail makes pop return err once the queue is empty.
+func (q *muxQueue) fail(err error) {
+	q.mtx.Lock()
+	if q.err == nil {
+		q.err = err
+	}
+	q.mtx.Unlock()
+	q.wake()
+}
+
+func (q *muxQueue) wake() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop blocks until a message is available, the queue failed, or ctx is done.
+func (q *muxQueue) pop(ctx context.Context) ([]byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		q.mtx.Lock()
+		if len(q.items) > 0 {
+			data := q.items[0]
+			q.items = q.items[1:]
+			q.mtx.Unlock()
+			return data, nil
+		}
+		err := q.err
+		q.mtx.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-q.notify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
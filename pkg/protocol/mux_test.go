@@ -0,0 +1,206 @@
+package protocol_test
+
+import (
+	"context"
+	"fmt"
+	mrand "math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"MPC_ECDSA/communication"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/sign"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMuxConcurrentSessions runs two sign executions at the same time over the same network.
+func TestMuxConcurrentSessions(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	N := 3
+	T := N - 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	publicPoint := configs[partyIDs[0]].PublicPoint()
+
+	network := communication.NewMemoryNetwork(partyIDs)
+	muxes := make(map[party.ID]*protocol.Mux, N)
+	for _, id := range partyIDs {
+		muxes[id] = protocol.NewMux(network.Transport(id), partyIDs.Remove(id), protocol.DefaultMaxPending, protocol.DefaultPendingTTL)
+		defer muxes[id].Close()
+	}
+
+	sessions := map[string][]byte{
+		"session 1": []byte("first message"),
+		"session 2": []byte("second message"),
+	}
+
+	var mtx sync.Mutex
+	results := make(map[string][]interface{}, len(sessions))
+	wg := sync.WaitGroup{}
+	for sessionID, message := range sessions {
+		for _, id := range partyIDs {
+			wg.Add(1)
+			go func(sessionID string, message []byte, id party.ID) {
+				defer wg.Done()
+				h, err := protocol.NewMultiHandler(sign.StartSign(configs[id], partyIDs, message, pl), []byte(sessionID), muxes[id])
+				require.NoError(t, err)
+				result, err := h.Result(context.Background())
+				require.NoError(t, err)
+				mtx.Lock()
+				results[sessionID] = append(results[sessionID], result)
+				mtx.Unlock()
+			}(sessionID, message, id)
+		}
+	}
+	wg.Wait()
+
+	for sessionID, message := range sessions {
+		require.Len(t, results[sessionID], N)
+		for _, result := range results[sessionID] {
+			require.IsType(t, &ecdsa.Signature{}, result)
+			assert.True(t, result.(*ecdsa.Signature).Verify(publicPoint, message), "expected valid signature")
+		}
+	}
+}
+
+// TestMuxPending checks that messages for sessions which are not open yet are buffered, bounded and expired.
+func TestMuxPending(t *testing.T) {
+	partyIDs := test.PartyIDs(2)
+	a, b := partyIDs[0], partyIDs[1]
+	network := communication.NewMemoryNetwork(partyIDs)
+	sender := network.Transport(b)
+
+	send := func(protocolID string, ssid []byte, data []byte) {
+		msg, err := cbor.Marshal(&protocol.Message{SSID: ssid, From: b, To: a, Protocol: protocolID, Data: data})
+		require.NoError(t, err)
+		require.NoError(t, sender.Send(context.Background(), a, msg))
+	}
+	// flush waits until the mux has routed all the messages sent so far, since messages of a peer are read in order.
+	flush := func(mux *protocol.Mux) {
+		s, err := mux.Open("flush", nil, nil)
+		require.NoError(t, err)
+		defer s.Close()
+		send("flush", nil, []byte("flush"))
+		_, err = s.Receive(context.Background(), b)
+		require.NoError(t, err)
+	}
+	receive := func(s *protocol.MuxSession) (*protocol.Message, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		data, err := s.Receive(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+		msg := &protocol.Message{}
+		require.NoError(t, cbor.Unmarshal(data, msg))
+		return msg, nil
+	}
+
+	t.Run("delivered on open", func(t *testing.T) {
+		mux := protocol.NewMux(network.Transport(a), []party.ID{b}, 2, time.Minute)
+		defer mux.Close()
+		send("test", []byte("ssid"), []byte("early"))
+		flush(mux)
+		require.Equal(t, 1, mux.Pending())
+
+		s, err := mux.Open("test", []byte("ssid"), []party.ID{b})
+		require.NoError(t, err)
+		defer s.Close()
+		assert.Equal(t, 0, mux.Pending())
+		msg, err := receive(s)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("early"), msg.Data)
+
+		_, err = mux.Open("test", []byte("ssid"), []party.ID{b})
+		assert.Error(t, err, "a session can only be opened once")
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		mux := protocol.NewMux(network.Transport(a), []party.ID{b}, 2, time.Minute)
+		defer mux.Close()
+		send("test", []byte("ssid"), []byte("1"))
+		send("test", []byte("ssid"), []byte("2"))
+		send("test", []byte("ssid"), []byte("3"))
+		flush(mux)
+		require.Equal(t, 2, mux.Pending())
+
+		s, err := mux.Open("test", []byte("ssid"), []party.ID{b})
+		require.NoError(t, err)
+		defer s.Close()
+		// the oldest message was dropped
+		msg, err := receive(s)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("2"), msg.Data)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mux := protocol.NewMux(network.Transport(a), []party.ID{b}, 2, 10*time.Millisecond)
+		defer mux.Close()
+		send("test", []byte("ssid"), []byte("late"))
+		flush(mux)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, 0, mux.Pending())
+
+		s, err := mux.Open("test", []byte("ssid"), []party.ID{b})
+		require.NoError(t, err)
+		defer s.Close()
+		_, err = receive(s)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// TestMuxPendingPerSender checks that a peer flooding the buffer with messages for sessions which are never opened
+// does not push out the messages of the other peers.
+func TestMuxPendingPerSender(t *testing.T) {
+	partyIDs := test.PartyIDs(3)
+	a, b, c := partyIDs[0], partyIDs[1], partyIDs[2]
+	network := communication.NewMemoryNetwork(partyIDs)
+	mux := protocol.NewMux(network.Transport(a), []party.ID{b, c}, 2, time.Minute)
+	defer mux.Close()
+
+	send := func(from party.ID, ssid []byte, data []byte) {
+		msg, err := cbor.Marshal(&protocol.Message{SSID: ssid, From: from, To: a, Protocol: "test", Data: data})
+		require.NoError(t, err)
+		require.NoError(t, network.Transport(from).Send(context.Background(), a, msg))
+	}
+	// flush waits until the mux has routed all the messages sent so far by from, since they are read in order.
+	flush := func(from party.ID) {
+		ssid := []byte("flush " + string(from))
+		s, err := mux.Open("test", ssid, []party.ID{b, c})
+		require.NoError(t, err)
+		defer s.Close()
+		send(from, ssid, []byte("flush"))
+		_, err = s.Receive(context.Background(), from)
+		require.NoError(t, err)
+	}
+	send(b, []byte("ssid"), []byte("honest"))
+	flush(b)
+	for i := 0; i < 10; i++ {
+		send(c, []byte(fmt.Sprintf("never opened %d", i)), []byte("flood"))
+	}
+	flush(c)
+	// c keeps at most 2 messages, the message of b is still there
+	assert.Equal(t, 3, mux.Pending())
+
+	s, err := mux.Open("test", []byte("ssid"), []party.ID{b, c})
+	require.NoError(t, err)
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	data, err := s.Receive(ctx, b)
+	require.NoError(t, err)
+	msg := &protocol.Message{}
+	require.NoError(t, cbor.Unmarshal(data, msg))
+	assert.Equal(t, []byte("honest"), msg.Data)
+}
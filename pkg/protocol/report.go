@@ -0,0 +1,137 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package protocol
+
+import (
+	"errors"
+	"sort"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/party"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// AbortReport describes an aborted protocol execution, it is the error returned by MultiHandler.Result.
+// It can be saved and handed to the other parties to prove the misbehaviour of the culprits,
+// since the evidence contains the messages they sent during the execution.
+type AbortReport struct {
+	// Protocol is the ID of the aborted protocol
+	Protocol string `json:"protocol"`
+	// SSID identifies the aborted execution
+	SSID []byte `json:"ssid"`
+	// Round is the round in which the execution aborted
+	Round round.Number `json:"round"`
+	// SelfID is the party which made the report
+	SelfID party.ID `json:"selfID"`
+	// Culprits are the parties responsible for the abort, empty if they could not be identified
+	Culprits []party.ID `json:"culprits,omitempty"`
+	// ReportedBy is the party which aborted the execution first, if SelfID only relays its abort.
+	// The report then has no culprits, those of the party are in its own report.
+	ReportedBy party.ID `json:"reportedBy,omitempty"`
+	// Check names the check which failed, such as the zero-knowledge proof "affg", "dec" or "nth", empty if unknown
+	Check string `json:"check,omitempty"`
+	// Reason describes the failure
+	Reason string `json:"reason"`
+	// Evidence are the CBOR encoded messages received from the culprits during the execution
+	Evidence [][]byte `json:"evidence,omitempty"`
+	// err is the error of the execution, it is not kept when the report is saved
+	err Error
+}
+
+// Error implement error.
+func (r *AbortReport) Error() string {
+	if r.err.Err != nil {
+		return r.err.Error()
+	}
+	return Error{Culprits: r.Culprits, Err: errors.New(r.Reason)}.Error()
+}
+
+// Unwrap implement errors.Wrapper, it returns the Error of the execution.
+func (r *AbortReport) Unwrap() error {
+	if r.err.Err == nil {
+		return nil
+	}
+	return r.err
+}
+
+// Messages decodes the evidence of the report.
+func (r *AbortReport) Messages() ([]*Message, error) {
+	msgs := make([]*Message, 0, len(r.Evidence))
+	for _, data := range r.Evidence {
+		msg := &Message{}
+		if err := cbor.Unmarshal(data, msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// newReport creates the AbortReport of the execution aborted in the given round with err.
+func (h *MultiHandler) newReport(number round.Number, err Error) *AbortReport {
+	r := &AbortReport{
+		Protocol:   h.currentRound.ProtocolID(),
+		SSID:       h.currentRound.SSID(),
+		Round:      number,
+		SelfID:     h.currentRound.SelfID(),
+		Culprits:   err.Culprits,
+		ReportedBy: h.relayedBy,
+		Reason:     err.Err.Error(),
+		err:        err,
+	}
+	var checkErr *round.CheckError
+	if errors.As(err.Err, &checkErr) {
+		r.Check = checkErr.Check
+	}
+	for _, msg := range h.evidence(err.Culprits) {
+		data, marshalErr := cbor.Marshal(msg)
+		if marshalErr != nil {
+			continue
+		}
+		r.Evidence = append(r.Evidence, data)
+	}
+	return r
+}
+
+// evidence returns the messages stored from the culprits, ordered by round.
+func (h *MultiHandler) evidence(culprits []party.ID) []*Message {
+	numbers := make([]round.Number, 0, len(h.broadcast)+len(h.messages))
+	for number := range h.broadcast {
+		numbers = append(numbers, number)
+	}
+	for number := range h.messages {
+		if _, ok := h.broadcast[number]; !ok {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	var msgs []*Message
+	for _, number := range numbers {
+		for _, id := range culprits {
+			if msg := h.broadcast[number][id]; msg != nil {
+				msgs = append(msgs, msg)
+			}
+			if msg := h.messages[number][id]; msg != nil {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	return msgs
+}
+
+// lastRound returns the number of the last round the execution reached, abort and output rounds excluded.
+func (h *MultiHandler) lastRound() round.Number {
+	var last round.Number
+	for number := range h.rounds {
+		if number > last {
+			last = number
+		}
+	}
+	return last
+}
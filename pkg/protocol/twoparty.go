@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -42,7 +43,7 @@ func NewTwoPartyHandler(create StartFunc, sessionID []byte, leader bool) (*TwoPa
 	return handler, nil
 }
 
-func (h *TwoPartyHandler) Result() (interface{}, error) {
+func (h *TwoPartyHandler) Result(context.Context) (interface{}, error) {
 	h.mtx.Lock()
 	defer h.mtx.Unlock()
 	if h.result != nil {
@@ -0,0 +1,205 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package recovery
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"MPC_ECDSA/pkg/math/curve"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+// wifMainnet is the version byte of a WIF encoded private key on the Bitcoin mainnet.
+const wifMainnet = 0x80
+
+// base58Alphabet is the Bitcoin base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// ScryptParams are the parameters of the scrypt key derivation of an Ethereum keystore.
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+var (
+	// StandardScrypt are the parameters used by Ethereum wallets by default, they use 256 MiB of memory.
+	StandardScrypt = ScryptParams{N: 1 << 18, R: 8, P: 1}
+	// LightScrypt are weaker parameters, for machines with little memory.
+	LightScrypt = ScryptParams{N: 1 << 12, R: 8, P: 6}
+)
+
+// Hex returns the 32 byte big endian encoding of the secret key, hex encoded.
+func Hex(secret curve.Scalar) (string, error) {
+	data, err := secret.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// WIF returns the secret key in the Wallet Import Format of Bitcoin mainnet, for a compressed public key.
+func WIF(secret curve.Scalar) (string, error) {
+	data, err := secret.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	// version || key || compressed flag
+	payload := make([]byte, 0, 1+len(data)+1+4)
+	payload = append(payload, wifMainnet)
+	payload = append(payload, data...)
+	payload = append(payload, 0x01)
+	first := sha256.Sum256(payload)
+	checksum := sha256.Sum256(first[:])
+	return base58Encode(append(payload, checksum[:4]...)), nil
+}
+
+// base58Encode encodes data with the Bitcoin base58 alphabet, keeping the leading zero bytes as '1'.
+func base58Encode(data []byte) string {
+	x := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// EthereumAddress returns the Ethereum address of a secp256k1 public key, hex encoded without checksum.
+func EthereumAddress(public curve.Point) (string, error) {
+	if public.Curve().Name() != (curve.Secp256k1{}).Name() {
+		return "", errors.New("recovery: Ethereum addresses require a secp256k1 key")
+	}
+	data, err := public.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	key, err := secp256k1.ParsePubKey(data)
+	if err != nil {
+		return "", err
+	}
+	// keccak256(X || Y)[12:]
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(key.SerializeUncompressed()[1:])
+	return hex.EncodeToString(h.Sum(nil)[12:]), nil
+}
+
+// keystoreV3 is the Web3 Secret Storage format of an Ethereum keystore.
+type keystoreV3 struct {
+	Address string           `json:"address"`
+	Crypto  keystoreV3Crypto `json:"crypto"`
+	ID      string           `json:"id"`
+	Version int              `json:"version"`
+}
+
+type keystoreV3Crypto struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams keystoreV3CipherParams `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    keystoreV3KDFParams    `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type keystoreV3CipherParams struct {
+	IV string `json:"iv"`
+}
+
+type keystoreV3KDFParams struct {
+	DKLen int    `json:"dklen"`
+	N     int    `json:"n"`
+	P     int    `json:"p"`
+	R     int    `json:"r"`
+	Salt  string `json:"salt"`
+}
+
+// KeystoreV3 returns the secret key encrypted with password, as the JSON of an Ethereum keystore v3
+// (scrypt key derivation, AES-128-CTR encryption and a Keccak-256 MAC).
+func KeystoreV3(secret curve.Scalar, password []byte, params ScryptParams) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("recovery: empty keystore password")
+	}
+	address, err := EthereumAddress(secret.ActOnBase())
+	if err != nil {
+		return nil, err
+	}
+	data, err := secret.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 32+aes.BlockSize+16)
+	if _, err = rand.Read(random); err != nil {
+		return nil, fmt.Errorf("recovery: failed to generate randomness: %w", err)
+	}
+	salt, iv, id := random[:32], random[32:32+aes.BlockSize], random[32+aes.BlockSize:]
+
+	derivedKey, err := scrypt.Key(password, salt, params.N, params.R, params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return nil, err
+	}
+	cipherText := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(cipherText, data)
+
+	// MAC = keccak256(derivedKey[16:32] || cipherText)
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(derivedKey[16:32])
+	_, _ = h.Write(cipherText)
+
+	return json.Marshal(keystoreV3{
+		Address: address,
+		Crypto: keystoreV3Crypto{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: keystoreV3CipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          "scrypt",
+			KDFParams: keystoreV3KDFParams{
+				DKLen: 32,
+				N:     params.N,
+				P:     params.P,
+				R:     params.R,
+				Salt:  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(h.Sum(nil)),
+		},
+		ID:      uuid(id),
+		Version: 3,
+	})
+}
+
+// uuid formats 16 random bytes as a version 4 UUID.
+func uuid(b []byte) string {
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
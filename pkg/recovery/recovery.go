@@ -0,0 +1,95 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package recovery reconstructs the full ECDSA secret key from the saved key shares of Threshold+1 parties,
+// for disaster recovery, and exports it in formats other wallets can import.
+//
+// Reconstructing the key defeats the purpose of MPC custody, so it must only be done offline.
+package recovery
+
+import (
+	"errors"
+	"fmt"
+
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/protocols/config"
+)
+
+var (
+	// ErrNotEnoughShares is returned when fewer than Threshold+1 shares are given.
+	ErrNotEnoughShares = errors.New("recovery: not enough shares")
+	// ErrInconsistentShare is returned when a share does not belong to the same key as the others,
+	// or does not match its public ECDSA share.
+	ErrInconsistentShare = errors.New("recovery: inconsistent share")
+	// ErrPublicKeyMismatch is returned when the interpolated secret does not match the group public key.
+	ErrPublicKeyMismatch = errors.New("recovery: secret does not match the public key")
+)
+
+// Reconstruct interpolates the secret ECDSA key x from the shares xᵢ of the given configs, with the Lagrange coefficients at 0.
+// It refuses to do so if any config is inconsistent:
+//   - all the configs must have the same group, threshold and public shares, and different IDs
+//   - each share must satisfy xᵢ⋅G = Xᵢ
+//   - the interpolated secret must satisfy x⋅G = PublicPoint().
+func Reconstruct(configs []*config.Config) (curve.Scalar, error) {
+	if len(configs) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+	first := configs[0]
+	group := first.Group
+	ids := make([]party.ID, 0, len(configs))
+	shares := make(map[party.ID]curve.Scalar, len(configs))
+	for _, c := range configs {
+		if err := checkShare(first, c); err != nil {
+			return nil, err
+		}
+		if _, ok := shares[c.ID]; ok {
+			return nil, fmt.Errorf("%w: %s is given twice", ErrInconsistentShare, c.ID)
+		}
+		ids = append(ids, c.ID)
+		shares[c.ID] = c.ECDSA
+	}
+	if len(configs) < first.Threshold+1 {
+		return nil, fmt.Errorf("%w: %d given, %d needed", ErrNotEnoughShares, len(configs), first.Threshold+1)
+	}
+
+	// x = ∑ lᵢ⋅xᵢ
+	secret := group.NewScalar()
+	lagrange := polynomial.Lagrange(group, ids)
+	for _, id := range ids {
+		secret.Add(group.NewScalar().Set(lagrange[id]).Mul(shares[id]))
+	}
+	if !secret.ActOnBase().Equal(first.PublicPoint()) {
+		return nil, ErrPublicKeyMismatch
+	}
+	return secret, nil
+}
+
+// checkShare checks that c is a share of the same key as first, and that its secret share matches its public share.
+func checkShare(first, c *config.Config) error {
+	if c == nil || c.ECDSA == nil {
+		return fmt.Errorf("%w: missing share", ErrInconsistentShare)
+	}
+	if c.Group.Name() != first.Group.Name() || c.Threshold != first.Threshold || len(c.Public) != len(first.Public) {
+		return fmt.Errorf("%w: %s belongs to another key", ErrInconsistentShare, c.ID)
+	}
+	for j, public := range first.Public {
+		other, ok := c.Public[j]
+		if !ok || public == nil || other == nil || !public.ECDSA.Equal(other.ECDSA) {
+			return fmt.Errorf("%w: %s belongs to another key", ErrInconsistentShare, c.ID)
+		}
+	}
+	public, ok := c.Public[c.ID]
+	if !ok {
+		return fmt.Errorf("%w: %s is not a party of the key", ErrInconsistentShare, c.ID)
+	}
+	// Xᵢ = xᵢ⋅G
+	if !c.ECDSA.ActOnBase().Equal(public.ECDSA) {
+		return fmt.Errorf("%w: the share of %s does not match its public share", ErrInconsistentShare, c.ID)
+	}
+	return nil
+}
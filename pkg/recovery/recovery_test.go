@@ -0,0 +1,129 @@
+package recovery
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	mrand "math/rand"
+	"testing"
+
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/BigInt"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/protocols/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+var group = curve.Secp256k1{}
+
+func generateConfigs(seed int64) []*config.Config {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	configs, partyIDs := test.GenerateConfig(group, 4, 2, mrand.New(mrand.NewSource(seed)), pl)
+	result := make([]*config.Config, 0, len(partyIDs))
+	for _, id := range partyIDs {
+		result = append(result, configs[id])
+	}
+	return result
+}
+
+func TestReconstruct(t *testing.T) {
+	configs := generateConfigs(1)
+	public := configs[0].PublicPoint()
+
+	secret, err := Reconstruct(configs[1:])
+	require.NoError(t, err)
+	assert.True(t, secret.ActOnBase().Equal(public))
+
+	all, err := Reconstruct(configs)
+	require.NoError(t, err)
+	assert.True(t, all.Equal(secret))
+}
+
+func TestReconstructRefuses(t *testing.T) {
+	configs := generateConfigs(1)
+
+	_, err := Reconstruct(configs[:2])
+	assert.ErrorIs(t, err, ErrNotEnoughShares)
+
+	_, err = Reconstruct([]*config.Config{configs[0], configs[1], configs[1]})
+	assert.ErrorIs(t, err, ErrInconsistentShare)
+
+	tampered := *configs[1]
+	tampered.ECDSA = sample.Scalar(rand.Reader, group)
+	_, err = Reconstruct([]*config.Config{configs[0], &tampered, configs[2]})
+	assert.ErrorIs(t, err, ErrInconsistentShare)
+
+	// a share of another key, whose public shares differ
+	other := *configs[2]
+	other.Public = make(map[party.ID]*config.Public, len(configs[2].Public))
+	for id, public := range configs[2].Public {
+		other.Public[id] = public
+	}
+	other.Public[configs[0].ID] = &config.Public{ECDSA: sample.Scalar(rand.Reader, group).ActOnBase()}
+	_, err = Reconstruct([]*config.Config{configs[0], configs[1], &other})
+	assert.ErrorIs(t, err, ErrInconsistentShare)
+}
+
+func one() curve.Scalar {
+	return group.NewScalar().SetNat(new(BigInt.Nat).SetUint64(1))
+}
+
+func TestWIF(t *testing.T) {
+	wif, err := WIF(one())
+	require.NoError(t, err)
+	assert.Equal(t, "KwDiBf89QgGbjEhKnhXJuH7LrciVrZi3qYjgd9M7rFU73sVHnoWn", wif)
+
+	h, err := Hex(one())
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000001", h)
+}
+
+func TestEthereumAddress(t *testing.T) {
+	address, err := EthereumAddress(one().ActOnBase())
+	require.NoError(t, err)
+	assert.Equal(t, "7e5f4552091a69125d5dfcb7b8c2659029395bdf", address)
+}
+
+func TestKeystoreV3(t *testing.T) {
+	secret := sample.Scalar(rand.Reader, group)
+	password := []byte("password")
+	data, err := KeystoreV3(secret, password, LightScrypt)
+	require.NoError(t, err)
+
+	var keystore keystoreV3
+	require.NoError(t, json.Unmarshal(data, &keystore))
+	assert.Equal(t, 3, keystore.Version)
+	address, err := EthereumAddress(secret.ActOnBase())
+	require.NoError(t, err)
+	assert.Equal(t, address, keystore.Address)
+
+	// decrypt as an Ethereum wallet would
+	params := keystore.Crypto.KDFParams
+	salt, _ := hex.DecodeString(params.Salt)
+	derivedKey, err := scrypt.Key(password, salt, params.N, params.R, params.P, params.DKLen)
+	require.NoError(t, err)
+	cipherText, _ := hex.DecodeString(keystore.Crypto.CipherText)
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(derivedKey[16:32])
+	_, _ = h.Write(cipherText)
+	assert.Equal(t, keystore.Crypto.MAC, hex.EncodeToString(h.Sum(nil)))
+
+	iv, _ := hex.DecodeString(keystore.Crypto.CipherParams.IV)
+	block, err := aes.NewCipher(derivedKey[:16])
+	require.NoError(t, err)
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+	expected, err := secret.MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, expected, plainText)
+}
@@ -0,0 +1,56 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package auxinfo rotates the auxiliary information of a config, without touching the key shares.
+//
+// Each party generates a new Paillier key and Pedersen parameters, proves them with zkmod and zkprm,
+// and reliably broadcasts them. The ECDSA and ElGamal shares, RID and ChainKey are unchanged,
+// so that it is much faster than a refresh, which runs the whole keygen.
+// It is used after a suspected leak of a Paillier secret key.
+package auxinfo
+
+import (
+	"errors"
+	"fmt"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/config"
+)
+
+const (
+	protocolID = "cmp/aux-info"
+	// Rounds is the number of rounds of the protocol.
+	Rounds round.Number = 2
+)
+
+// Start returns the StartFunc of the aux-info protocol, run by all the parties of c.
+// Returns *config.Config with the new Paillier keys and Pedersen parameters if successful.
+func Start(c *config.Config, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if c == nil {
+			return nil, errors.New("auxinfo: config is nil")
+		}
+		info := round.Info{
+			ProtocolID:       protocolID,
+			FinalRoundNumber: Rounds,
+			SelfID:           c.ID,
+			PartyIDs:         c.PartyIDs(),
+			Threshold:        c.Threshold,
+			Group:            c.Group,
+		}
+		// bind the session to the key, the proofs cannot be replayed for another config
+		helper, err := round.NewSession(info, sessionID, pl, c)
+		if err != nil {
+			return nil, fmt.Errorf("auxinfo: %w", err)
+		}
+		return &round1{
+			Helper: helper,
+			Config: c,
+		}, nil
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package auxinfo
+
+import (
+	mrand "math/rand"
+	"testing"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/protocols/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var group = curve.Secp256k1{}
+
+func TestAuxInfo(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	N, T := 3, 1
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+
+	rounds := make([]round.Session, 0, N)
+	for _, id := range partyIDs {
+		r, err := Start(configs[id], pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r)
+		require.IsType(t, &config.Config{}, r.(*round.Output).Result)
+		c := r.(*round.Output).Result.(*config.Config)
+		data, err := c.MarshalBinary()
+		require.NoError(t, err)
+		newConfig := config.EmptyConfig(group)
+		require.NoError(t, newConfig.UnmarshalBinary(data), "failed to unmarshal new config", c.ID)
+
+		old := configs[c.ID]
+		assert.True(t, newConfig.ECDSA.Equal(old.ECDSA), "the ECDSA share should not change")
+		assert.True(t, newConfig.ElGamal.Equal(old.ElGamal), "the ElGamal share should not change")
+		assert.True(t, newConfig.PublicPoint().Equal(old.PublicPoint()))
+		assert.Equal(t, old.RID, newConfig.RID)
+		assert.Equal(t, old.ChainKey, newConfig.ChainKey)
+		assert.False(t, newConfig.Paillier.PublicKey.Equal(old.Paillier.PublicKey), "the Paillier key should be new")
+		for _, id := range partyIDs {
+			public := newConfig.Public[id]
+			assert.True(t, public.ECDSA.Equal(old.Public[id].ECDSA))
+			assert.False(t, public.Paillier.Equal(old.Public[id].Paillier), "the Paillier key should be new")
+		}
+		assert.True(t, newConfig.Public[c.ID].Paillier.Equal(newConfig.Paillier.PublicKey))
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package auxinfo
+
+import (
+	"MPC_ECDSA/internal/round"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pedersen"
+	zkmod "MPC_ECDSA/pkg/zk/mod"
+	zkprm "MPC_ECDSA/pkg/zk/prm"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round1)(nil)
+
+type round1 struct {
+	*round.Helper
+
+	// Config is the config whose auxiliary information is rotated
+	Config *config.Config
+}
+
+// VerifyMessage implements round.Round.
+func (r *round1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (r *round1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - sample Paillier (pᵢ, qᵢ)
+// - sample Pedersen Nᵢ, sᵢ, tᵢ
+// - prove Nᵢ is a Blum modulus with zkmod, and sᵢ, tᵢ are Pedersen parameters with zkprm.
+func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	PaillierSecret := paillier.NewSecretKey(nil)
+	PedersenPublic, PedersenSecret := PaillierSecret.GeneratePedersen()
+
+	h := r.HashForID(r.SelfID())
+	mod := zkmod.NewProofMal(h.Clone(), zkmod.Private{
+		P:   PaillierSecret.P(),
+		Q:   PaillierSecret.Q(),
+		Phi: PaillierSecret.Phi(),
+	}, zkmod.Public{N: PedersenPublic.N()}, r.Pool)
+	prm := zkprm.NewProofMal(zkprm.Private{
+		Lambda: PedersenSecret,
+		Phi:    PaillierSecret.Phi(),
+		P:      PaillierSecret.P(),
+		Q:      PaillierSecret.Q(),
+	}, h.Clone(), zkprm.Public{N: PedersenPublic.N(), S: PedersenPublic.S(), T: PedersenPublic.T()}, r.Pool)
+
+	if err := r.BroadcastMessage(out, &broadcast2{
+		N:   PedersenPublic.N(),
+		S:   PedersenPublic.S(),
+		T:   PedersenPublic.T(),
+		Mod: mod,
+		Prm: prm,
+	}); err != nil {
+		return r, err
+	}
+	return &round2{
+		round1:         r,
+		PaillierPublic: map[party.ID]*paillier.PublicKey{r.SelfID(): PaillierSecret.PublicKey},
+		Pedersen:       map[party.ID]*pedersen.Parameters{r.SelfID(): PedersenPublic},
+		PaillierSecret: PaillierSecret,
+	}, nil
+}
+
+// MessageContent implements round.Round.
+func (round1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (round1) Number() round.Number { return 1 }
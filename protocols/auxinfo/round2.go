@@ -0,0 +1,118 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package auxinfo
+
+import (
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/BigInt"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pedersen"
+	zkmod "MPC_ECDSA/pkg/zk/mod"
+	zkprm "MPC_ECDSA/pkg/zk/prm"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round2)(nil)
+
+type round2 struct {
+	*round1
+
+	// PaillierPublic[j] = Nⱼ
+	PaillierPublic map[party.ID]*paillier.PublicKey
+	// Pedersen[j] = (Nⱼ, sⱼ, tⱼ)
+	Pedersen map[party.ID]*pedersen.Parameters
+
+	// PaillierSecret = (pᵢ, qᵢ)
+	PaillierSecret *paillier.SecretKey
+}
+
+type broadcast2 struct {
+	round.ReliableBroadcastContent
+
+	// N, S, T are the new Paillier modulus and Pedersen parameters Nⱼ, sⱼ, tⱼ
+	N, S, T *BigInt.Nat
+	// Mod proves Nⱼ is a Blum modulus
+	Mod *zkmod.Proofbuf
+	// Prm proves sⱼ, tⱼ are Pedersen parameters of Nⱼ
+	Prm *zkprm.Proofbuf
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - validate Paillier Nⱼ and Pedersen sⱼ, tⱼ
+// - verify zkmod and zkprm
+// - save Nⱼ, sⱼ, tⱼ.
+func (r *round2) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if body.N == nil || body.S == nil || body.T == nil || body.Mod == nil || body.Prm == nil {
+		return round.ErrNilFields
+	}
+	if err := paillier.ValidateN(body.N); err != nil {
+		return err
+	}
+	if err := pedersen.ValidateParameters(body.N, body.S, body.T); err != nil {
+		return err
+	}
+	// a party must not keep its previous modulus
+	if body.N.Eq(r.Config.Public[from].Paillier.N()) == 1 {
+		return errors.New("Paillier modulus was not rotated")
+	}
+	if !body.Mod.VerifyMal(zkmod.Public{N: body.N}, r.HashForID(from), r.Pool) {
+		return errors.New("failed to validate mod proof")
+	}
+	if !body.Prm.VerifyMal(zkprm.Public{N: body.N, S: body.S, T: body.T}, r.HashForID(from), r.Pool) {
+		return errors.New("failed to validate prm proof")
+	}
+	PaillierPublic := paillier.NewPublicKeyFromN(body.N)
+	r.PaillierPublic[from] = PaillierPublic
+	r.Pedersen[from] = pedersen.New(PaillierPublic.Modulus(), body.S, body.T)
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - output the config with the new Paillier keys and Pedersen parameters, and the same shares.
+func (r *round2) Finalize(chan<- *round.Message) (round.Session, error) {
+	Public := make(map[party.ID]*config.Public, len(r.Config.Public))
+	for j, public := range r.Config.Public {
+		Public[j] = &config.Public{
+			ECDSA:    public.ECDSA,
+			ElGamal:  public.ElGamal,
+			Paillier: r.PaillierPublic[j],
+			Pedersen: r.Pedersen[j],
+		}
+	}
+	updated := *r.Config
+	updated.Paillier = r.PaillierSecret
+	updated.Public = Public
+	return r.ResultRound(&updated), nil
+}
+
+// MessageContent implements round.Round.
+func (round2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast2) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round2) BroadcastContent() round.BroadcastContent { return &broadcast2{} }
+
+// Number implements round.Round.
+func (round2) Number() round.Number { return 2 }
@@ -12,8 +12,10 @@ import (
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/mnemonic"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pedersen"
+	"MPC_ECDSA/pkg/taproot"
 )
 
 // Config contains all necessary cryptographic keys necessary to generate a signature.
@@ -43,6 +45,11 @@ type Config struct {
 	ChainKey types.RID
 	// Public 所有参与方的公开信息 maps party.ID to public. It contains all public information associated to a party.
 	Public map[party.ID]*Public
+	// Mnemonic is the BIP-39 mnemonic the constant of this party's VSS polynomial was derived from, when the key
+	// was generated with useMnemonic. The constant is this party's contribution to the secret ECDSA key, see mnemonic.VSSConstant.
+	Mnemonic string
+	// EncryptedMnemonic is Mnemonic encrypted to an operator key by EncryptMnemonic, which then clears Mnemonic.
+	EncryptedMnemonic []byte
 }
 
 type ResharingInfo struct {
@@ -60,6 +67,25 @@ type Public struct {
 	Pedersen *pedersen.Parameters
 }
 
+// EncryptMnemonic encrypts Mnemonic to the public key of an operator, so that only the operator can read it.
+func (c *Config) EncryptMnemonic(operator curve.Point) error {
+	if c.Mnemonic == "" {
+		return errors.New("config: no mnemonic to encrypt")
+	}
+	encrypted, err := mnemonic.Encrypt(c.Mnemonic, operator)
+	if err != nil {
+		return err
+	}
+	c.EncryptedMnemonic = encrypted
+	c.Mnemonic = ""
+	return nil
+}
+
+// Format implements fmt.Formatter, only the party and the threshold of a config are printed, never its secrets.
+func (c *Config) Format(f fmt.State, _ rune) {
+	_, _ = fmt.Fprintf(f, "config of %s, threshold %d", c.ID, c.Threshold)
+}
+
 // PublicPoint returns the group's public ECC point.
 func (c *Config) PublicPoint() curve.Point {
 	sum := c.Group.NewPoint()
@@ -276,3 +302,59 @@ func (c *Config) DeriveBIP32(i uint32) (*Config, error) {
 	}
 	return c.Derive(scalar, newChainKey)
 }
+
+// TaprootTweak derives a sharing of the BIP-341 output key Q = P + [t]⋅G,
+// where t = H_TapTweak(P|ₓ, merkleRoot) and P is the group key with an even y coordinate.
+//
+// An empty merkleRoot commits to no script tree, as recommended by BIP-86 for key path only outputs.
+// If the group key has an odd y coordinate, all the shares are negated before adding t,
+// so that the resulting config holds a sharing of Q itself, whose parity is again arbitrary.
+//
+// See: https://github.com/bitcoin/bips/blob/master/bip-0341.mediawiki#constructing-and-spending-taproot-outputs
+func (c *Config) TaprootTweak(merkleRoot []byte) (*Config, error) {
+	publicPoint, ok := c.PublicPoint().(*curve.Secp256k1Point)
+	if !ok {
+		return nil, errors.New("TaprootTweak must be called with secp256k1")
+	}
+	if len(merkleRoot) != 0 && len(merkleRoot) != 32 {
+		return nil, fmt.Errorf("expected 32 bytes for merkle root, found %d", len(merkleRoot))
+	}
+	tweak := c.Group.NewScalar()
+	if err := tweak.UnmarshalBinary(taproot.TaggedHash("TapTweak", publicPoint.XBytes(), merkleRoot)); err != nil {
+		return nil, fmt.Errorf("invalid taproot tweak: %w", err)
+	}
+
+	base := c
+	if !publicPoint.HasEvenY() {
+		// x ↦ -x gives a sharing of -P, which has an even y coordinate
+		public := make(map[party.ID]*Public, len(c.Public))
+		for k, v := range c.Public {
+			public[k] = &Public{
+				ECDSA:    v.ECDSA.Negate(),
+				ElGamal:  v.ElGamal,
+				Paillier: v.Paillier,
+				Pedersen: v.Pedersen,
+			}
+		}
+		base = &Config{
+			Group:     c.Group,
+			ID:        c.ID,
+			Threshold: c.Threshold,
+			ECDSA:     c.Group.NewScalar().Set(c.ECDSA).Negate(),
+			ElGamal:   c.ElGamal,
+			Paillier:  c.Paillier,
+			RID:       c.RID,
+			ChainKey:  c.ChainKey,
+			Public:    public,
+		}
+	}
+
+	tweaked, err := base.Derive(tweak, nil)
+	if err != nil {
+		return nil, err
+	}
+	if tweaked.PublicPoint().IsIdentity() {
+		return nil, errors.New("taproot tweak results in the identity")
+	}
+	return tweaked, nil
+}
@@ -30,6 +30,9 @@ type configMarshal struct {
 	P, Q           *BigInt.Nat
 	RID, ChainKey  types.RID
 	Public         []cbor.RawMessage
+	// the mnemonic is only present for keys generated with useMnemonic
+	Mnemonic          string `cbor:",omitempty"`
+	EncryptedMnemonic []byte `cbor:",omitempty"`
 }
 
 type publicMarshal struct {
@@ -67,6 +70,9 @@ func (c *Config) MarshalBinary() ([]byte, error) {
 		RID:       c.RID,
 		ChainKey:  c.ChainKey,
 		Public:    ps,
+
+		Mnemonic:          c.Mnemonic,
+		EncryptedMnemonic: c.EncryptedMnemonic,
 	})
 }
 
@@ -161,6 +167,9 @@ func (c *Config) UnmarshalBinary(data []byte) error {
 		RID:       cm.RID,
 		ChainKey:  cm.ChainKey,
 		Public:    ps,
+
+		Mnemonic:          cm.Mnemonic,
+		EncryptedMnemonic: cm.EncryptedMnemonic,
 	}
 	return nil
 }
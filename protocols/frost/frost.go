@@ -0,0 +1,139 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package frost implements FROST threshold Schnorr signatures over secp256k1, producing BIP-340 signatures
+// with the shares of a config.Config generated by keygen.
+//
+// The protocol is split in two parts:
+//   - StartPreprocess generates the nonce commitments of a signer set, it does not depend on the message
+//     and can run ahead of time.
+//   - StartSign uses the Nonces of a preprocessing to sign a message in a single round.
+package frost
+
+import (
+	"errors"
+	"fmt"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/types"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/config"
+)
+
+const (
+	protocolPreprocessID                  = "frost/preprocess"
+	protocolPreprocessRounds round.Number = 2
+	protocolSignID                        = "frost/sign"
+	protocolSignRounds       round.Number = 2
+)
+
+// StartPreprocess returns the StartFunc of the preprocessing phase, which generates the nonces used by StartSign.
+// Returns *Nonces if successful, they should be treated as secret key material and can only be used for one signature.
+// They only live in memory and must never be persisted.
+func StartPreprocess(config *config.Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if err := checkConfig(config); err != nil {
+			return nil, fmt.Errorf("frost.Preprocess: %w", err)
+		}
+		info := round.Info{
+			ProtocolID:       protocolPreprocessID,
+			FinalRoundNumber: protocolPreprocessRounds,
+			SelfID:           config.ID,
+			PartyIDs:         signers,
+			Threshold:        config.Threshold,
+			Group:            config.Group,
+		}
+		helper, err := round.NewSession(info, sessionID, pl, config)
+		if err != nil {
+			return nil, fmt.Errorf("frost.Preprocess: %w", err)
+		}
+		if !config.CanSign(helper.PartyIDs()) {
+			return nil, errors.New("frost.Preprocess: signers is not a valid signing subset")
+		}
+		return &preprocess1{
+			Helper: helper,
+		}, nil
+	}
+}
+
+// StartSign returns the StartFunc of the signing phase, which signs message with the nonces of a preprocessing
+// between the same signers. The nonces are erased before the signature share is computed.
+// Returns taproot.Signature if successful.
+func StartSign(config *config.Config, nonces *Nonces, message []byte, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		group := config.Group
+		if err := checkConfig(config); err != nil {
+			return nil, fmt.Errorf("frost.Sign: %w", err)
+		}
+		if len(message) == 0 {
+			return nil, errors.New("frost.Sign: message is nil")
+		}
+		if nonces == nil {
+			return nil, errors.New("frost.Sign: nonces are nil")
+		}
+		info := round.Info{
+			ProtocolID:       protocolSignID,
+			FinalRoundNumber: protocolSignRounds,
+			SelfID:           config.ID,
+			PartyIDs:         nonces.Signers(),
+			Threshold:        config.Threshold,
+			Group:            group,
+		}
+		helper, err := round.NewSession(info, sessionID, pl, config, types.SigningMessage(message))
+		if err != nil {
+			return nil, fmt.Errorf("frost.Sign: %w", err)
+		}
+		if !config.CanSign(helper.PartyIDs()) {
+			return nil, errors.New("frost.Sign: signers is not a valid signing subset")
+		}
+		if err = nonces.validate(config.ID, helper.PartyIDs()); err != nil {
+			return nil, fmt.Errorf("frost.Sign: %w", err)
+		}
+
+		// Scale the key shares, so that the group key is the sum of the public shares.
+		// BIP-340 keys have an even y coordinate, if the group key does not the secret is negated,
+		// which every signer does by negating its share.
+		lagrange := polynomial.Lagrange(group, helper.PartyIDs())
+		PublicKey := group.NewPoint()
+		PublicShares := make(map[party.ID]curve.Point, helper.N())
+		for _, j := range helper.PartyIDs() {
+			PublicShares[j] = lagrange[j].Act(config.Public[j].ECDSA)
+			PublicKey = PublicKey.Add(PublicShares[j])
+		}
+		SecretShare := group.NewScalar().Set(lagrange[config.ID]).Mul(config.ECDSA)
+		if !PublicKey.(*curve.Secp256k1Point).HasEvenY() {
+			PublicKey = PublicKey.Negate()
+			SecretShare.Negate()
+			for j, share := range PublicShares {
+				PublicShares[j] = share.Negate()
+			}
+		}
+
+		return &sign1{
+			Helper:       helper,
+			PublicKey:    PublicKey,
+			PublicShares: PublicShares,
+			SecretShare:  SecretShare,
+			Nonces:       nonces,
+			Message:      message,
+		}, nil
+	}
+}
+
+// checkConfig returns an error if config cannot be used for BIP-340 signatures.
+func checkConfig(config *config.Config) error {
+	if config == nil {
+		return errors.New("config is nil")
+	}
+	if _, ok := config.Group.(curve.Secp256k1); !ok {
+		return errors.New("BIP-340 signatures require a secp256k1 key")
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	mrand "math/rand"
+	"testing"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/taproot"
+	"MPC_ECDSA/protocols/config"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runRounds executes the rounds until they all reach their output round, and returns the results.
+func runRounds(t *testing.T, rounds []round.Session) []interface{} {
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	results := make([]interface{}, 0, len(rounds))
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		results = append(results, r.(*round.Output).Result)
+	}
+	return results
+}
+
+// preprocess runs the preprocessing among signers, and returns the nonces of each of them.
+func preprocess(t *testing.T, configs map[party.ID]*config.Config, signers party.IDSlice, pl *pool.Pool) map[party.ID]*Nonces {
+	rounds := make([]round.Session, 0, len(signers))
+	for _, id := range signers {
+		r, err := StartPreprocess(configs[id], signers, pl)(nil)
+		require.NoError(t, err)
+		rounds = append(rounds, r)
+	}
+	nonces := make(map[party.ID]*Nonces, len(signers))
+	for _, result := range runRounds(t, rounds) {
+		require.IsType(t, &Nonces{}, result)
+		n := result.(*Nonces)
+		nonces[n.ID()] = n
+	}
+	return nonces
+}
+
+// TestSign preprocesses nonces, then signs with them, for group keys with an even and an odd y coordinate.
+func TestSign(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+	N, T := 4, 2
+	messageHash := sha256.Sum256([]byte("hello"))
+
+	seenParity := map[bool]bool{}
+	for seed := int64(1); len(seenParity) < 2 && seed < 10; seed++ {
+		configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(seed)), pl)
+		signers := partyIDs[:T+1]
+		publicPoint := configs[signers[0]].PublicPoint()
+		seenParity[publicPoint.(*curve.Secp256k1Point).HasEvenY()] = true
+		publicKey := taproot.PublicKey(publicPoint.(*curve.Secp256k1Point).XBytes())
+
+		nonces := preprocess(t, configs, signers, pl)
+		// the nonces cannot be persisted, a stored copy would survive the erasure
+		for _, n := range nonces {
+			_, err := cbor.Marshal(n)
+			assert.ErrorIs(t, err, errPersist)
+			_, err = json.Marshal(n)
+			assert.Error(t, err)
+		}
+
+		rounds := make([]round.Session, 0, len(signers))
+		for _, id := range signers {
+			r, err := StartSign(configs[id], nonces[id], messageHash[:], pl)(nil)
+			require.NoError(t, err)
+			rounds = append(rounds, r)
+		}
+		for _, result := range runRounds(t, rounds) {
+			require.IsType(t, taproot.Signature{}, result)
+			assert.True(t, publicKey.Verify(result.(taproot.Signature), messageHash[:]), "expected valid signature")
+		}
+
+		// the nonces were erased and cannot sign again
+		for _, id := range signers {
+			assert.True(t, nonces[id].Used())
+			_, err := StartSign(configs[id], nonces[id], messageHash[:], pl)(nil)
+			assert.Error(t, err)
+		}
+	}
+	assert.Len(t, seenParity, 2, "expected group keys of both parities")
+}
+
+// TestSignWrongSigners checks that nonces cannot be used with another signer set or by another party.
+func TestSignWrongSigners(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+	configs, partyIDs := test.GenerateConfig(group, 4, 1, mrand.New(mrand.NewSource(1)), pl)
+	message := []byte("message")
+
+	nonces := preprocess(t, configs, partyIDs[:2], pl)
+	_, err := StartSign(configs[partyIDs[0]], nonces[partyIDs[1]], message, pl)(nil)
+	assert.Error(t, err)
+
+	other := preprocess(t, configs, partyIDs[1:3], pl)
+	_, err = StartSign(configs[partyIDs[1]], other[partyIDs[1]], message, pl)(nil)
+	require.NoError(t, err)
+	// mixing the commitments of two preprocessings is detected
+	other[partyIDs[1]].hidingCommitments = nonces[partyIDs[1]].hidingCommitments
+	_, err = StartSign(configs[partyIDs[1]], other[partyIDs[1]], message, pl)(nil)
+	assert.Error(t, err)
+}
+
+// TestSignTaprootTweak signs with configs tweaked by a merkle root, and checks the signature against the BIP-341 output key.
+func TestSignTaprootTweak(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+	N, T := 3, 1
+	messageHash := sha256.Sum256([]byte("key path spend"))
+	merkleRoot := sha256.Sum256([]byte("script tree"))
+
+	seenParity := map[bool]bool{}
+	for seed := int64(1); len(seenParity) < 2 && seed < 10; seed++ {
+		configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(seed)), pl)
+		signers := partyIDs[:T+1]
+		internalKey := configs[signers[0]].PublicPoint().(*curve.Secp256k1Point)
+		seenParity[internalKey.HasEvenY()] = true
+
+		// Q = lift_x(P|ₓ) + [H_TapTweak(P|ₓ, merkleRoot)]⋅G
+		evenKey, err := group.LiftX(internalKey.XBytes())
+		require.NoError(t, err)
+		tweak := group.NewScalar()
+		require.NoError(t, tweak.UnmarshalBinary(taproot.TaggedHash("TapTweak", internalKey.XBytes(), merkleRoot[:])))
+		outputKey := tweak.ActOnBase().Add(evenKey)
+
+		tweaked := make(map[party.ID]*config.Config, len(signers))
+		for _, id := range signers {
+			tweaked[id], err = configs[id].TaprootTweak(merkleRoot[:])
+			require.NoError(t, err)
+			require.True(t, outputKey.Equal(tweaked[id].PublicPoint()), "expected tweaked config to share Q")
+		}
+
+		nonces := preprocess(t, tweaked, signers, pl)
+		rounds := make([]round.Session, 0, len(signers))
+		for _, id := range signers {
+			r, err := StartSign(tweaked[id], nonces[id], messageHash[:], pl)(nil)
+			require.NoError(t, err)
+			rounds = append(rounds, r)
+		}
+		publicKey := taproot.PublicKey(outputKey.(*curve.Secp256k1Point).XBytes())
+		for _, result := range runRounds(t, rounds) {
+			assert.True(t, publicKey.Verify(result.(taproot.Signature), messageHash[:]), "expected valid signature under Q")
+		}
+	}
+	assert.Len(t, seenParity, 2, "expected internal keys of both parities")
+
+	configs, _ := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	for _, c := range configs {
+		_, err := c.TaprootTweak(make([]byte, 31))
+		assert.Error(t, err, "expected invalid merkle root length to be rejected")
+		break
+	}
+}
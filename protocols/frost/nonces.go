@@ -0,0 +1,128 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+)
+
+// errPersist is returned by the encoders of Nonces.
+var errPersist = errors.New("frost: nonces must never be persisted")
+
+// Nonces is the output of the preprocessing phase for one signer, it is consumed by a single signature.
+//
+// The nonces only live in memory: signing twice with the same nonces reveals the secret share, and a copy restored
+// from storage would undo the erasure done by the first signature. Nonces cannot be encoded, and must never be persisted,
+// a new preprocessing costs a single round and can be run again after a restart.
+type Nonces struct {
+	mtx sync.Mutex
+	// id is the signer these nonces belong to.
+	id party.ID
+	// signers is the sorted signer set of the preprocessing, the signature must use the same one.
+	signers party.IDSlice
+	// hidingNonce = dᵢ
+	hidingNonce curve.Scalar
+	// bindingNonce = eᵢ
+	bindingNonce curve.Scalar
+	// hidingCommitments[j] = Dⱼ = [dⱼ]⋅G
+	hidingCommitments *party.PointMap
+	// bindingCommitments[j] = Eⱼ = [eⱼ]⋅G
+	bindingCommitments *party.PointMap
+}
+
+// ID returns the signer these nonces belong to.
+func (n *Nonces) ID() party.ID { return n.id }
+
+// Signers returns the signer set of the preprocessing.
+func (n *Nonces) Signers() party.IDSlice { return n.signers.Copy() }
+
+// MarshalBinary implements encoding.BinaryMarshaler, it always fails since the nonces must never be persisted.
+func (*Nonces) MarshalBinary() ([]byte, error) { return nil, errPersist }
+
+// MarshalCBOR implements cbor.Marshaler, it always fails since the nonces must never be persisted.
+func (*Nonces) MarshalCBOR() ([]byte, error) { return nil, errPersist }
+
+// MarshalJSON implements json.Marshaler, it always fails since the nonces must never be persisted.
+func (*Nonces) MarshalJSON() ([]byte, error) { return nil, errPersist }
+
+// Used reports whether the nonces have been consumed by a signature.
+func (n *Nonces) Used() bool {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.used()
+}
+
+func (n *Nonces) used() bool {
+	return n.hidingNonce.IsZero() || n.bindingNonce.IsZero()
+}
+
+// consume returns copies of the secret nonces and zeroes them, so that they cannot sign a second message.
+// It fails if they were already consumed, also when two signatures race for the same nonces.
+func (n *Nonces) consume() (hidingNonce, bindingNonce curve.Scalar, err error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.used() {
+		return nil, nil, errors.New("nonces already used")
+	}
+	group := n.hidingNonce.Curve()
+	hidingNonce = group.NewScalar().Set(n.hidingNonce)
+	bindingNonce = group.NewScalar().Set(n.bindingNonce)
+	zero := group.NewScalar()
+	n.hidingNonce.Set(zero)
+	n.bindingNonce.Set(zero)
+	return hidingNonce, bindingNonce, nil
+}
+
+// validate checks that the nonces can be used by selfID to sign with signers.
+func (n *Nonces) validate(selfID party.ID, signers party.IDSlice) error {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	if n.hidingNonce == nil || n.bindingNonce == nil || n.hidingCommitments == nil || n.bindingCommitments == nil {
+		return errors.New("nonces: nil fields")
+	}
+	if n.id != selfID {
+		return fmt.Errorf("nonces: generated for %s", n.id)
+	}
+	if n.used() {
+		return errors.New("nonces: already used")
+	}
+	if len(n.signers) != len(signers) || !party.NewIDSlice(n.signers).Contains(signers...) {
+		return errors.New("nonces: generated for another signer set")
+	}
+	for _, j := range signers {
+		D, E := n.hidingCommitments.Points[j], n.bindingCommitments.Points[j]
+		if D == nil || E == nil || D.IsIdentity() || E.IsIdentity() {
+			return fmt.Errorf("nonces: missing commitments of %s", j)
+		}
+	}
+	if len(n.hidingCommitments.Points) != len(signers) || len(n.bindingCommitments.Points) != len(signers) {
+		return errors.New("nonces: commitments of parties outside the signer set")
+	}
+	if !n.hidingNonce.ActOnBase().Equal(n.hidingCommitments.Points[selfID]) ||
+		!n.bindingNonce.ActOnBase().Equal(n.bindingCommitments.Points[selfID]) {
+		return errors.New("nonces: commitments do not match the nonces")
+	}
+	return nil
+}
+
+// commitmentsHash returns the hash of the commitments of all the signers, in the order of signers.
+// It is used to derive the binding factors, and to check that all the signers use the same commitments.
+func (n *Nonces) commitmentsHash(signers party.IDSlice) ([]byte, error) {
+	h := hash.New()
+	for _, j := range signers {
+		if err := h.WriteAny(j, n.hidingCommitments.Points[j], n.bindingCommitments.Points[j]); err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(), nil
+}
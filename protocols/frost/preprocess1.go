@@ -0,0 +1,59 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"crypto/rand"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+)
+
+var _ round.Round = (*preprocess1)(nil)
+
+type preprocess1 struct {
+	*round.Helper
+}
+
+// VerifyMessage implements round.Round.
+func (preprocess1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (preprocess1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - sample dᵢ, eᵢ <- 𝔽
+// - broadcast Dᵢ = [dᵢ]⋅G, Eᵢ = [eᵢ]⋅G.
+func (r *preprocess1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	HidingNonce, HidingCommitment := sample.ScalarPointPair(rand.Reader, r.Group())
+	BindingNonce, BindingCommitment := sample.ScalarPointPair(rand.Reader, r.Group())
+
+	err := r.BroadcastMessage(out, &broadcast2{
+		HidingCommitment:  HidingCommitment,
+		BindingCommitment: BindingCommitment,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	return &preprocess2{
+		preprocess1:        r,
+		HidingNonce:        HidingNonce,
+		BindingNonce:       BindingNonce,
+		HidingCommitments:  map[party.ID]curve.Point{r.SelfID(): HidingCommitment},
+		BindingCommitments: map[party.ID]curve.Point{r.SelfID(): BindingCommitment},
+	}, nil
+}
+
+// MessageContent implements round.Round.
+func (preprocess1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (preprocess1) Number() round.Number { return 1 }
@@ -0,0 +1,90 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+)
+
+var _ round.Round = (*preprocess2)(nil)
+
+type preprocess2 struct {
+	*preprocess1
+
+	// HidingNonce = dᵢ
+	HidingNonce curve.Scalar
+	// BindingNonce = eᵢ
+	BindingNonce curve.Scalar
+
+	// HidingCommitments[j] = Dⱼ
+	HidingCommitments map[party.ID]curve.Point
+	// BindingCommitments[j] = Eⱼ
+	BindingCommitments map[party.ID]curve.Point
+}
+
+type broadcast2 struct {
+	round.NormalBroadcastContent
+	// HidingCommitment = Dᵢ
+	HidingCommitment curve.Point
+	// BindingCommitment = Eᵢ
+	BindingCommitment curve.Point
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - save Dⱼ, Eⱼ.
+func (r *preprocess2) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcast2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if body.HidingCommitment.IsIdentity() || body.BindingCommitment.IsIdentity() {
+		return round.ErrNilFields
+	}
+	r.HidingCommitments[msg.From] = body.HidingCommitment
+	r.BindingCommitments[msg.From] = body.BindingCommitment
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (preprocess2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (preprocess2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - output the nonces and the commitments of all the signers.
+func (r *preprocess2) Finalize(chan<- *round.Message) (round.Session, error) {
+	return r.ResultRound(&Nonces{
+		id:                 r.SelfID(),
+		signers:            r.PartyIDs().Copy(),
+		hidingNonce:        r.HidingNonce,
+		bindingNonce:       r.BindingNonce,
+		hidingCommitments:  party.NewPointMap(r.HidingCommitments),
+		bindingCommitments: party.NewPointMap(r.BindingCommitments),
+	}), nil
+}
+
+// MessageContent implements round.Round.
+func (preprocess2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast2) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *preprocess2) BroadcastContent() round.BroadcastContent {
+	return &broadcast2{
+		HidingCommitment:  r.Group().NewPoint(),
+		BindingCommitment: r.Group().NewPoint(),
+	}
+}
+
+// Number implements round.Round.
+func (preprocess2) Number() round.Number { return 2 }
@@ -0,0 +1,117 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/BigInt"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/taproot"
+)
+
+var _ round.Round = (*sign1)(nil)
+
+type sign1 struct {
+	*round.Helper
+
+	// PublicKey = P, the group key with an even y coordinate
+	PublicKey curve.Point
+	// PublicShares[j] = ±λⱼ⋅Xⱼ, the scaled public shares, which sum to P
+	PublicShares map[party.ID]curve.Point
+	// SecretShare = ±λᵢ⋅xᵢ
+	SecretShare curve.Scalar
+
+	Nonces  *Nonces
+	Message []byte
+}
+
+// VerifyMessage implements round.Round.
+func (sign1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (sign1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - compute the binding factors ρⱼ = H(P, B, m, j) where B is the list of commitments
+// - Rⱼ = Dⱼ + [ρⱼ]⋅Eⱼ, R = ∑ⱼ Rⱼ, negated if R has an odd y coordinate
+// - c = H_BIP0340/challenge(R|ₓ, P|ₓ, m)
+// - broadcast zᵢ = ±(dᵢ + ρᵢ⋅eᵢ) + c⋅λᵢ⋅xᵢ, and erase dᵢ, eᵢ.
+func (r *sign1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	// never compute a second share, it would reveal the secret share: the nonces are erased before they are used
+	HidingNonce, BindingNonce, err := r.Nonces.consume()
+	if err != nil {
+		return r.AbortRound(err), nil
+	}
+	group := r.Group()
+	signers := r.PartyIDs()
+	CommitmentsHash, err := r.Nonces.commitmentsHash(signers)
+	if err != nil {
+		return r, err
+	}
+	PublicKeyX := r.PublicKey.(*curve.Secp256k1Point).XBytes()
+	messageHash := sha256.Sum256(r.Message)
+
+	R := group.NewPoint()
+	RShares := make(map[party.ID]curve.Point, len(signers))
+	bindingFactors := make(map[party.ID]curve.Scalar, len(signers))
+	for _, j := range signers {
+		bindingFactors[j] = hashToScalar(group, taproot.TaggedHash("FROST/secp256k1/rho", PublicKeyX, CommitmentsHash, messageHash[:], []byte(j)))
+		RShares[j] = bindingFactors[j].Act(r.Nonces.bindingCommitments.Points[j]).Add(r.Nonces.hidingCommitments.Points[j])
+		R = R.Add(RShares[j])
+	}
+	if R.IsIdentity() {
+		return r.AbortRound(errors.New("nonce commitments sum to the identity")), nil
+	}
+
+	// kᵢ = dᵢ + ρᵢ⋅eᵢ
+	KShare := group.NewScalar().Set(bindingFactors[r.SelfID()]).Mul(BindingNonce).Add(HidingNonce)
+	// BIP-340 nonces have an even y coordinate, if R does not all the nonce shares are negated
+	if !R.(*curve.Secp256k1Point).HasEvenY() {
+		R = R.Negate()
+		KShare.Negate()
+		for j, RShare := range RShares {
+			RShares[j] = RShare.Negate()
+		}
+	}
+
+	RX := R.(*curve.Secp256k1Point).XBytes()
+	Challenge := hashToScalar(group, taproot.TaggedHash("BIP0340/challenge", RX, PublicKeyX, r.Message))
+
+	// zᵢ = kᵢ + c⋅λᵢ⋅xᵢ
+	SigShare := group.NewScalar().Set(Challenge).Mul(r.SecretShare).Add(KShare)
+	if err = r.BroadcastMessage(out, &broadcast2Sign{
+		SigShare:        SigShare,
+		CommitmentsHash: CommitmentsHash,
+	}); err != nil {
+		return r, err
+	}
+
+	return &sign2{
+		sign1:           r,
+		R:               R,
+		RShares:         RShares,
+		Challenge:       Challenge,
+		CommitmentsHash: CommitmentsHash,
+		SigShares:       map[party.ID]curve.Scalar{r.SelfID(): SigShare},
+	}, nil
+}
+
+// MessageContent implements round.Round.
+func (sign1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (sign1) Number() round.Number { return 1 }
+
+// hashToScalar interprets a 32 byte hash as a scalar, modulo the group order.
+func hashToScalar(group curve.Curve, h []byte) curve.Scalar {
+	return group.NewScalar().SetNat(new(BigInt.Nat).SetBytes(h))
+}
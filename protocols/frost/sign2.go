@@ -0,0 +1,123 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package frost
+
+import (
+	"bytes"
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/taproot"
+)
+
+var _ round.Round = (*sign2)(nil)
+
+type sign2 struct {
+	*sign1
+
+	// R = ±∑ⱼ Rⱼ, with an even y coordinate
+	R curve.Point
+	// RShares[j] = ±Rⱼ
+	RShares map[party.ID]curve.Point
+	// Challenge = c
+	Challenge curve.Scalar
+	// CommitmentsHash is the hash of the commitments used by this party
+	CommitmentsHash []byte
+
+	// SigShares[j] = zⱼ
+	SigShares map[party.ID]curve.Scalar
+}
+
+type broadcast2Sign struct {
+	round.NormalBroadcastContent
+	// SigShare = zᵢ
+	SigShare curve.Scalar
+	// CommitmentsHash is the hash of the commitments used by the sender, they must be the same for all the signers
+	CommitmentsHash []byte
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - check that the sender used the same commitments
+// - save zⱼ.
+func (r *sign2) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcast2Sign)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if body.SigShare == nil || body.SigShare.IsZero() {
+		return round.ErrNilFields
+	}
+	if !bytes.Equal(body.CommitmentsHash, r.CommitmentsHash) {
+		return errors.New("signer used different nonce commitments")
+	}
+	r.SigShares[msg.From] = body.SigShare
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (sign2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (sign2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - verify [zⱼ]⋅G = Rⱼ + [c]⋅Yⱼ for each signer, and abort naming the culprits otherwise
+// - output the BIP-340 signature (R|ₓ, z = ∑ⱼ zⱼ).
+func (r *sign2) Finalize(chan<- *round.Message) (round.Session, error) {
+	var culprits []party.ID
+	z := r.Group().NewScalar()
+	for _, j := range r.PartyIDs() {
+		SigShare, ok := r.SigShares[j]
+		if !ok {
+			culprits = append(culprits, j)
+			continue
+		}
+		expected := r.Challenge.Act(r.PublicShares[j]).Add(r.RShares[j])
+		if !SigShare.ActOnBase().Equal(expected) {
+			culprits = append(culprits, j)
+			continue
+		}
+		z.Add(SigShare)
+	}
+	if len(culprits) > 0 {
+		return r.AbortRound(errors.New("invalid signature shares"), culprits...), nil
+	}
+
+	zBytes, err := z.MarshalBinary()
+	if err != nil {
+		return r, err
+	}
+	signature := make(taproot.Signature, 0, taproot.SignatureLen)
+	signature = append(signature, r.R.(*curve.Secp256k1Point).XBytes()...)
+	signature = append(signature, zBytes...)
+
+	publicKey := taproot.PublicKey(r.PublicKey.(*curve.Secp256k1Point).XBytes())
+	if !publicKey.Verify(signature, r.Message) {
+		return r.AbortRound(errors.New("failed to validate signature")), nil
+	}
+	return r.ResultRound(signature), nil
+}
+
+// MessageContent implements round.Round.
+func (sign2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast2Sign) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *sign2) BroadcastContent() round.BroadcastContent {
+	return &broadcast2Sign{
+		SigShare: r.Group().NewScalar(),
+	}
+}
+
+// Number implements round.Round.
+func (sign2) Number() round.Number { return 2 }
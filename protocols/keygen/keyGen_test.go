@@ -1,12 +1,15 @@
 package keygen
 
 import (
+	"crypto/rand"
 	mrand "math/rand"
 	"testing"
 
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/internal/test"
 	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/mnemonic"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/protocols/config"
 	"github.com/fxamacker/cbor/v2"
@@ -55,6 +58,7 @@ func checkOutput(t *testing.T, rounds []round.Session) {
 		c2 := config.EmptyConfig(group)
 		err = c2.UnmarshalBinary(data)
 		assert.NoError(t, err, "failed to unmarshal new config", c.ID)
+		assert.Equal(t, c.Mnemonic, c2.Mnemonic, "mnemonic not the same", c.ID)
 	}
 }
 
@@ -91,6 +95,59 @@ func TestKeygen(t *testing.T) {
 	}
 	//the checkOutput function is called to verify the output of the protocol rounds
 	checkOutput(t, rounds)
+
+	// the mnemonics of all the parties recover the secret key
+	mnemonics := make([]string, 0, N)
+	for _, r := range rounds {
+		c := r.(*round.Output).Result.(*config.Config)
+		mnemonics = append(mnemonics, c.Mnemonic)
+	}
+	secret, err := mnemonic.Combine(group, mnemonics)
+	require.NoError(t, err)
+	assert.True(t, secret.ActOnBase().Equal(rounds[0].(*round.Output).Result.(*config.Config).PublicPoint()))
+}
+
+func TestImport(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	N := 2
+	partyIDs := test.PartyIDs(N)
+	dealer := partyIDs[0]
+	secret := sample.Scalar(rand.Reader, group)
+	publicKey := secret.ActOnBase()
+
+	rounds := make([]round.Session, 0, N)
+	for _, partyID := range partyIDs {
+		info := round.Info{
+			ProtocolID:       "cmp/import-test",
+			FinalRoundNumber: Rounds,
+			SelfID:           partyID,
+			PartyIDs:         partyIDs,
+			Threshold:        N - 1,
+			Group:            group,
+		}
+		var partySecret curve.Scalar
+		if partyID == dealer {
+			partySecret = secret
+		}
+		r, err := StartImport(info, pl, dealer, publicKey, partySecret)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	checkOutput(t, rounds)
+
+	// the imported key is shared, and the dealer's copy is erased
+	assert.True(t, publicKey.Equal(rounds[0].(*round.Output).Result.(*config.Config).PublicPoint()))
+	assert.True(t, secret.IsZero())
 }
 
 func TestRefresh(t *testing.T) {
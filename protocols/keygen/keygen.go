@@ -4,18 +4,18 @@ package keygen
 
 import (
 	"MPC_ECDSA/internal/round"
-	"MPC_ECDSA/pkg/BigInt"
+	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/logging"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/polynomial"
 	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/mnemonic"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/pkg/protocol"
 	"MPC_ECDSA/protocols/config"
 	"crypto/rand"
-	log "github.com/sirupsen/logrus"
-	"github.com/tyler-smith/go-bip32"
-	"github.com/tyler-smith/go-bip39"
+	"errors"
 )
 
 // Rounds represents the number of rounds
@@ -36,7 +36,7 @@ func Start(info round.Info, pl *pool.Pool, c *config.Config, useMnemonic bool) p
 			helper, err = round.NewSession(info, sessionID, pl, c)
 		}
 		if err != nil {
-			log.Errorf("keygen: %v", err)
+			logging.Default().WithField(logging.FieldProtocol, info.ProtocolID).Errorf("keygen: %v", err)
 			return nil, err
 		}
 
@@ -53,12 +53,22 @@ func Start(info round.Info, pl *pool.Pool, c *config.Config, useMnemonic bool) p
 				PreviousPublicSharesECDSA: PublicSharesECDSA, //公钥 私钥乘以基点
 				PreviousChainKey:          c.ChainKey,
 				VSSSecret:                 polynomial.NewPolynomial(group, helper.Threshold(), group.NewScalar()), // fᵢ(X) deg(fᵢ) = t, fᵢ(0) = 0
+				Mnemonic:                  c.Mnemonic,
+				EncryptedMnemonic:         c.EncryptedMnemonic,
 			}, nil
 		} else { // Generate keys without configuration
 			var VSSConstant curve.Scalar
+			var words string
 			if useMnemonic {
-				// Generate a 256-bit random value based on the mnemonic
-				VSSConstant = generateMnemonic(group)
+				// Generate a 256-bit random value based on the mnemonic, which is returned with the result as a backup
+				if words, err = mnemonic.Generate(); err != nil {
+					helper.Logger().Errorf("keygen: %v", err)
+					return nil, err
+				}
+				if VSSConstant, err = mnemonic.VSSConstant(group, words); err != nil {
+					helper.Logger().Errorf("keygen: %v", err)
+					return nil, err
+				}
 			} else {
 				// Generate a random 256-bit constant value
 				VSSConstant = sample.Scalar(rand.Reader, group)
@@ -69,28 +79,57 @@ func Start(info round.Info, pl *pool.Pool, c *config.Config, useMnemonic bool) p
 			return &round1{
 				Helper:    helper,    //The Helper field stores the helper object that provides essential functionality and information for the protocol execution.
 				VSSSecret: VSSSecret, // The VSSSecret field stores a polynomial object that plays a crucial role in the protocol
+				Mnemonic:  words,
 			}, nil
 		}
 	}
 }
 
-// generateMnemonic generates a mnemonic phrase, which is used for memorization or user-friendly seeds.
-// It follows the BIP-39 standard for mnemonic generation.
-func generateMnemonic(group curve.Curve) curve.Scalar {
-
-	// Generate a mnemonic for memorization or user-friendly seeds
-	//Generate a random entropy of 256 bits using the bip39.NewEntropy function.
-	entropy, _ := bip39.NewEntropy(256)
-	//Convert the entropy into a mnemonic phrase using the bip39.NewMnemonic function.
-	mnemonic, _ := bip39.NewMnemonic(entropy)
+// StartImport returns a function that starts a key generation session sharing an existing secret key,
+// so that the output config has PublicPoint() equal to publicKey.
+// The dealer gives the secret key, and the other parties give a nil secret.
+// Only the polynomial of the dealer has a non zero constant, equal to the secret key,
+// and the setup and proofs are the same as for Start.
+// The secret is erased (set to zero) once the dealer has sent its shares.
+func StartImport(info round.Info, pl *pool.Pool, dealer party.ID, publicKey curve.Point, secret curve.Scalar) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if publicKey == nil || publicKey.IsIdentity() {
+			return nil, errors.New("keygen: invalid public key to import")
+		}
+		if !party.NewIDSlice(info.PartyIDs).Contains(dealer) {
+			return nil, errors.New("keygen: dealer is not a party")
+		}
+		if (info.SelfID == dealer) != (secret != nil) {
+			return nil, errors.New("keygen: only the dealer gives the secret key")
+		}
+		if secret != nil && !secret.ActOnBase().Equal(publicKey) {
+			return nil, errors.New("keygen: secret key does not match the public key")
+		}
+		publicKeyBytes, err := publicKey.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		// bind the session to the imported key and its dealer
+		helper, err := round.NewSession(info, sessionID, pl, dealer, &hash.BytesWithDomain{
+			TheDomain: "Imported Public Key",
+			Bytes:     publicKeyBytes,
+		})
+		if err != nil {
+			logging.Default().WithField(logging.FieldProtocol, info.ProtocolID).Errorf("keygen: %v", err)
+			return nil, err
+		}
 
-	//Generate a seed from the mnemonic and an empty password using the bip39.NewSeed function.
-	seed := bip39.NewSeed(mnemonic, "")
-	//Create a master key from the seed using the bip32.NewMasterKey function.
-	masterKey, _ := bip32.NewMasterKey(seed)
-	//Creates a new BigInt.Nat instance n and sets its value by converting the byte slice masterKey.Key to a BigInt.Nat.
-	n := new(BigInt.Nat).SetBytes(masterKey.Key)
-	//Convert the master key to a scalar value suitable for the given curve group using the group.NewScalar function.
-	//The resulting scalar value, representing the master key, is returned by the function.
-	return group.NewScalar().SetNat(n)
+		group := helper.Group()
+		// fᵢ(0) = x for the dealer, and fᵢ(0) = 0 for the other parties
+		VSSConstant := group.NewScalar()
+		if secret != nil {
+			VSSConstant = secret
+		}
+		return &round1{
+			Helper:            helper,
+			VSSSecret:         polynomial.NewPolynomial(group, helper.Threshold(), VSSConstant),
+			ImportDealer:      dealer,
+			ImportedPublicKey: publicKey,
+		}, nil
+	}
 }
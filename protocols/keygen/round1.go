@@ -13,7 +13,6 @@ import (
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"crypto/rand"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round1)(nil)
@@ -43,6 +42,18 @@ type round1 struct {
 	// Keygen:  fᵢ(0) = xⁱ
 	// Refresh: fᵢ(0) = 0
 	VSSSecret *polynomial.Polynomial
+
+	// Mnemonic and EncryptedMnemonic back up the constant of the VSS polynomial, when it was derived from a mnemonic.
+	// Keygen:  the newly generated mnemonic
+	// Refresh: the previous backup, since the refresh does not change this party's contribution to the secret
+	Mnemonic          string
+	EncryptedMnemonic []byte
+
+	// ImportDealer and ImportedPublicKey are set when importing an existing key,
+	// in which case only the polynomial of the dealer has a non zero constant, the imported secret key.
+	// Keygen and Refresh: ImportedPublicKey = nil
+	ImportDealer      party.ID
+	ImportedPublicKey curve.Point
 }
 
 // VerifyMessage implements round.Round.
@@ -81,13 +92,13 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 	// Sample RIDᵢ
 	SelfRID, err := types.NewRID(rand.Reader)
 	if err != nil {
-		log.Errorln("failed to sample Rho")
+		round.Logger(r).Errorln("failed to sample Rho")
 		return r, errors.New("failed to sample Rho")
 	}
 	// Sample chainKey
 	chainKey, err := types.NewRID(rand.Reader)
 	if err != nil {
-		log.Errorln("failed to sample c")
+		round.Logger(r).Errorln("failed to sample c")
 		return r, errors.New("failed to sample c")
 	}
 
@@ -96,7 +107,7 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 		SelfRID, chainKey, SelfVSSPolynomial, SchnorrRand.Commitment(), ElGamalPublic,
 		SelfPedersenPublic.N(), SelfPedersenPublic.S(), SelfPedersenPublic.T())
 	if err != nil {
-		log.Errorln("failed to commit")
+		round.Logger(r).Errorln("failed to commit")
 		return r, errors.New("failed to commit")
 	}
 	//Create a broadcast message of type broadcast2
@@ -10,7 +10,6 @@ import (
 	"MPC_ECDSA/pkg/math/polynomial"
 	"MPC_ECDSA/pkg/party"
 	zksch "MPC_ECDSA/pkg/zk/sch"
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round2)(nil)
@@ -74,7 +73,7 @@ func (r *round2) StoreBroadcastMessage(msg round.Message) error {
 		return round.ErrInvalidContent
 	}
 	if err := body.Commitment.Validate(); err != nil {
-		log.Errorln("fail to validate commit")
+		round.Logger(r).Errorln("fail to validate commit")
 		return err
 	}
 	r.Commitments[msg.From] = body.Commitment
@@ -104,7 +103,7 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 		Decommitment:       r.Decommitment,
 	})
 	if err != nil {
-		log.Errorln("fail to broadcast")
+		round.Logger(r).Errorln("fail to broadcast")
 		return r, err
 	}
 	return &round3{
@@ -14,8 +14,6 @@ import (
 	zkprm "MPC_ECDSA/pkg/zk/prm"
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"errors"
-
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round3)(nil)
@@ -72,16 +70,16 @@ func (r *round3) StoreBroadcastMessage(msg round.Message) error {
 	}
 	// check RID length
 	if err := body.RID.Validate(); err != nil {
-		log.Errorln(err)
+		round.Logger(r).Errorln(err)
 		return err
 	}
 	if err := body.C.Validate(); err != nil {
-		log.Errorln(err)
+		round.Logger(r).Errorln(err)
 		return err
 	}
 	// check decommitment
 	if err := body.Decommitment.Validate(); err != nil {
-		log.Errorln(err)
+		round.Logger(r).Errorln(err)
 		return err
 	}
 
@@ -89,31 +87,38 @@ func (r *round3) StoreBroadcastMessage(msg round.Message) error {
 	VSSPolynomial := body.VSSPolynomial
 	// check that the constant coefficient is 0
 	// if refresh then the polynomial is constant
-	if !(r.VSSSecret.Constant().IsZero() == VSSPolynomial.IsConstant) {
-		log.Errorln("vss polynomial has incorrect constant")
+	// if import then only the dealer's constant is the imported public key
+	if r.ImportedPublicKey != nil {
+		if VSSPolynomial.IsConstant != (from != r.ImportDealer) ||
+			(from == r.ImportDealer && !VSSPolynomial.Constant().Equal(r.ImportedPublicKey)) {
+			round.Logger(r).Errorln("vss polynomial does not share the imported key")
+			return errors.New("vss polynomial does not share the imported key")
+		}
+	} else if !(r.VSSSecret.Constant().IsZero() == VSSPolynomial.IsConstant) {
+		round.Logger(r).Errorln("vss polynomial has incorrect constant")
 		return errors.New("vss polynomial has incorrect constant")
 	}
 	// check deg(Fⱼ) = t
 	if VSSPolynomial.Degree() != r.Threshold() {
-		log.Errorln("vss polynomial has incorrect degree")
+		round.Logger(r).Errorln("vss polynomial has incorrect degree")
 		return errors.New("vss polynomial has incorrect degree")
 	}
 
 	// Set Paillier
 	if err := paillier.ValidateN(body.N); err != nil {
-		log.Errorln(err)
+		round.Logger(r).Errorln(err)
 		return err
 	}
 
 	// Verify Pedersen
 	if err := pedersen.ValidateParameters(body.N, body.S, body.T); err != nil {
-		log.Errorln(err)
+		round.Logger(r).Errorln(err)
 		return err
 	}
 	// Verify decommit
 	if !r.HashForID(from).Decommit(r.Commitments[from], body.Decommitment,
 		body.RID, body.C, VSSPolynomial, body.SchnorrCommitments, body.ElGamalPublic, body.N, body.S, body.T) {
-		log.Errorln("failed to decommit")
+		round.Logger(r).Errorln("failed to decommit")
 		return errors.New("failed to decommit")
 	}
 	r.RIDs[from] = body.RID
@@ -201,6 +206,9 @@ func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
 		}
 	}
 
+	// the shares have been sent, so the secret polynomial is no longer needed
+	r.VSSSecret.Erase()
+
 	// Write rid to the hash state
 	r.UpdateHashState(rid)
 	return &round4{
@@ -13,8 +13,6 @@ import (
 	zkmod "MPC_ECDSA/pkg/zk/mod"
 	zkprm "MPC_ECDSA/pkg/zk/prm"
 	"MPC_ECDSA/protocols/config"
-
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round4)(nil)
@@ -52,13 +50,13 @@ func (r *round4) StoreBroadcastMessage(msg round.Message) error {
 
 	// verify zkmod
 	if !body.Mod.VerifyMal(zkmod.Public{N: r.NModulus[from]}, r.HashForID(from), r.Pool) {
-		log.Errorln("failed to validate mod proof")
+		round.Logger(r).Errorln("failed to validate mod proof")
 		return errors.New("failed to validate mod proof")
 	}
 
 	// verify zkprm
 	if !body.Prm.VerifyMal(zkprm.Public{N: r.NModulus[from], S: r.S[from], T: r.T[from]}, r.HashForID(from), r.Pool) {
-		log.Errorln("failed to validate prm proof")
+		round.Logger(r).Errorln("failed to validate prm proof")
 		return errors.New("failed to validate prm proof")
 	}
 	return nil
@@ -70,12 +68,12 @@ func (r *round4) StoreBroadcastMessage(msg round.Message) error {
 func (r *round4) VerifyMessage(msg round.Message) error {
 	body, ok := msg.Content.(*message4)
 	if !ok || body == nil {
-		log.Errorln("fail to get body")
+		round.Logger(r).Errorln("fail to get body")
 		return round.ErrInvalidContent
 	}
 
 	if !r.PaillierPublic[msg.To].ValidateCiphertexts(body.Share) {
-		log.Errorln("fail to ValidateCiphertexts")
+		round.Logger(r).Errorln("fail to ValidateCiphertexts")
 		return errors.New("invalid ciphertext")
 	}
 
@@ -178,6 +176,9 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 		RID:       r.RID.Copy(),
 		ChainKey:  r.ChainKey.Copy(),
 		Public:    PublicData,
+
+		Mnemonic:          r.Mnemonic,
+		EncryptedMnemonic: r.EncryptedMnemonic,
 	}
 
 	// write new ssid to hash, to bind the Schnorr proof to this new config
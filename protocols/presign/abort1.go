@@ -1,8 +1,6 @@
 package presign
 
 import (
-	"errors"
-
 	"MPC_ECDSA/internal/round"
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
 	"MPC_ECDSA/pkg/hash"
@@ -55,17 +53,17 @@ func (r *abort1) StoreBroadcastMessage(msg round.Message) error {
 	public := r.Paillier[from]
 	//Verify the validity of the k share
 	if !body.KProof.Verify(r.HashForID(from), public, r.K[from]) {
-		return errors.New("failed to verify validity of k")
+		return round.CheckFailed("nth", "failed to verify validity of k")
 	}
 
 	BigGammaShareActual := r.Group().NewScalar().SetNat(body.GammaShare.Mod1(r.Group().Order())).ActOnBase()
 	if !r.BigGammaShare[from].Equal(BigGammaShareActual) {
-		return errors.New("different BigGammaShare")
+		return round.CheckFailed("gamma", "different BigGammaShare")
 	}
 	//verify the validity of each delta Nth proof.
 	for id, deltaProof := range body.DeltaProofs {
 		if !deltaProof.Verify(r.HashForID(from), public, r.DeltaCiphertext[from][id]) {
-			return errors.New("failed to validate Delta MtA Nth proof")
+			return round.CheckFailed("nth", "failed to validate Delta MtA Nth proof")
 		}
 	}
 	return nil
@@ -106,7 +104,7 @@ func (r *abort1) Finalize(chan<- *round.Message) (round.Session, error) {
 			culprits = append(culprits, j)
 		}
 	}
-	return r.AbortRound(errors.New("abort1: detected culprit"), culprits...), nil
+	return r.AbortRound(round.CheckFailed("delta", "abort1: detected culprit"), culprits...), nil
 }
 
 // MessageContent implements round.Round.
@@ -1,8 +1,6 @@
 package presign
 
 import (
-	"errors"
-
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
@@ -50,17 +48,17 @@ func (r *abort2) StoreBroadcastMessage(msg round.Message) error {
 		X: r.ElGamal[from],
 		Y: body.YHat,
 	}) {
-		return errors.New("failed to verify YHat log proof")
+		return round.CheckFailed("log", "failed to verify YHat log proof")
 	}
 
 	public := r.Paillier[from]
 	if !body.KProof.Verify(r.HashForID(from), public, r.K[from]) {
-		return errors.New("failed to verify validity of k")
+		return round.CheckFailed("nth", "failed to verify validity of k")
 	}
 
 	for id, chiProof := range body.ChiProofs {
 		if !chiProof.Verify(r.HashForID(from), public, r.ChiCiphertext[from][id]) {
-			return errors.New("failed to validate Delta MtA Nth proof")
+			return round.CheckFailed("nth", "failed to validate Delta MtA Nth proof")
 		}
 	}
 	return nil
@@ -93,7 +91,7 @@ func (r *abort2) Finalize(chan<- *round.Message) (round.Session, error) {
 		}
 	}
 
-	return r.AbortRound(errors.New("abort2: detected culprit"), culprits...), nil
+	return r.AbortRound(round.CheckFailed("chi", "abort2: detected culprit"), culprits...), nil
 }
 
 // MessageContent implements round.Round.
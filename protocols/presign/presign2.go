@@ -13,7 +13,6 @@ import (
 	zkaffg "MPC_ECDSA/pkg/zk/affg"
 	zkaffp "MPC_ECDSA/pkg/zk/affp"
 	zkencelg "MPC_ECDSA/pkg/zk/encelg"
-	"errors"
 )
 
 var _ round.Round = (*presign2)(nil)
@@ -111,7 +110,7 @@ func (r *presign2) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate enc-elg proof for K")
+		return round.CheckFailed("encelg", "failed to validate enc-elg proof for K")
 	}
 	return nil
 }
@@ -1,9 +1,6 @@
 package presign
 
 import (
-	"errors"
-	"fmt"
-
 	"MPC_ECDSA/internal/elgamal"
 	"MPC_ECDSA/internal/round"
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
@@ -64,7 +61,7 @@ func (r *presign3) StoreBroadcastMessage(msg round.Message) error {
 		}
 		DeltaCiphertext, ChiCiphertext := body.DeltaCiphertext[id], body.ChiCiphertext[id]
 		if !r.Paillier[id].ValidateCiphertexts(DeltaCiphertext, ChiCiphertext) {
-			return errors.New("received invalid ciphertext")
+			return round.CheckFailed("ciphertext", "received invalid ciphertext")
 		}
 	}
 
@@ -92,7 +89,7 @@ func (r *presign3) VerifyMessage(msg round.Message) error {
 		Verifier: r.Paillier[to],
 		Aux:      r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate affp proof for Delta MtA")
+		return round.CheckFailed("affp", "failed to validate affp proof for Delta MtA")
 	}
 
 	if !body.ChiProof.VerifyMal(r.Group(), r.HashForID(from), zkaffg.Public{
@@ -104,7 +101,7 @@ func (r *presign3) VerifyMessage(msg round.Message) error {
 		Verifier: r.Paillier[to],
 		Aux:      r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate affg proof for Chi MtA")
+		return round.CheckFailed("affg", "failed to validate affg proof for Chi MtA")
 	}
 
 	return nil
@@ -163,7 +160,7 @@ func (r *presign3) Finalize(out chan<- *round.Message) (round.Session, error) {
 	}
 	//If any culprits are found, indicating decryption failures, the method aborts the round and returns an error.
 	if culprits != nil {
-		return r.AbortRound(fmt.Errorf("failed to decrypt alpha shares for mta"), culprits...), nil
+		return r.AbortRound(round.CheckFailed("decryption", "failed to decrypt alpha shares for mta"), culprits...), nil
 	}
 
 	// ElGamalChi = Ẑⱼ = (b̂ⱼ⋅G, χᵢ+b̂ⱼ⋅Yᵢ)
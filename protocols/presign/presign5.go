@@ -1,8 +1,6 @@
 package presign
 
 import (
-	"errors"
-
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
@@ -58,7 +56,7 @@ func (r *presign5) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate log* proof for BigGammaShare")
+		return round.CheckFailed("logstar", "failed to validate log* proof for BigGammaShare")
 	}
 
 	return nil
@@ -1,8 +1,6 @@
 package presign
 
 import (
-	"errors"
-
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
@@ -50,7 +48,7 @@ func (r *presign6) StoreBroadcastMessage(msg round.Message) error {
 		Base:          r.Gamma,
 		Y:             body.BigDeltaShare,
 	}) {
-		return errors.New("failed to validate elog proof for BigDeltaShare")
+		return round.CheckFailed("elog", "failed to validate elog proof for BigDeltaShare")
 	}
 
 	r.BigDeltaShares[from] = body.BigDeltaShare
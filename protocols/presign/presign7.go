@@ -1,8 +1,6 @@
 package presign
 
 import (
-	"errors"
-
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/internal/types"
 	"MPC_ECDSA/pkg/ecdsa"
@@ -60,7 +58,7 @@ func (r *presign7) StoreBroadcastMessage(msg round.Message) error {
 		return err
 	}
 	if !r.HashForID(from).Decommit(r.CommitmentID[from], body.DecommitmentID, body.PresignatureID) {
-		return errors.New("failed to decommit presignature ID")
+		return round.CheckFailed("commitment", "failed to decommit presignature ID")
 	}
 
 	if !body.Proof.VerifyMal(r.Group(), r.HashForID(from), zkelog.Public{
@@ -69,7 +67,7 @@ func (r *presign7) StoreBroadcastMessage(msg round.Message) error {
 		Base:          r.R,
 		Y:             body.S,
 	}) {
-		return errors.New("failed to validate elog proof for S")
+		return round.CheckFailed("elog", "failed to validate elog proof for S")
 	}
 	r.S[from] = body.S
 	r.PresignatureID[from] = body.PresignatureID
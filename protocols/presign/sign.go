@@ -6,6 +6,7 @@ import (
 
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/internal/types"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/ecdsa"
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
 	"MPC_ECDSA/pkg/hash"
@@ -13,17 +14,20 @@ import (
 	"MPC_ECDSA/pkg/math/polynomial"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pedersen"
+	"MPC_ECDSA/pkg/policy"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/pkg/protocol"
 	"MPC_ECDSA/protocols/config"
 )
 
 const (
-	protocolOfflineID                  = "cmp/presign-offline"
-	protocolOnlineID                   = "cmp/presign-online"
-	protocolFullID                     = "cmp/presign-full"
-	protocolOfflineRounds round.Number = 7
-	protocolFullRounds    round.Number = 8
+	protocolOfflineID                   = "cmp/presign-offline"
+	protocolOnlineID                    = "cmp/presign-online"
+	protocolFullID                      = "cmp/presign-full"
+	protocolOnlineBatchID               = "cmp/presign-online-batch"
+	protocolOfflineBatchID              = "cmp/presign-offline-batch"
+	protocolOfflineRounds  round.Number = 7
+	protocolFullRounds     round.Number = 8
 )
 
 // StartPresign function returns a protocol.StartFunc for the Presign protocol.
@@ -98,8 +102,56 @@ func StartPresign(c *config.Config, signers []party.ID, message []byte, pl *pool
 	}
 }
 
-func StartPresignOnline(c *config.Config, preSignature *ecdsa.PreSignature, message []byte, pl *pool.Pool) protocol.StartFunc {
+// StartPresignBatch returns a protocol.StartFunc generating count independent presignatures in a single session.
+// Each round, the messages of all the presignatures are sent together, and each presignature gets its own ID.
+// Returns []*ecdsa.PreSignature if successful.
+func StartPresignBatch(c *config.Config, signers []party.ID, count int, pl *pool.Pool) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
+		if c == nil {
+			return nil, errors.New("presign: config is nil")
+		}
+		info := round.Info{
+			ProtocolID:       protocolOfflineBatchID,
+			FinalRoundNumber: protocolOfflineRounds,
+			SelfID:           c.ID,
+			PartyIDs:         signers,
+			Threshold:        c.Threshold,
+			Group:            c.Group,
+		}
+		start := func(instanceID []byte, instancePool *pool.Pool) (round.Session, error) {
+			return StartPresign(c, signers, nil, instancePool)(instanceID)
+		}
+		collect := func(results []interface{}) interface{} {
+			preSignatures := make([]*ecdsa.PreSignature, len(results))
+			for l, result := range results {
+				preSignatures[l] = result.(*ecdsa.PreSignature)
+			}
+			return preSignatures
+		}
+		return round.NewBatch(info, sessionID, pl, count, start, collect)
+	}
+}
+
+// StartPresignOnline signs message with preSignature, the policies are evaluated as in StartPresignOnlineDigest.
+func StartPresignOnline(c *config.Config, preSignature *ecdsa.PreSignature, message []byte, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return startPresignOnline(c, preSignature, digest.Digest{Scheme: digest.Raw, Hash: message}, policy.All(policies...), pl)
+}
+
+// StartPresignOnlineDigest is StartPresignOnline for a digest of the data to sign, the digest scheme is bound to the session.
+// The policies are evaluated before the signature share is computed, the execution aborts if one of them does not allow it.
+func StartPresignOnlineDigest(c *config.Config, preSignature *ecdsa.PreSignature, d digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("sign.Create: %w", err)
+		}
+		return startPresignOnline(c, preSignature, d, policy.All(policies...), pl, types.DigestScheme(d.Scheme))(sessionID)
+	}
+}
+
+// startPresignOnline creates the first online round for the digest d, checked by p, with extra data bound to the session in aux.
+func startPresignOnline(c *config.Config, preSignature *ecdsa.PreSignature, d digest.Digest, p policy.Policy, pl *pool.Pool, aux ...hash.WriterToWithDomain) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		message := d.Hash
 		if c == nil || preSignature == nil {
 			return nil, errors.New("presign: config or preSignature is nil")
 		}
@@ -132,12 +184,14 @@ func StartPresignOnline(c *config.Config, preSignature *ecdsa.PreSignature, mess
 			info,
 			sessionID,
 			pl,
-			c,
-			hash.BytesWithDomain{
-				TheDomain: "PreSignatureID",
-				Bytes:     preSignature.ID,
-			},
-			types.SigningMessage(message),
+			append([]hash.WriterToWithDomain{
+				c,
+				hash.BytesWithDomain{
+					TheDomain: "PreSignatureID",
+					Bytes:     preSignature.ID,
+				},
+				types.SigningMessage(message),
+			}, aux...)...,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("sign.Create: %w", err)
@@ -147,7 +201,83 @@ func StartPresignOnline(c *config.Config, preSignature *ecdsa.PreSignature, mess
 			Helper:       helper,
 			PublicKey:    c.PublicPoint(),
 			Message:      message,
+			Scheme:       d.Scheme,
+			Policy:       p,
 			PreSignature: preSignature,
 		}, nil
 	}
 }
+
+// StartPresignOnlineBatch signs each digest with the presignature of the same index,
+// exchanging the signature shares of the whole batch in a single round.
+// Each digest must be exactly 32 bytes, and its scheme is bound to the session like in StartPresignOnlineDigest.
+// The policies are evaluated on every digest before any signature share is computed,
+// the execution aborts if one of them does not allow one of the digests.
+// All the presignatures must be distinct, and generated by the same signers.
+// Returns []*ecdsa.Signature if successful, or aborts with an *ecdsa.BatchError listing the culprits of each failed message.
+func StartPresignOnlineBatch(c *config.Config, preSignatures []*ecdsa.PreSignature, digests []digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if c == nil {
+			return nil, errors.New("presign: config is nil")
+		}
+		if len(preSignatures) == 0 || len(preSignatures) != len(digests) {
+			return nil, fmt.Errorf("sign.Create: expected one presignature per digest, found %d for %d digests", len(preSignatures), len(digests))
+		}
+		if preSignatures[0] == nil {
+			return nil, errors.New("presign: preSignature is nil")
+		}
+		signers := preSignatures[0].SignerIDs()
+		// each presignature is bound to the session together with its digest and the scheme of the digest
+		aux := []hash.WriterToWithDomain{c}
+		messages := make([][]byte, len(digests))
+		schemes := make([]digest.Scheme, len(digests))
+		seen := make(map[string]bool, len(preSignatures))
+		for i, preSignature := range preSignatures {
+			if preSignature == nil {
+				return nil, errors.New("presign: preSignature is nil")
+			}
+			if err := digests[i].Validate(); err != nil {
+				return nil, fmt.Errorf("sign.Create: digest %d: %w", i, err)
+			}
+			messages[i], schemes[i] = digests[i].Hash, digests[i].Scheme
+			if err := preSignature.Validate(); err != nil {
+				return nil, fmt.Errorf("sign.Create: presignature %d: %w", i, err)
+			}
+			if ids := preSignature.SignerIDs(); len(ids) != len(signers) || !signers.Contains(ids...) {
+				return nil, fmt.Errorf("sign.Create: presignature %d has different signers", i)
+			}
+			// signing two messages with the same presignature reveals the secret key
+			if seen[string(preSignature.ID)] {
+				return nil, fmt.Errorf("sign.Create: presignature %d is used twice", i)
+			}
+			seen[string(preSignature.ID)] = true
+			aux = append(aux, hash.BytesWithDomain{
+				TheDomain: "PreSignatureID",
+				Bytes:     preSignature.ID,
+			}, types.SigningMessage(messages[i]), types.DigestScheme(schemes[i]))
+		}
+		if !c.CanSign(signers) {
+			return nil, errors.New("sign.Create: signers is not a valid signing subset")
+		}
+		info := round.Info{
+			ProtocolID:       protocolOnlineBatchID,
+			FinalRoundNumber: protocolFullRounds,
+			SelfID:           c.ID,
+			PartyIDs:         signers,
+			Threshold:        c.Threshold,
+			Group:            c.Group,
+		}
+		helper, err := round.NewSession(info, sessionID, pl, aux...)
+		if err != nil {
+			return nil, fmt.Errorf("sign.Create: %w", err)
+		}
+		return &signBatch1{
+			Helper:        helper,
+			PublicKey:     c.PublicPoint(),
+			Messages:      messages,
+			Schemes:       schemes,
+			Policy:        policy.All(policies...),
+			PreSignatures: preSignatures,
+		}, nil
+	}
+}
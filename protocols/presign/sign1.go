@@ -1,10 +1,14 @@
 package presign
 
 import (
+	"time"
+
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/ecdsa"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
 )
 
 var _ round.Round = (*sign1)(nil)
@@ -15,6 +19,10 @@ type sign1 struct {
 	PublicKey curve.Point
 	// Message = m
 	Message []byte
+	// Scheme is the digest scheme of Message
+	Scheme digest.Scheme
+	// Policy decides whether the signature share is released
+	Policy policy.Policy
 	// PreSignature = (R, {R̄ⱼ,Sⱼ}ⱼ, kᵢ, χᵢ)
 	PreSignature *ecdsa.PreSignature
 }
@@ -27,6 +35,19 @@ func (r *sign1) StoreMessage(round.Message) error { return nil }
 
 // Finalize implements round.Round
 func (r *sign1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	// the local policy decides whether the signature share is released
+	if err := policy.Enforce(r.Policy, &policy.Request{
+		Protocol:       r.ProtocolID(),
+		SelfID:         r.SelfID(),
+		Signers:        r.PartyIDs(),
+		PublicKey:      r.PublicKey,
+		Digest:         digest.Digest{Scheme: r.Scheme, Hash: r.Message},
+		PresignatureID: r.PreSignature.ID,
+		Time:           time.Now(),
+	}); err != nil {
+		return r.AbortRound(err), nil
+	}
+
 	// σᵢ = kᵢm+rχᵢ (mod q)
 	SigmaShare := r.PreSignature.SignatureShare(r.Message)
 	//broadcast SigmaShare value to all other parties using the broadcastSign2 message.
@@ -0,0 +1,95 @@
+package presign
+
+import (
+	"fmt"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+)
+
+var _ round.Round = (*signBatch2)(nil)
+
+type signBatch2 struct {
+	*signBatch1
+	// SigmaShares[j][l] = σⱼₗ
+	SigmaShares map[party.ID][]curve.Scalar
+}
+
+type broadcastSignBatch2 struct {
+	round.NormalBroadcastContent
+	// Sigmas[l] = σᵢₗ
+	Sigmas []curve.Scalar
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - save σⱼₗ for every message.
+func (r *signBatch2) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcastSignBatch2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if len(body.Sigmas) != len(r.Messages) {
+		return fmt.Errorf("expected %d signature shares, found %d", len(r.Messages), len(body.Sigmas))
+	}
+	for _, sigma := range body.Sigmas {
+		if sigma == nil || sigma.IsZero() {
+			return round.ErrNilFields
+		}
+	}
+
+	r.SigmaShares[msg.From] = body.Sigmas
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (signBatch2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (signBatch2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - verify (rₗ,sₗ) for every message
+// - if one is invalid, find the culprits of each invalid signature.
+func (r *signBatch2) Finalize(chan<- *round.Message) (round.Session, error) {
+	signatures := make([]*ecdsa.Signature, len(r.Messages))
+	culprits := map[int][]party.ID{}
+	for l, preSignature := range r.PreSignatures {
+		shares := make(map[party.ID]ecdsa.SignatureShare, len(r.SigmaShares))
+		for j, sigmas := range r.SigmaShares {
+			shares[j] = sigmas[l]
+		}
+		signatures[l] = preSignature.Signature(shares)
+		if !signatures[l].Verify(r.PublicKey, r.Messages[l]) {
+			culprits[l] = party.NewIDSlice(preSignature.VerifySignatureShares(shares, r.Messages[l]))
+		}
+	}
+	if len(culprits) > 0 {
+		err := &ecdsa.BatchError{Culprits: culprits}
+		return r.AbortRound(err, err.AllCulprits()...), nil
+	}
+	return r.ResultRound(signatures), nil
+}
+
+// MessageContent implements round.Round.
+func (signBatch2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcastSignBatch2) RoundNumber() round.Number { return 8 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *signBatch2) BroadcastContent() round.BroadcastContent {
+	sigmas := make([]curve.Scalar, len(r.Messages))
+	for l := range sigmas {
+		sigmas[l] = r.Group().NewScalar()
+	}
+	return &broadcastSignBatch2{
+		Sigmas: sigmas,
+	}
+}
+
+// Number implements round.Round.
+func (signBatch2) Number() round.Number { return 8 }
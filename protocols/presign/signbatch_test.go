@@ -0,0 +1,249 @@
+package presign
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/internal/types"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/ecdsa"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
+	"MPC_ECDSA/pkg/pool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dealPreSignatures generates count presignatures for the configs, as a trusted dealer knowing the secret key would.
+func dealPreSignatures(t *testing.T, count int) map[party.ID][]*ecdsa.PreSignature {
+	lagrange := polynomial.Lagrange(group, partyIDs)
+	x := group.NewScalar()
+	for _, j := range partyIDs {
+		x.Add(group.NewScalar().Set(lagrange[j]).Mul(configs[j].ECDSA))
+	}
+	require.True(t, x.ActOnBase().Equal(configs[partyIDs[0]].PublicPoint()))
+
+	preSignatures := make(map[party.ID][]*ecdsa.PreSignature, len(partyIDs))
+	for l := 0; l < count; l++ {
+		id, err := types.NewRID(rand.Reader)
+		require.NoError(t, err)
+		k := sample.Scalar(rand.Reader, group)
+		kInv := group.NewScalar().Set(k).Invert()
+		R := kInv.ActOnBase()
+		// χ = x⋅k, shared additively as k
+		chi := group.NewScalar().Set(x).Mul(k)
+		kShares, chiShares := additiveShares(k), additiveShares(chi)
+
+		RBar := make(map[party.ID]curve.Point, len(partyIDs))
+		S := make(map[party.ID]curve.Point, len(partyIDs))
+		for _, j := range partyIDs {
+			RBar[j] = group.NewScalar().Set(kShares[j]).Mul(kInv).ActOnBase()
+			S[j] = chiShares[j].Act(R)
+		}
+		for _, j := range partyIDs {
+			preSignatures[j] = append(preSignatures[j], &ecdsa.PreSignature{
+				ID:       id,
+				R:        R,
+				RBar:     party.NewPointMap(RBar),
+				S:        party.NewPointMap(S),
+				KShare:   kShares[j],
+				ChiShare: chiShares[j],
+			})
+		}
+	}
+	return preSignatures
+}
+
+// additiveShares splits secret into random shares for partyIDs, which sum to it.
+func additiveShares(secret curve.Scalar) map[party.ID]curve.Scalar {
+	shares := make(map[party.ID]curve.Scalar, len(partyIDs))
+	sum := group.NewScalar()
+	for _, j := range partyIDs[1:] {
+		shares[j] = sample.Scalar(rand.Reader, group)
+		sum.Add(shares[j])
+	}
+	shares[partyIDs[0]] = group.NewScalar().Set(secret).Sub(sum)
+	return shares
+}
+
+// batchDigests returns the SHA-256 digests of count distinct messages.
+func batchDigests(t *testing.T, count int) []digest.Digest {
+	digests := make([]digest.Digest, count)
+	for l := range digests {
+		d, err := digest.Compute(digest.SHA256, []byte(fmt.Sprintf("withdrawal %d", l)))
+		require.NoError(t, err)
+		digests[l] = d
+	}
+	return digests
+}
+
+func TestSignBatch(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 5
+	preSignatures := dealPreSignatures(t, count)
+	messages := batchDigests(t, count)
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for _, j := range partyIDs {
+		r, err := StartPresignOnlineBatch(configs[j], preSignatures[j], messages, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		signatures, ok := r.(*round.Output).Result.([]*ecdsa.Signature)
+		require.True(t, ok, "result should be []*ecdsa.Signature")
+		require.Len(t, signatures, count)
+		for l, signature := range signatures {
+			assert.True(t, signature.Verify(configs[r.SelfID()].PublicPoint(), messages[l].Hash))
+		}
+	}
+
+	// a presignature can never sign two messages
+	c := configs[partyIDs[0]]
+	reused := []*ecdsa.PreSignature{preSignatures[partyIDs[0]][0], preSignatures[partyIDs[0]][0]}
+	_, err := StartPresignOnlineBatch(c, reused, batchDigests(t, 2), pl)(nil)
+	assert.Error(t, err)
+	_, err = StartPresignOnlineBatch(c, preSignatures[partyIDs[0]], batchDigests(t, count-1), pl)(nil)
+	assert.Error(t, err)
+	// a digest which is not 32 bytes is rejected instead of being truncated
+	truncated := batchDigests(t, count)
+	truncated[1].Hash = append(truncated[1].Hash, 0)
+	_, err = StartPresignOnlineBatch(c, preSignatures[partyIDs[0]], truncated, pl)(nil)
+	assert.Error(t, err)
+}
+
+// TestSignBatchPolicy checks that a party whose policy denies one of the messages aborts before it releases any signature share.
+func TestSignBatchPolicy(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 3
+	preSignatures := dealPreSignatures(t, count)
+	messages := batchDigests(t, count)
+	denied := messages[1]
+	deny := policy.Func(func(req *policy.Request) policy.Verdict {
+		if bytes.Equal(req.Digest.Hash, denied.Hash) {
+			return policy.Verdict{Decision: policy.Deny, Rule: "test", Reason: "denied"}
+		}
+		return policy.Verdict{Decision: policy.Allow}
+	})
+
+	c := configs[partyIDs[0]]
+	r, err := StartPresignOnlineBatch(c, preSignatures[partyIDs[0]], messages, pl, deny)(nil)
+	require.NoError(t, err)
+	out := make(chan *round.Message, len(partyIDs))
+	next, err := r.(round.Round).Finalize(out)
+	close(out)
+	require.NoError(t, err)
+	require.IsType(t, &round.Abort{}, next, "expected abort round")
+	assert.True(t, errors.Is(next.(*round.Abort).Err, policy.ErrDenied))
+	assert.Empty(t, out, "no signature share should be released")
+}
+
+// TestSignBatchCulprit corrupts the share of one party for one message, and checks that it is named for that message only.
+func TestSignBatchCulprit(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 3
+	preSignatures := dealPreSignatures(t, count)
+	messages := batchDigests(t, count)
+	culprit := partyIDs[1]
+	preSignatures[culprit][1].KShare = group.NewScalar().Set(preSignatures[culprit][1].KShare).Add(group.NewScalar().SetNat(oneNat))
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for _, j := range partyIDs {
+		r, err := StartPresignOnlineBatch(configs[j], preSignatures[j], messages, pl)(nil)
+		require.NoError(t, err)
+		rounds = append(rounds, r)
+	}
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	for _, r := range rounds {
+		require.IsType(t, &round.Abort{}, r, "expected abort round")
+		abort := r.(*round.Abort)
+		assert.Equal(t, []party.ID{culprit}, abort.Culprits)
+		var batchErr *ecdsa.BatchError
+		require.True(t, errors.As(abort.Err, &batchErr))
+		assert.Equal(t, map[int][]party.ID{1: {culprit}}, batchErr.Culprits)
+	}
+}
+
+// TestPresignBatch generates two presignatures in a single session, and signs a batch of messages with them.
+func TestPresignBatch(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 2
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for _, j := range partyIDs {
+		r, err := StartPresignBatch(configs[j], partyIDs, count, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	preSignatures := make(map[party.ID][]*ecdsa.PreSignature, len(partyIDs))
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		batch, ok := r.(*round.Output).Result.([]*ecdsa.PreSignature)
+		require.True(t, ok, "result should be []*ecdsa.PreSignature")
+		require.Len(t, batch, count)
+		preSignatures[r.SelfID()] = batch
+	}
+	// each presignature has its own ID, shared by all the parties
+	assert.NotEqual(t, preSignatures[partyIDs[0]][0].ID, preSignatures[partyIDs[0]][1].ID)
+	for _, j := range partyIDs {
+		for l := range preSignatures[j] {
+			require.NoError(t, preSignatures[j][l].Validate())
+			assert.Equal(t, preSignatures[partyIDs[0]][l].ID, preSignatures[j][l].ID)
+		}
+	}
+
+	messages := batchDigests(t, count)
+	rounds = rounds[:0]
+	for _, j := range partyIDs {
+		r, err := StartPresignOnlineBatch(configs[j], preSignatures[j], messages, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	for _, r := range rounds {
+		signatures := r.(*round.Output).Result.([]*ecdsa.Signature)
+		for l, signature := range signatures {
+			assert.True(t, signature.Verify(configs[r.SelfID()].PublicPoint(), messages[l].Hash))
+		}
+	}
+}
@@ -57,7 +57,7 @@ func (r *abort1) StoreBroadcastMessage(msg round.Message) error {
 			Aux:      r.Pedersen[to],
 		}) {
 			r.culprits = append(r.culprits, from)
-			return errors.New("failed to validate affg proof for Delta MtA")
+			return round.CheckFailed("affg", "failed to validate affg proof for Delta MtA")
 		}
 	}
 
@@ -68,7 +68,7 @@ func (r *abort1) StoreBroadcastMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 	}) {
 		r.culprits = append(r.culprits, from)
-		return errors.New("failed to validate Mul Proof for Hi")
+		return round.CheckFailed("mul", "failed to validate Mul Proof for Hi")
 	}
 
 	for to, DecProof := range body.deltaDecProofMap {
@@ -79,7 +79,7 @@ func (r *abort1) StoreBroadcastMessage(msg round.Message) error {
 			Aux:    r.Pedersen[to],
 		}) {
 			r.culprits = append(r.culprits, from)
-			return errors.New("failed to validate dec Proof for deltai")
+			return round.CheckFailed("dec", "failed to validate dec Proof for deltai")
 		}
 	}
 	return nil
@@ -52,7 +52,7 @@ func (r *abort2) StoreBroadcastMessage(msg round.Message) error {
 			Aux:      record.Pedersen[to],
 		}) {
 			r.culprits = append(r.culprits, from)
-			return errors.New("failed to validate affg proof for Delta MtA")
+			return round.CheckFailed("affg", "failed to validate affg proof for Delta MtA")
 		}
 	}
 	for to, MulProof := range body.MulProofMap {
@@ -64,7 +64,7 @@ func (r *abort2) StoreBroadcastMessage(msg round.Message) error {
 			Aux:      record.Pedersen[to],
 		}) {
 			r.culprits = append(r.culprits, from)
-			return errors.New("failed to validate Mul* Proof for Hhati")
+			return round.CheckFailed("mulstar", "failed to validate Mul* Proof for Hhati")
 		}
 	}
 
@@ -76,7 +76,7 @@ func (r *abort2) StoreBroadcastMessage(msg round.Message) error {
 			Aux:    record.Pedersen[to],
 		}) {
 			r.culprits = append(r.culprits, from)
-			return errors.New("failed to validate dec Proof for deltai")
+			return round.CheckFailed("dec", "failed to validate dec Proof for deltai")
 		}
 	}
 	return nil
@@ -18,7 +18,6 @@ import (
 	zkaffg "MPC_ECDSA/pkg/zk/affg"
 	zkenc "MPC_ECDSA/pkg/zk/enc"
 	zklogstar "MPC_ECDSA/pkg/zk/logstar"
-	"errors"
 )
 
 var _ round.Round = (*presign2)(nil)
@@ -112,7 +111,7 @@ func (r *presign2) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate enc proof for K")
+		return round.CheckFailed("enc", "failed to validate enc proof for K")
 	}
 	return nil
 }
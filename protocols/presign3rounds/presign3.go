@@ -15,8 +15,6 @@ import (
 	"MPC_ECDSA/pkg/party"
 	zkaffg "MPC_ECDSA/pkg/zk/affg"
 	zklogstar "MPC_ECDSA/pkg/zk/logstar"
-	"errors"
-	"fmt"
 )
 
 var _ round.Round = (*presign3)(nil)
@@ -92,7 +90,7 @@ func (r *presign3) StoreBroadcastMessage(msg round.Message) error {
 		}
 		DeltaCiphertext, ChiCiphertext := body.DeltaCiphertext[id], body.ChiCiphertext[id]
 		if !r.Paillier[id].ValidateCiphertexts(DeltaCiphertext, ChiCiphertext) {
-			return errors.New("received invalid ciphertext")
+			return round.CheckFailed("ciphertext", "received invalid ciphertext")
 		}
 	}
 
@@ -123,7 +121,7 @@ func (r *presign3) VerifyMessage(msg round.Message) error {
 		Aux:      r.Pedersen[to],
 	}
 	if !body.DeltaProof.VerifyMal(r.Group(), r.HashForID(from), zkPublic) {
-		return errors.New("failed to validate affg proof for Delta MtA")
+		return round.CheckFailed("affg", "failed to validate affg proof for Delta MtA")
 	}
 
 	if !body.ChiProof.VerifyMal(r.Group(), r.HashForID(from), zkaffg.Public{
@@ -135,7 +133,7 @@ func (r *presign3) VerifyMessage(msg round.Message) error {
 		Verifier: r.Paillier[to],
 		Aux:      r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate affg proof for Chi MtA")
+		return round.CheckFailed("affg", "failed to validate affg proof for Chi MtA")
 	}
 
 	if !body.ProofLog.VerifyMal(r.Group(), r.HashForID(from), zklogstar.Public{
@@ -144,7 +142,7 @@ func (r *presign3) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate log* proof for BigGammaShare")
+		return round.CheckFailed("logstar", "failed to validate log* proof for BigGammaShare")
 	}
 
 	return nil
@@ -234,7 +232,7 @@ func (r *presign3) Finalize(out chan<- *round.Message) (round.Session, error) {
 
 	//If any culprits are found, indicating decryption failures, the method aborts the round and returns an error.
 	if culprits != nil {
-		return r.AbortRound(fmt.Errorf("failed to decrypt alpha shares for mta"), culprits...), nil
+		return r.AbortRound(round.CheckFailed("decryption", "failed to decrypt alpha shares for mta"), culprits...), nil
 	}
 	msgs := make(map[party.ID]*message4, len(r.OtherPartyIDs()))
 	for _, j := range r.OtherPartyIDs() {
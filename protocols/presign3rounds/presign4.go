@@ -20,7 +20,6 @@ import (
 	zkdec "MPC_ECDSA/pkg/zk/dec"
 	zklogstar "MPC_ECDSA/pkg/zk/logstar"
 	zkmul "MPC_ECDSA/pkg/zk/mul"
-	"errors"
 )
 
 var _ round.Round = (*presign4)(nil)
@@ -74,7 +73,7 @@ func (r *presign4) StoreBroadcastMessage(msg round.Message) error {
 		return err
 	}
 	if !r.HashForID(from).Decommit(r.CommitmentID[from], body.DecommitmentID, body.PresignatureID) {
-		return errors.New("failed to decommit presignature ID")
+		return round.CheckFailed("commitment", "failed to decommit presignature ID")
 	}
 	r.PresignatureID[from] = body.PresignatureID
 	return nil
@@ -98,7 +97,7 @@ func (r *presign4) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate log* proof for BigDeltaShare")
+		return round.CheckFailed("logstar", "failed to validate log* proof for BigDeltaShare")
 	}
 
 	return nil
@@ -0,0 +1,83 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package presign3rounds
+
+import (
+	"fmt"
+	"time"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/ecdsa3rounds"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
+)
+
+var _ round.Round = (*signBatch1)(nil)
+
+type signBatch1 struct {
+	*round.Helper
+	// PublicKey = X
+	PublicKey curve.Point
+	// Messages[l] = mₗ
+	Messages [][]byte
+	// Schemes[l] is the digest scheme of mₗ
+	Schemes []digest.Scheme
+	// Policy decides whether the signature shares are released
+	Policy policy.Policy
+	// PreSignatures[l] is the presignature used for mₗ
+	PreSignatures []*ecdsa3rounds.PreSignature3
+}
+
+// VerifyMessage implements round.Round.
+func (r *signBatch1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (r *signBatch1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - broadcast σᵢₗ = kᵢₗmₗ+rₗχᵢₗ for every message of the batch.
+func (r *signBatch1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	// the local policy decides on every message before any signature share is released
+	for l, preSignature := range r.PreSignatures {
+		if err := policy.Enforce(r.Policy, &policy.Request{
+			Protocol:       r.ProtocolID(),
+			SelfID:         r.SelfID(),
+			Signers:        r.PartyIDs(),
+			PublicKey:      r.PublicKey,
+			Digest:         digest.Digest{Scheme: r.Schemes[l], Hash: r.Messages[l]},
+			PresignatureID: preSignature.ID,
+			Time:           time.Now(),
+		}); err != nil {
+			return r.AbortRound(fmt.Errorf("message %d: %w", l, err)), nil
+		}
+	}
+
+	SigmaShares := make([]curve.Scalar, len(r.Messages))
+	for l, preSignature := range r.PreSignatures {
+		SigmaShares[l] = preSignature.SignatureShare(r.Messages[l])
+	}
+	err := r.BroadcastMessage(out, &broadcastSignBatch2{
+		Sigmas: SigmaShares,
+	})
+	if err != nil {
+		return r, err
+	}
+
+	return &signBatch2{
+		signBatch1:  r,
+		SigmaShares: map[party.ID][]curve.Scalar{r.SelfID(): SigmaShares},
+	}, nil
+}
+
+// MessageContent implements round.Round.
+func (signBatch1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (signBatch1) Number() round.Number { return 1 }
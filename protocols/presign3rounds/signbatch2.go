@@ -0,0 +1,107 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package presign3rounds
+
+import (
+	"fmt"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/ecdsa3rounds"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+)
+
+var _ round.Round = (*signBatch2)(nil)
+
+type signBatch2 struct {
+	*signBatch1
+	// SigmaShares[j][l] = σⱼₗ
+	SigmaShares map[party.ID][]curve.Scalar
+}
+
+type broadcastSignBatch2 struct {
+	round.NormalBroadcastContent
+	// Sigmas[l] = σᵢₗ
+	Sigmas []curve.Scalar
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - save σⱼₗ for every message.
+func (r *signBatch2) StoreBroadcastMessage(msg round.Message) error {
+	body, ok := msg.Content.(*broadcastSignBatch2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+	if len(body.Sigmas) != len(r.Messages) {
+		return fmt.Errorf("expected %d signature shares, found %d", len(r.Messages), len(body.Sigmas))
+	}
+	for _, sigma := range body.Sigmas {
+		if sigma == nil || sigma.IsZero() {
+			return round.ErrNilFields
+		}
+	}
+
+	r.SigmaShares[msg.From] = body.Sigmas
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (signBatch2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (signBatch2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - verify (rₗ,sₗ) for every message
+// - if one is invalid, prove the correctness of its share as in sign2, so that abort2 finds the culprits.
+func (r *signBatch2) Finalize(out chan<- *round.Message) (round.Session, error) {
+	signatures := make([]*ecdsa3rounds.Signature, len(r.Messages))
+	for l, preSignature := range r.PreSignatures {
+		shares := make(map[party.ID]ecdsa3rounds.SignatureShare, len(r.SigmaShares))
+		for j, sigmas := range r.SigmaShares {
+			shares[j] = sigmas[l]
+		}
+		signatures[l] = preSignature.Signature(shares)
+		if !signatures[l].Verify(r.PublicKey, r.Messages[l]) {
+			// all the parties see the same shares, and pick the same message
+			single := &sign2{
+				sign1: &sign1{
+					Helper:       r.Helper,
+					PublicKey:    r.PublicKey,
+					Message:      r.Messages[l],
+					Scheme:       r.Schemes[l],
+					PreSignature: preSignature,
+				},
+				SigmaShares: shares,
+			}
+			return single.Finalize(out)
+		}
+	}
+	return r.ResultRound(signatures), nil
+}
+
+// MessageContent implements round.Round.
+func (signBatch2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcastSignBatch2) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *signBatch2) BroadcastContent() round.BroadcastContent {
+	sigmas := make([]curve.Scalar, len(r.Messages))
+	for l := range sigmas {
+		sigmas[l] = r.Group().NewScalar()
+	}
+	return &broadcastSignBatch2{
+		Sigmas: sigmas,
+	}
+}
+
+// Number implements round.Round.
+func (signBatch2) Number() round.Number { return 2 }
@@ -0,0 +1,147 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package presign3rounds
+
+import (
+	"fmt"
+	"testing"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/digest"
+	"MPC_ECDSA/pkg/ecdsa3rounds"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runToOutput processes the rounds until they all reach their output round.
+func runToOutput(t *testing.T, rounds []round.Session) {
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+	}
+}
+
+// batchDigests returns the SHA-256 digests of count distinct messages.
+func batchDigests(t *testing.T, count int) []digest.Digest {
+	digests := make([]digest.Digest, count)
+	for l := range digests {
+		d, err := digest.Compute(digest.SHA256, []byte(fmt.Sprintf("withdrawal %d", l)))
+		require.NoError(t, err)
+		digests[l] = d
+	}
+	return digests
+}
+
+// The TestSignBatch function generates presignatures offline, and signs a batch of messages with them.
+func TestSignBatch(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 2
+
+	preSignatures := make(map[party.ID][]*ecdsa3rounds.PreSignature3, N)
+	for l := 0; l < count; l++ {
+		rounds := make([]round.Session, 0, N)
+		for _, c := range configs {
+			r, err := StartPresign(c, partyIDs, nil, pl)(nil)
+			require.NoError(t, err)
+			rounds = append(rounds, r)
+		}
+		runToOutput(t, rounds)
+		for _, r := range rounds {
+			preSignature := r.(*round.Output).Result.(*ecdsa3rounds.PreSignature3)
+			preSignatures[r.SelfID()] = append(preSignatures[r.SelfID()], preSignature)
+		}
+	}
+
+	messages := batchDigests(t, count)
+	rounds := make([]round.Session, 0, N)
+	for id, c := range configs {
+		r, err := StartPresignOnlineBatch(c, partyIDs, preSignatures[id], messages, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	runToOutput(t, rounds)
+	for _, r := range rounds {
+		signatures, ok := r.(*round.Output).Result.([]*ecdsa3rounds.Signature)
+		require.True(t, ok, "result should be []*ecdsa3rounds.Signature")
+		require.Len(t, signatures, count)
+		for l, signature := range signatures {
+			assert.True(t, signature.Verify(configs[r.SelfID()].PublicPoint(), messages[l].Hash))
+		}
+	}
+
+	// a presignature can never sign two messages
+	id := partyIDs[0]
+	reused := []*ecdsa3rounds.PreSignature3{preSignatures[id][0], preSignatures[id][0]}
+	_, err := StartPresignOnlineBatch(configs[id], partyIDs, reused, messages, pl)(nil)
+	assert.Error(t, err)
+	// a digest which is not 32 bytes is rejected instead of being truncated
+	_, err = StartPresignOnlineBatch(configs[id], partyIDs, preSignatures[id], []digest.Digest{messages[0], {Scheme: digest.Raw, Hash: []byte("withdrawal 1")}}, pl)(nil)
+	assert.Error(t, err)
+}
+
+// The TestPresignBatch function generates several presignatures in a single session, and signs with all of them.
+func TestPresignBatch(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	count := 2
+
+	rounds := make([]round.Session, 0, N)
+	for _, c := range configs {
+		r, err := StartPresignBatch(c, partyIDs, count, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	runToOutput(t, rounds)
+
+	preSignatures := make(map[party.ID][]*ecdsa3rounds.PreSignature3, N)
+	for _, r := range rounds {
+		batch, ok := r.(*round.Output).Result.([]*ecdsa3rounds.PreSignature3)
+		require.True(t, ok, "result should be []*ecdsa3rounds.PreSignature3")
+		require.Len(t, batch, count)
+		for _, preSignature := range batch {
+			require.NoError(t, preSignature.Validate())
+		}
+		preSignatures[r.SelfID()] = batch
+	}
+	// each presignature has its own ID and nonce, shared by all the parties
+	first, second := preSignatures[partyIDs[0]][0], preSignatures[partyIDs[0]][1]
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.False(t, first.R.Equal(second.R))
+	for _, id := range partyIDs {
+		assert.Equal(t, first.ID, preSignatures[id][0].ID)
+		assert.Equal(t, second.ID, preSignatures[id][1].ID)
+	}
+
+	messages := batchDigests(t, count)
+	rounds = rounds[:0]
+	for id, c := range configs {
+		r, err := StartPresignOnlineBatch(c, partyIDs, preSignatures[id], messages, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	runToOutput(t, rounds)
+	for _, r := range rounds {
+		signatures := r.(*round.Output).Result.([]*ecdsa3rounds.Signature)
+		for l, signature := range signatures {
+			assert.True(t, signature.Verify(configs[r.SelfID()].PublicPoint(), messages[l].Hash))
+		}
+	}
+
+	_, err := StartPresignBatch(configs[partyIDs[0]], partyIDs, 0, pl)(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+// Package repair regenerates the key share of a party which lost it, without resharing the key.
+//
+// All the parties of the key take part, so that they all update the public data of the lost party, which only knows
+// the public key. A subset of at least Threshold+1 of the others, the helpers, contribute to the share,
+// the other parties only receive the new public data.
+//   - the lost party Pₗ generates a new Paillier key, Pedersen parameters and ElGamal key, with zkmod and zkprm proofs,
+//     and the helpers send it their view of the public data of the key.
+//   - each helper Pᵢ splits its Lagrange weighted share cᵢ = λᵢ(l)⋅xᵢ into random parts δᵢⱼ, one per helper,
+//     and encrypts δᵢⱼ to Pⱼ, committing to Δᵢⱼ = δᵢⱼ⋅G.
+//   - each helper Pⱼ encrypts σⱼ = ∑ᵢ δᵢⱼ to Pₗ, which sums them to xₗ = ∑ⱼ σⱼ = f(l).
+//
+// The lost party only learns xₗ, and each helper only learns blinded parts of the shares of the others.
+// The commitments identify a helper sending a wrong part.
+// The lost party outputs a new config for the same ID and public key, and the other parties update the public data of the lost party in theirs.
+package repair
+
+import (
+	"errors"
+	"fmt"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/hash"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/config"
+)
+
+const (
+	protocolID = "cmp/repair"
+	// Rounds is the number of rounds of the protocol.
+	Rounds round.Number = 4
+)
+
+// StartRepair returns the StartFunc of a party which holds config, and helps the party lost recover its share.
+// All the parties of the key take part, the helpers, which must not include lost, contribute to the share.
+// Returns *config.Config, with the new public data of lost, if successful.
+func StartRepair(c *config.Config, lost party.ID, helpers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if c == nil {
+			return nil, errors.New("repair: config is nil")
+		}
+		if c.ID == lost {
+			return nil, errors.New("repair: the lost party cannot help")
+		}
+		info := round.Info{
+			ProtocolID:       protocolID,
+			FinalRoundNumber: Rounds,
+			SelfID:           c.ID,
+			PartyIDs:         c.PartyIDs(),
+			Threshold:        c.Threshold,
+			Group:            c.Group,
+		}
+		return start(info, sessionID, pl, lost, helpers, c.PublicPoint(), c)
+	}
+}
+
+// StartRecover returns the StartFunc of the party selfID which lost its share of the key publicKey, shared between partyIDs,
+// recovered with the given helpers. The public data of the other parties is learnt from the messages they send.
+// Returns *config.Config with the same share as before if successful.
+func StartRecover(group curve.Curve, selfID party.ID, partyIDs, helpers []party.ID, threshold int, publicKey curve.Point, pl *pool.Pool) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if publicKey == nil || publicKey.IsIdentity() {
+			return nil, errors.New("repair: invalid public key")
+		}
+		info := round.Info{
+			ProtocolID:       protocolID,
+			FinalRoundNumber: Rounds,
+			SelfID:           selfID,
+			PartyIDs:         partyIDs,
+			Threshold:        threshold,
+			Group:            group,
+		}
+		return start(info, sessionID, pl, selfID, helpers, publicKey, nil)
+	}
+}
+
+// start creates the first round, binding the session to the lost party, the helpers and the public key.
+func start(info round.Info, sessionID []byte, pl *pool.Pool, lost party.ID, helpers []party.ID, publicKey curve.Point, c *config.Config) (round.Session, error) {
+	publicKeyBytes, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("repair: %w", err)
+	}
+	helperIDs := party.NewIDSlice(helpers)
+	if !helperIDs.Valid() {
+		return nil, errors.New("repair: invalid helpers")
+	}
+	helper, err := round.NewSession(info, sessionID, pl, lost, helperIDs, &hash.BytesWithDomain{
+		TheDomain: "Public Key",
+		Bytes:     publicKeyBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("repair: %w", err)
+	}
+	if !helper.PartyIDs().Contains(lost) {
+		return nil, errors.New("repair: the lost party is not a party of the key")
+	}
+	if helperIDs.Contains(lost) {
+		return nil, errors.New("repair: the lost party cannot help")
+	}
+	if !helper.PartyIDs().Contains(helperIDs...) {
+		return nil, errors.New("repair: the helpers must be parties of the key")
+	}
+	// the helpers interpolate the share of the lost party, so they must be more than the threshold
+	if len(helperIDs) < helper.Threshold()+1 {
+		return nil, fmt.Errorf("repair: %d helpers cannot recover a share with threshold %d", len(helperIDs), helper.Threshold())
+	}
+	return &round1{
+		Helper:    helper,
+		Lost:      lost,
+		Helpers:   helperIDs,
+		PublicKey: publicKey,
+		Config:    c,
+	}, nil
+}
@@ -0,0 +1,114 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package repair
+
+import (
+	mrand "math/rand"
+	"testing"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pool"
+	"MPC_ECDSA/protocols/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var group = curve.Secp256k1{}
+
+func TestRepair(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	N, T := 4, 2
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	runRepair(t, configs, partyIDs[0], partyIDs[1:], pl)
+}
+
+// TestRepairSubset recovers a share with Threshold+1 helpers, while another party of the key only receives the new public data.
+func TestRepairSubset(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+
+	N, T := 5, 2
+	configs, partyIDs := test.GenerateConfig(group, N, T, mrand.New(mrand.NewSource(1)), pl)
+	lost, receiver := partyIDs[0], partyIDs[N-1]
+	newConfigs := runRepair(t, configs, lost, partyIDs[1:N-1], pl)
+	repaired := newConfigs[lost]
+	require.Len(t, repaired.Public, N)
+	assert.True(t, repaired.Public[receiver].ECDSA.Equal(configs[receiver].Public[receiver].ECDSA))
+	assert.True(t, repaired.Public[receiver].Paillier.Equal(configs[receiver].Paillier.PublicKey))
+
+	// the party which did not contribute updated the public data of lost, and can sign with it
+	updated := newConfigs[receiver]
+	assert.True(t, updated.ECDSA.Equal(configs[receiver].ECDSA))
+	assert.True(t, updated.Public[lost].Paillier.Equal(repaired.Paillier.PublicKey))
+	assert.True(t, updated.Public[lost].Pedersen.N().Eq(repaired.Paillier.PublicKey.N()) == 1)
+	assert.False(t, updated.Public[lost].Paillier.Equal(configs[receiver].Public[lost].Paillier))
+
+	// Threshold helpers are not enough
+	_, err := StartRepair(configs[partyIDs[1]], lost, partyIDs[1:T+1], pl)(nil)
+	assert.Error(t, err)
+	_, err = StartRecover(group, lost, partyIDs, partyIDs[1:T+1], T, configs[lost].PublicPoint(), pl)(nil)
+	assert.Error(t, err)
+	// the lost party cannot help
+	_, err = StartRepair(configs[partyIDs[1]], lost, partyIDs, pl)(nil)
+	assert.Error(t, err)
+}
+
+// runRepair recovers the share of lost with the helpers, among all the parties of the key, checks the new configs and returns them.
+func runRepair(t *testing.T, configs map[party.ID]*config.Config, lost party.ID, helpers []party.ID, pl *pool.Pool) map[party.ID]*config.Config {
+	T := configs[lost].Threshold
+	publicKey := configs[lost].PublicPoint()
+	partyIDs := configs[lost].PartyIDs()
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	r, err := StartRecover(group, lost, partyIDs, helpers, T, publicKey, pl)(nil)
+	require.NoError(t, err, "round creation should not result in an error")
+	rounds = append(rounds, r)
+	for _, id := range partyIDs.Remove(lost) {
+		r, err = StartRepair(configs[id], lost, helpers, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	newConfigs := make(map[party.ID]*config.Config, len(rounds))
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r)
+		require.IsType(t, &config.Config{}, r.(*round.Output).Result)
+		c := r.(*round.Output).Result.(*config.Config)
+		data, err := c.MarshalBinary()
+		require.NoError(t, err)
+		c2 := config.EmptyConfig(group)
+		require.NoError(t, c2.UnmarshalBinary(data), "failed to unmarshal new config", c.ID)
+		newConfigs[c2.ID] = c2
+	}
+
+	require.Len(t, newConfigs, len(partyIDs))
+	repaired := newConfigs[lost]
+	require.Equal(t, lost, repaired.ID)
+	assert.True(t, repaired.ECDSA.Equal(configs[lost].ECDSA), "the share should be the same")
+	assert.False(t, repaired.Paillier.PublicKey.Equal(configs[lost].Paillier.PublicKey), "the Paillier key should be new")
+	for _, c := range newConfigs {
+		assert.True(t, publicKey.Equal(c.PublicPoint()))
+		assert.Equal(t, configs[lost].RID, c.RID)
+		assert.True(t, c.Public[lost].Paillier.Equal(repaired.Paillier.PublicKey), "public data of the lost party not updated", c.ID)
+		assert.True(t, c.Public[lost].ElGamal.Equal(repaired.ElGamal.ActOnBase()), "public data of the lost party not updated", c.ID)
+	}
+	return newConfigs
+}
@@ -0,0 +1,170 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package repair
+
+import (
+	"bytes"
+	"crypto/rand"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/types"
+	"MPC_ECDSA/pkg/BigInt"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+	zkmod "MPC_ECDSA/pkg/zk/mod"
+	zkprm "MPC_ECDSA/pkg/zk/prm"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round1)(nil)
+
+type round1 struct {
+	*round.Helper
+
+	// Lost is the ID of the party recovering its share
+	Lost party.ID
+	// Helpers are the parties contributing to the share, the other parties only receive the new public data
+	Helpers party.IDSlice
+	// PublicKey is the public key of the config
+	PublicKey curve.Point
+	// Config of a helper, nil for the lost party
+	Config *config.Config
+}
+
+// publicView is the public data of the key, as seen by a helper.
+type publicView struct {
+	Threshold     int
+	RID, ChainKey types.RID
+	ECDSA         *party.PointMap
+	ElGamal       *party.PointMap
+	N, S, T       map[party.ID]*BigInt.Nat
+}
+
+// VerifyMessage implements round.Round.
+func (r *round1) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (r *round1) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - lost party: sample Paillier Nₗ, Pedersen sₗ, tₗ and ElGamal Yₗ, and prove Nₗ, sₗ, tₗ with zkmod and zkprm
+// - other parties: send their view of the public data.
+func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
+	if r.Config != nil {
+		view := newPublicView(r.Config)
+		if err := r.BroadcastMessage(out, &broadcast2{View: view}); err != nil {
+			return r, err
+		}
+		return &round2{
+			round1: r,
+			Views:  map[party.ID]*publicView{r.SelfID(): view},
+		}, nil
+	}
+
+	PaillierSecret := paillier.NewSecretKey(nil)
+	PedersenPublic, PedersenSecret := PaillierSecret.GeneratePedersen()
+	ElGamalSecret, ElGamalPublic := sample.ScalarPointPair(rand.Reader, r.Group())
+
+	h := r.HashForID(r.SelfID())
+	mod := zkmod.NewProofMal(h.Clone(), zkmod.Private{
+		P:   PaillierSecret.P(),
+		Q:   PaillierSecret.Q(),
+		Phi: PaillierSecret.Phi(),
+	}, zkmod.Public{N: PedersenPublic.N()}, r.Pool)
+	prm := zkprm.NewProofMal(zkprm.Private{
+		Lambda: PedersenSecret,
+		Phi:    PaillierSecret.Phi(),
+		P:      PaillierSecret.P(),
+		Q:      PaillierSecret.Q(),
+	}, h.Clone(), zkprm.Public{N: PedersenPublic.N(), S: PedersenPublic.S(), T: PedersenPublic.T()}, r.Pool)
+
+	if err := r.BroadcastMessage(out, &broadcast2{Lost: &lostData{
+		N:       PedersenPublic.N(),
+		S:       PedersenPublic.S(),
+		T:       PedersenPublic.T(),
+		ElGamal: ElGamalPublic,
+		Mod:     mod,
+		Prm:     prm,
+	}}); err != nil {
+		return r, err
+	}
+	return &round2{
+		round1:         r,
+		Views:          map[party.ID]*publicView{},
+		PaillierSecret: PaillierSecret,
+		ElGamalSecret:  ElGamalSecret,
+		LostPublic: &config.Public{
+			ElGamal:  ElGamalPublic,
+			Paillier: PaillierSecret.PublicKey,
+			Pedersen: PedersenPublic,
+		},
+	}, nil
+}
+
+// newPublicView returns the public data of c.
+func newPublicView(c *config.Config) *publicView {
+	ECDSA := make(map[party.ID]curve.Point, len(c.Public))
+	ElGamal := make(map[party.ID]curve.Point, len(c.Public))
+	N := make(map[party.ID]*BigInt.Nat, len(c.Public))
+	S := make(map[party.ID]*BigInt.Nat, len(c.Public))
+	T := make(map[party.ID]*BigInt.Nat, len(c.Public))
+	for id, public := range c.Public {
+		ECDSA[id] = public.ECDSA
+		ElGamal[id] = public.ElGamal
+		N[id] = public.Pedersen.N()
+		S[id] = public.Pedersen.S()
+		T[id] = public.Pedersen.T()
+	}
+	return &publicView{
+		Threshold: c.Threshold,
+		RID:       c.RID,
+		ChainKey:  c.ChainKey,
+		ECDSA:     party.NewPointMap(ECDSA),
+		ElGamal:   party.NewPointMap(ElGamal),
+		N:         N,
+		S:         S,
+		T:         T,
+	}
+}
+
+// equal returns true if v and other hold the same public data.
+func (v *publicView) equal(other *publicView) bool {
+	if v.Threshold != other.Threshold || !bytes.Equal(v.RID, other.RID) || !bytes.Equal(v.ChainKey, other.ChainKey) {
+		return false
+	}
+	if len(v.ECDSA.Points) != len(other.ECDSA.Points) || len(v.ElGamal.Points) != len(other.ElGamal.Points) ||
+		len(v.N) != len(other.N) || len(v.S) != len(other.S) || len(v.T) != len(other.T) {
+		return false
+	}
+	for id, point := range v.ECDSA.Points {
+		if q, ok := other.ECDSA.Points[id]; !ok || !point.Equal(q) {
+			return false
+		}
+	}
+	for id, point := range v.ElGamal.Points {
+		if q, ok := other.ElGamal.Points[id]; !ok || !point.Equal(q) {
+			return false
+		}
+	}
+	for _, nats := range [][2]map[party.ID]*BigInt.Nat{{v.N, other.N}, {v.S, other.S}, {v.T, other.T}} {
+		for id, n := range nats[0] {
+			if m, ok := nats[1][id]; !ok || n == nil || m == nil || n.Eq(m) != 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MessageContent implements round.Round.
+func (round1) MessageContent() round.Content { return nil }
+
+// Number implements round.Round.
+func (round1) Number() round.Number { return 1 }
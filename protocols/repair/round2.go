@@ -0,0 +1,245 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package repair
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/BigInt"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/math/sample"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/pedersen"
+	zkmod "MPC_ECDSA/pkg/zk/mod"
+	zkprm "MPC_ECDSA/pkg/zk/prm"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round2)(nil)
+
+type round2 struct {
+	*round1
+
+	// Views[j] is the public data sent by the party Pⱼ
+	Views map[party.ID]*publicView
+
+	// LostPublic is the new public data of the lost party, without its ECDSA share
+	LostPublic *config.Public
+
+	// PaillierSecret and ElGamalSecret are the new secrets of the lost party, nil for the other parties
+	PaillierSecret *paillier.SecretKey
+	ElGamalSecret  curve.Scalar
+}
+
+type broadcast2 struct {
+	round.ReliableBroadcastContent
+
+	// lost party: its new public data
+	Lost *lostData
+	// other parties: the public data of the key
+	View *publicView
+}
+
+// lostData is the new public data of the lost party: Nₗ, sₗ, tₗ, Yₗ and the proofs of Nₗ, sₗ, tₗ.
+type lostData struct {
+	N, S, T *BigInt.Nat
+	ElGamal curve.Point
+	Mod     *zkmod.Proofbuf
+	Prm     *zkprm.Proofbuf
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - lost party: verify Nₗ, sₗ, tₗ and their proofs, save Nₗ, sₗ, tₗ, Yₗ
+// - other parties: check the view is the same as its own, save the view.
+func (r *round2) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast2)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	if from != r.Lost {
+		if body.View == nil || body.View.ECDSA == nil || body.View.ElGamal == nil {
+			return round.ErrNilFields
+		}
+		if r.Config != nil && !body.View.equal(newPublicView(r.Config)) {
+			return errors.New("public data differs")
+		}
+		r.Views[from] = body.View
+		return nil
+	}
+
+	if body.Lost == nil {
+		return round.ErrNilFields
+	}
+	lost := body.Lost
+	if lost.N == nil || lost.S == nil || lost.T == nil || lost.ElGamal == nil || lost.Mod == nil || lost.Prm == nil {
+		return round.ErrNilFields
+	}
+	if lost.ElGamal.IsIdentity() {
+		return errors.New("ElGamal public key is identity")
+	}
+	if err := paillier.ValidateN(lost.N); err != nil {
+		return err
+	}
+	if err := pedersen.ValidateParameters(lost.N, lost.S, lost.T); err != nil {
+		return err
+	}
+	if !lost.Mod.VerifyMal(zkmod.Public{N: lost.N}, r.HashForID(from), r.Pool) {
+		return errors.New("failed to validate mod proof")
+	}
+	if !lost.Prm.VerifyMal(zkprm.Public{N: lost.N, S: lost.S, T: lost.T}, r.HashForID(from), r.Pool) {
+		return errors.New("failed to validate prm proof")
+	}
+	PaillierPublic := paillier.NewPublicKeyFromN(lost.N)
+	r.LostPublic = &config.Public{
+		ElGamal:  lost.ElGamal,
+		Paillier: PaillierPublic,
+		Pedersen: pedersen.New(PaillierPublic.Modulus(), lost.S, lost.T),
+	}
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round2) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round2) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - lost party: check all the other parties sent the same public data, for the expected public key
+// - helper: split cᵢ = λᵢ(l)⋅xᵢ into δᵢⱼ, and send Encⱼ(δᵢⱼ) and Δᵢⱼ = δᵢⱼ⋅G.
+func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
+	others := r.PartyIDs().Remove(r.Lost)
+	helpers := r.Helpers
+
+	var view *publicView
+	for _, j := range others {
+		if view == nil {
+			view = r.Views[j]
+		} else if !view.equal(r.Views[j]) {
+			return r.AbortRound(errors.New("parties sent different public data")), nil
+		}
+	}
+	public, err := view.config(r.Group(), r.PartyIDs())
+	if err != nil {
+		return r.AbortRound(err, others...), nil
+	}
+	if view.Threshold != r.Threshold() || !public.PublicPoint().Equal(r.PublicKey) {
+		return r.AbortRound(errors.New("public data does not match the public key"), others...), nil
+	}
+
+	nextRound := &round3{
+		round2:      r,
+		Public:      public.Public,
+		RID:         view.RID,
+		ChainKey:    view.ChainKey,
+		Commitments: map[party.ID]map[party.ID]curve.Point{},
+		Parts:       map[party.ID]curve.Scalar{},
+	}
+
+	if r.Config == nil || !helpers.Contains(r.SelfID()) {
+		if err = r.BroadcastMessage(out, &broadcast3{}); err != nil {
+			return r, err
+		}
+		return nextRound, nil
+	}
+
+	// cᵢ = λᵢ(l)⋅xᵢ
+	contribution := r.Group().NewScalar().Set(polynomial.LagrangeAt(r.Group(), helpers, r.Lost)[r.SelfID()]).Mul(r.Config.ECDSA)
+
+	// δᵢᵢ = cᵢ - ∑ⱼ≠ᵢ δᵢⱼ
+	own := contribution
+	Commitments := make(map[party.ID]curve.Point, len(helpers))
+	Parts := make(map[party.ID]*paillier.Ciphertext, len(helpers)-1)
+	for _, j := range helpers {
+		if j == r.SelfID() {
+			continue
+		}
+		part := sample.Scalar(rand.Reader, r.Group())
+		own.Sub(part)
+		Commitments[j] = part.ActOnBase()
+		Parts[j], _ = public.Public[j].Paillier.Enc(curve.MakeInt(part))
+	}
+	Commitments[r.SelfID()] = own.ActOnBase()
+
+	if err = r.BroadcastMessage(out, &broadcast3{
+		Commitments: party.NewPointMap(Commitments),
+		Parts:       Parts,
+	}); err != nil {
+		return r, err
+	}
+	nextRound.Parts[r.SelfID()] = own
+	nextRound.Commitments[r.SelfID()] = Commitments
+	return nextRound, nil
+}
+
+// config returns the public data of the view as a config, after checking it.
+// The view covers all the parties of the key, which must all be parties of the session.
+func (v *publicView) config(group curve.Curve, sessionIDs party.IDSlice) (*config.Config, error) {
+	partyIDs := make([]party.ID, 0, len(v.ECDSA.Points))
+	for j := range v.ECDSA.Points {
+		partyIDs = append(partyIDs, j)
+	}
+	keyIDs := party.NewIDSlice(partyIDs)
+	c := &config.Config{
+		Group:     group,
+		Threshold: v.Threshold,
+		RID:       v.RID,
+		ChainKey:  v.ChainKey,
+		Public:    make(map[party.ID]*config.Public, len(keyIDs)),
+	}
+	if len(keyIDs) != len(sessionIDs) || !keyIDs.Contains(sessionIDs...) || !config.ValidThreshold(v.Threshold, len(keyIDs)) {
+		return nil, errors.New("invalid public data")
+	}
+	for _, j := range keyIDs {
+		ECDSA, ElGamal := v.ECDSA.Points[j], v.ElGamal.Points[j]
+		N, S, T := v.N[j], v.S[j], v.T[j]
+		if ECDSA == nil || ElGamal == nil || N == nil || S == nil || T == nil {
+			return nil, errors.New("invalid public data")
+		}
+		if err := pedersen.ValidateParameters(N, S, T); err != nil {
+			return nil, err
+		}
+		PaillierPublic := paillier.NewPublicKeyFromN(N)
+		c.Public[j] = &config.Public{
+			ECDSA:    ECDSA,
+			ElGamal:  ElGamal,
+			Paillier: PaillierPublic,
+			Pedersen: pedersen.New(PaillierPublic.Modulus(), S, T),
+		}
+	}
+	return c, nil
+}
+
+// MessageContent implements round.Round.
+func (round2) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast2) RoundNumber() round.Number { return 2 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *round2) BroadcastContent() round.BroadcastContent {
+	return &broadcast2{
+		Lost: &lostData{
+			ElGamal: r.Group().NewPoint(),
+		},
+		View: &publicView{
+			ECDSA:   party.EmptyPointMap(r.Group()),
+			ElGamal: party.EmptyPointMap(r.Group()),
+		},
+	}
+}
+
+// Number implements round.Round.
+func (round2) Number() round.Number { return 2 }
@@ -0,0 +1,160 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package repair
+
+import (
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/internal/types"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/math/polynomial"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round3)(nil)
+
+type round3 struct {
+	*round2
+
+	// Public is the public data of the key, before the repair
+	Public map[party.ID]*config.Public
+	// RID and ChainKey of the key
+	RID, ChainKey types.RID
+
+	// Commitments[i][j] = Δᵢⱼ = δᵢⱼ⋅G
+	Commitments map[party.ID]map[party.ID]curve.Point
+	// Parts[i] = δᵢⱼ received by the helper Pⱼ
+	Parts map[party.ID]curve.Scalar
+}
+
+type broadcast3 struct {
+	round.NormalBroadcastContent
+
+	// Commitments[j] = Δᵢⱼ
+	Commitments *party.PointMap
+	// Parts[j] = Encⱼ(δᵢⱼ), for all helpers but Pᵢ
+	Parts map[party.ID]*paillier.Ciphertext
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - lost party and other parties which are not helpers: check they sent nothing
+// - check ∑ⱼ Δᵢⱼ = λᵢ(l)⋅Xᵢ
+// - helper Pⱼ: decrypt δᵢⱼ and check δᵢⱼ⋅G = Δᵢⱼ.
+func (r *round3) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast3)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	if !r.Helpers.Contains(from) {
+		if (body.Commitments != nil && len(body.Commitments.Points) > 0) || len(body.Parts) > 0 {
+			return round.ErrInvalidContent
+		}
+		return nil
+	}
+
+	if body.Commitments == nil || len(body.Commitments.Points) != len(r.Helpers) || len(body.Parts) != len(r.Helpers)-1 {
+		return round.ErrNilFields
+	}
+	// ∑ⱼ Δᵢⱼ = λᵢ(l)⋅Xᵢ
+	sum := r.Group().NewPoint()
+	for _, j := range r.Helpers {
+		commitment, ok := body.Commitments.Points[j]
+		if !ok {
+			return round.ErrNilFields
+		}
+		sum = sum.Add(commitment)
+	}
+	lagrange := polynomial.LagrangeAt(r.Group(), r.Helpers, r.Lost)[from]
+	if !sum.Equal(lagrange.Act(r.Public[from].ECDSA)) {
+		return errors.New("commitments do not match the public share")
+	}
+
+	if r.Config != nil && r.Helpers.Contains(r.SelfID()) {
+		ciphertext, ok := body.Parts[r.SelfID()]
+		if !ok || !r.Config.Paillier.PublicKey.ValidateCiphertexts(ciphertext) {
+			return errors.New("invalid ciphertext")
+		}
+		part, err := decrypt(r.Config.Paillier, r.Group(), ciphertext)
+		if err != nil {
+			return err
+		}
+		if !part.ActOnBase().Equal(body.Commitments.Points[r.SelfID()]) {
+			return errors.New("part does not match its commitment")
+		}
+		r.Parts[from] = part
+	}
+	r.Commitments[from] = body.Commitments.Points
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round3) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round3) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - helper Pⱼ: send Encₗ(σⱼ) where σⱼ = ∑ᵢ δᵢⱼ.
+func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
+	nextRound := &round4{
+		round3: r,
+		Sums:   map[party.ID]curve.Scalar{},
+	}
+	if r.Config == nil || !r.Helpers.Contains(r.SelfID()) {
+		if err := r.BroadcastMessage(out, &broadcast4{}); err != nil {
+			return r, err
+		}
+		return nextRound, nil
+	}
+
+	// σⱼ = ∑ᵢ δᵢⱼ
+	sum := r.Group().NewScalar()
+	for _, i := range r.Helpers {
+		sum.Add(r.Parts[i])
+	}
+	Sum, _ := r.LostPublic.Paillier.Enc(curve.MakeInt(sum))
+	if err := r.BroadcastMessage(out, &broadcast4{Sum: Sum}); err != nil {
+		return r, err
+	}
+	return nextRound, nil
+}
+
+// decrypt decrypts a scalar encrypted with Paillier, checking that it did not overflow.
+func decrypt(sk *paillier.SecretKey, group curve.Curve, ciphertext *paillier.Ciphertext) (curve.Scalar, error) {
+	decrypted, err := sk.Dec(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	scalar := group.NewScalar().SetNat(decrypted.Mod1(group.Order()))
+	if decrypted.Eq(curve.MakeInt(scalar)) != 1 {
+		return nil, errors.New("decrypted scalar is not in correct range")
+	}
+	return scalar, nil
+}
+
+// MessageContent implements round.Round.
+func (round3) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast3) RoundNumber() round.Number { return 3 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (r *round3) BroadcastContent() round.BroadcastContent {
+	return &broadcast3{
+		Commitments: party.EmptyPointMap(r.Group()),
+	}
+}
+
+// Number implements round.Round.
+func (round3) Number() round.Number { return 3 }
@@ -0,0 +1,137 @@
+// Copyright © 2023 Antalpha
+//
+// This file is part of Antalpha. The full Antalpha copyright notice, including
+// terms governing use, modification, and redistribution, is contained in the
+// file LICENSE at the root of the source code distribution tree.
+
+package repair
+
+import (
+	"errors"
+
+	"MPC_ECDSA/internal/round"
+	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/protocols/config"
+)
+
+var _ round.Round = (*round4)(nil)
+
+type round4 struct {
+	*round3
+
+	// Sums[j] = σⱼ received by the lost party
+	Sums map[party.ID]curve.Scalar
+}
+
+type broadcast4 struct {
+	round.NormalBroadcastContent
+
+	// Sum = Encₗ(σⱼ)
+	Sum *paillier.Ciphertext
+}
+
+// StoreBroadcastMessage implements round.BroadcastRound.
+//
+// - lost party: decrypt σⱼ and check σⱼ⋅G = ∑ᵢ Δᵢⱼ.
+func (r *round4) StoreBroadcastMessage(msg round.Message) error {
+	from := msg.From
+	body, ok := msg.Content.(*broadcast4)
+	if !ok || body == nil {
+		return round.ErrInvalidContent
+	}
+
+	if !r.Helpers.Contains(from) {
+		if body.Sum != nil {
+			return round.ErrInvalidContent
+		}
+		return nil
+	}
+	if body.Sum == nil {
+		return round.ErrNilFields
+	}
+	if !r.LostPublic.Paillier.ValidateCiphertexts(body.Sum) {
+		return errors.New("invalid ciphertext")
+	}
+	if r.Config != nil {
+		return nil
+	}
+
+	sum, err := decrypt(r.PaillierSecret, r.Group(), body.Sum)
+	if err != nil {
+		return err
+	}
+	// σⱼ⋅G = ∑ᵢ Δᵢⱼ
+	expected := r.Group().NewPoint()
+	for _, i := range r.Helpers {
+		expected = expected.Add(r.Commitments[i][from])
+	}
+	if !sum.ActOnBase().Equal(expected) {
+		return errors.New("sum does not match the commitments")
+	}
+	r.Sums[from] = sum
+	return nil
+}
+
+// VerifyMessage implements round.Round.
+func (round4) VerifyMessage(round.Message) error { return nil }
+
+// StoreMessage implements round.Round.
+func (round4) StoreMessage(round.Message) error { return nil }
+
+// Finalize implements round.Round
+//
+// - lost party: xₗ = ∑ⱼ σⱼ, check xₗ⋅G = Xₗ
+// - output the config with the new public data of the lost party.
+func (r *round4) Finalize(chan<- *round.Message) (round.Session, error) {
+	Public := make(map[party.ID]*config.Public, len(r.Public))
+	for j, public := range r.Public {
+		Public[j] = public
+	}
+	// the ECDSA share of the lost party does not change
+	Public[r.Lost] = &config.Public{
+		ECDSA:    r.Public[r.Lost].ECDSA,
+		ElGamal:  r.LostPublic.ElGamal,
+		Paillier: r.LostPublic.Paillier,
+		Pedersen: r.LostPublic.Pedersen,
+	}
+
+	if r.Config != nil {
+		updated := *r.Config
+		updated.Public = Public
+		return r.ResultRound(&updated), nil
+	}
+
+	// xₗ = ∑ⱼ σⱼ
+	ECDSA := r.Group().NewScalar()
+	for _, j := range r.Helpers {
+		ECDSA.Add(r.Sums[j])
+	}
+	if !ECDSA.ActOnBase().Equal(Public[r.Lost].ECDSA) {
+		return r.AbortRound(errors.New("recovered share does not match the public share")), nil
+	}
+	return r.ResultRound(&config.Config{
+		Group:     r.Group(),
+		ID:        r.SelfID(),
+		Threshold: r.Threshold(),
+		ECDSA:     ECDSA,
+		ElGamal:   r.ElGamalSecret,
+		Paillier:  r.PaillierSecret,
+		RID:       r.RID.Copy(),
+		ChainKey:  r.ChainKey.Copy(),
+		Public:    Public,
+	}), nil
+}
+
+// MessageContent implements round.Round.
+func (round4) MessageContent() round.Content { return nil }
+
+// RoundNumber implements round.Content.
+func (broadcast4) RoundNumber() round.Number { return 4 }
+
+// BroadcastContent implements round.BroadcastRound.
+func (round4) BroadcastContent() round.BroadcastContent { return &broadcast4{} }
+
+// Number implements round.Round.
+func (round4) Number() round.Number { return 4 }
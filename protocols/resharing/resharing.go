@@ -8,9 +8,9 @@ package resharing
 
 import (
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/logging"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/pkg/protocol"
-	log "github.com/sirupsen/logrus"
 )
 
 const Rounds round.Number = 7
@@ -21,7 +21,7 @@ func Start(info round.Info, pl *pool.Pool) protocol.StartFunc {
 		var helper *round.Helper
 		helper, err = round.NewSession(info, sessionID, pl)
 		if err != nil {
-			log.Errorf("resharing: %v", err)
+			logging.Default().WithField(logging.FieldProtocol, info.ProtocolID).Errorf("resharing: %v", err)
 			return nil, err
 		}
 		return &round1{
@@ -15,7 +15,6 @@ import (
 	"errors"
 
 	"MPC_ECDSA/pkg/math/polynomial"
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round1)(nil)
@@ -58,7 +57,7 @@ func (r *round1) allNewOK() {
 
 // Finalize function is used to execute the first round of the key resharing protocol. New parties return directly to the next round, and old parties execute this round.
 func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round 1 Finalize ")
+	round.Logger(r).Debugln("initiates Finalize")
 	//check if all parties have stored their messages from the previous round
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -84,7 +83,7 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 		//get the keygen result
 		KeyGenResult, ok := r.Info.KeyGenConfig.(*config.Config)
 		if !ok {
-			log.Println("fail to get keygenconfig")
+			round.Logger(r).Errorln("fail to get keygenconfig")
 		}
 		group := r.Info.Group
 		//calculate the Lagrange coefficient of all old parties
@@ -121,7 +120,7 @@ func (r *round1) Finalize(out chan<- *round.Message) (round.Session, error) {
 		//do the commitment for the VSSPolynomialsOldParty
 		SelfCommitment, SelfDecommitment, err := r.HashForID(r.SelfID()).Commit(VSSPolynomialsOldParty)
 		if err != nil {
-			log.Errorln("failed to commit")
+			round.Logger(r).Errorln("failed to commit")
 			return r, err
 		}
 		//send the publickey and commitment to the new parties
@@ -161,6 +160,18 @@ func (round1) MessageContent() round.Content { return nil }
 // Number implements round.Round.
 func (round1) Number() round.Number { return 1 }
 
+// ExpectedSenders implements round.Session, the first round receives no message.
+func (round1) ExpectedSenders() party.IDSlice { return nil }
+
+// receiveFrom returns the parties of committee other than this party if it is a new party.
+// The old parties receive no message, they only send.
+func (r *round1) receiveFrom(committee []party.ID) party.IDSlice {
+	if !r.Info.IsNewCommittee {
+		return nil
+	}
+	return party.NewIDSlice(committee).Remove(r.SelfID())
+}
+
 func (r *round1) CheckOK() bool {
 	return true
 }
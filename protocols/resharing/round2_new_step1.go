@@ -19,7 +19,6 @@ import (
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"crypto/rand"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 var _ round.Round = (*round2)(nil)
@@ -57,7 +56,7 @@ func (b broadcastToNewParty1) RoundNumber() round.Number {
 
 // Finalize function is used to execute the second round of the key resharing protocol. Old parties return directly to the next round, and New parties execute this round.
 func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round2 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	//this party check whether it has already processed (verified + stored) messages from the previous round.
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -87,13 +86,13 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 		// Sample RIDᵢ
 		SelfRID, err := types.NewRID(rand.Reader)
 		if err != nil {
-			log.Errorln("failed to sample Rho")
+			round.Logger(r).Errorln("failed to sample Rho")
 			return r, err
 		}
 		// Sample chainKey
 		chainKey, err := types.NewRID(rand.Reader)
 		if err != nil {
-			log.Errorln("failed to sample c")
+			round.Logger(r).Errorln("failed to sample c")
 			return r, err
 		}
 		// Make a hash commitment of data
@@ -101,7 +100,7 @@ func (r *round2) Finalize(out chan<- *round.Message) (round.Session, error) {
 			SelfRID, chainKey, SchnorrRand.Commitment(), ElGamalPublic,
 			SelfPedersenPublic.N(), SelfPedersenPublic.S(), SelfPedersenPublic.T())
 		if err != nil {
-			log.Errorln("failed to commit")
+			round.Logger(r).Errorln("failed to commit")
 			return r, err
 		}
 		//broadcast the commitment to other new parties
@@ -146,14 +145,14 @@ func (r *round2) MessageContent() round.Content {
 // VerifyMessage implements round.Round.
 // new party verifies the message sent by old party in round1
 func (r *round2) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round2 VerifyMessage")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 	if r.Info.IsNewCommittee { //new party verifies the message sent by old party in round1
 		body, ok := msg.Content.(*broadcastToNewParty1)
 		if !ok || body == nil {
 			return round.ErrInvalidContent
 		}
 		if err := body.CommitmentOldParty.Validate(); err != nil {
-			log.Errorln("fail to validate commit")
+			round.Logger(r).Errorln("fail to validate commit")
 			return err
 		}
 		return nil
@@ -165,7 +164,7 @@ func (r *round2) VerifyMessage(msg round.Message) error {
 // StoreMessage implements round.Round.
 // if the VerifyMessage is passed, then store the message in round2
 func (r *round2) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round2 StoreMessage")
+	round.Logger(r).Debugln("initiates StoreMessage")
 	if r.Info.IsNewCommittee { //new party stores the message sent by old party in round1
 		body, ok := msg.Content.(*broadcastToNewParty1)
 		if !ok || body == nil {
@@ -198,3 +197,6 @@ func (r *round2) CheckOK() bool {
 
 // Number implements round.Round.
 func (round2) Number() round.Number { return 2 }
+
+// ExpectedSenders implements round.Session, the new parties receive the messages of the old parties.
+func (r *round2) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.OldPartyIDs) }
@@ -17,7 +17,6 @@ import (
 	"MPC_ECDSA/pkg/party"
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type round3 struct {
@@ -74,7 +73,7 @@ func (b broadcastToNewParty2) RoundNumber() round.Number {
 
 // - save commitment Vⱼ.
 func (r *round3) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round3 StoreMessage")
+	round.Logger(r).Debugln("initiates StoreMessage")
 	if r.Info.IsNewCommittee {
 		body, _ := msg.Content.(*broadcastToNewParty2)
 		r.Commitments[msg.From] = body.Commitment
@@ -88,14 +87,14 @@ func (r *round3) StoreMessage(msg round.Message) error {
 // VerifyMessage implements round.Round.
 // new party verifies the message sent by new party in round2
 func (r *round3) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round3 VerifyMessage")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 	if r.Info.IsNewCommittee {
 		body, ok := msg.Content.(*broadcastToNewParty2)
 		if !ok || body == nil {
 			return round.ErrInvalidContent
 		}
 		if err := body.Commitment.Validate(); err != nil {
-			log.Errorln("fail to validate commit")
+			round.Logger(r).Errorln("fail to validate commit")
 			return err
 		}
 		return nil
@@ -118,7 +117,7 @@ func (r *round3) CheckOK() bool {
 
 // Finalize function is used to execute the 3th round of the key resharing protocol. New parties return directly to the next round, and Old parties execute this round.
 func (r *round3) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round3 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	//check whether it has already processed (verified + stored) messages from the previous round.
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -170,3 +169,6 @@ func (r *round3) MessageContent() round.Content {
 
 // Number implements round.Round.
 func (round3) Number() round.Number { return 3 }
+
+// ExpectedSenders implements round.Session, the new parties receive the messages of the new parties.
+func (r *round3) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.NewPartyIDs) }
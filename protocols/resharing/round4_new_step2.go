@@ -14,7 +14,6 @@ import (
 	"MPC_ECDSA/pkg/party"
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type round4 struct {
@@ -34,22 +33,22 @@ func (m messageToNewParty3) RoundNumber() round.Number {
 
 // VerifyMessage implements round.Round.
 func (r *round4) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round4 VerifyMessage")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 	if r.Info.IsNewCommittee {
 		//验证哈希
 		body, ok := msg.Content.(*messageToNewParty3)
 		if !ok || body == nil {
-			log.Errorln("fail to get body")
+			round.Logger(r).Errorln("fail to get body")
 			return round.ErrInvalidContent
 		}
 		if body.DeCommitmentsOldParty.Validate() != nil {
-			log.Errorln("fail to validate commitments")
+			round.Logger(r).Errorln("fail to validate commitments")
 			return errors.New("invalid commitments")
 		}
 		from := msg.From
 		if !r.HashForID(from).Decommit(r.CommitmentsOldParty[from], body.DeCommitmentsOldParty,
 			body.VSSPolynomialsOldParty) {
-			log.Errorln("failed to decommit")
+			round.Logger(r).Errorln("failed to decommit")
 		}
 		//验证X == Fⱼ(i)，Fⱼ(i)是参与方j发送给参与方i的vss多项式F_j(用基点加密后)带入i的值, X是参与方i收到的来自参与方j的share乘以基点G
 		ExpectedPublicShare := body.VSSPolynomialsOldParty.Evaluate(r.SelfID().Scalar(r.Group())) // 别人的Fⱼ(x_i)
@@ -66,7 +65,7 @@ func (r *round4) VerifyMessage(msg round.Message) error {
 
 // StoreMessage implements round.Round.
 func (r *round4) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round4 StoreMessage")
+	round.Logger(r).Debugln("initiates StoreMessage")
 	if r.Info.IsNewCommittee {
 		from, body := msg.From, msg.Content.(*messageToNewParty3)
 		r.ShareReceivedOldParty[from] = body.ShareOldParty
@@ -95,7 +94,7 @@ func (r *round4) CheckOK() bool {
 
 // Finalize function is used to execute the 4th round of the key resharing protocol. Old parties return directly to the next round, and New parties execute this round.
 func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round4 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	//check whether it has already processed (verified + stored) messages from the previous round.
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -156,7 +155,7 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 		// Make a hash commitment of VSSPolynomialNewParty
 		SelfCommitmentNewParty, SelfDecommitmentNewParty, err := r.HashForID(r.SelfID()).Commit(VSSPolynomialNewParty)
 		if err != nil {
-			log.Errorln("failed to commit")
+			round.Logger(r).Errorln("failed to commit")
 			return r, err
 		}
 		//broadcast the message to other new parties
@@ -180,7 +179,7 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 				ShareNewParty:            sharesFromNewParty[j],
 			}, j)
 			if err != nil {
-				log.Errorln("fail to send message")
+				round.Logger(r).Errorln("fail to send message")
 				return r, err
 			}
 		}
@@ -207,3 +206,6 @@ func (r *round4) MessageContent() round.Content {
 
 // Number implements round.Round.
 func (round4) Number() round.Number { return 4 }
+
+// ExpectedSenders implements round.Session, the new parties receive the messages of the old parties.
+func (r *round4) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.OldPartyIDs) }
@@ -22,7 +22,6 @@ import (
 	zkprm "MPC_ECDSA/pkg/zk/prm"
 	zksch "MPC_ECDSA/pkg/zk/sch"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type round5 struct {
@@ -66,7 +65,7 @@ func (b broadcast3) RoundNumber() round.Number {
 
 // StoreMessage implements round.Round.
 func (r *round5) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round5 StoreMessage")
+	round.Logger(r).Debugln("initiates StoreMessage")
 
 	if r.Info.IsNewCommittee { //new party stores messages sent by new parties in round 4.
 		from := msg.From
@@ -94,7 +93,7 @@ func (r *round5) StoreMessage(msg round.Message) error {
 
 // VerifyMessage implements round.Round.
 func (r *round5) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round5 VerifyMessage")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 
 	if r.Info.IsNewCommittee {
 		from := msg.From
@@ -108,16 +107,16 @@ func (r *round5) VerifyMessage(msg round.Message) error {
 		}
 		// check RID length
 		if err := body.RID.Validate(); err != nil {
-			log.Errorln(err)
+			round.Logger(r).Errorln(err)
 			return err
 		}
 		if err := body.C.Validate(); err != nil {
-			log.Errorln(err)
+			round.Logger(r).Errorln(err)
 			return err
 		}
 		// check decommitment
 		if err := body.Decommitment.Validate(); err != nil {
-			log.Errorln(err)
+			round.Logger(r).Errorln(err)
 			return err
 		}
 		// Save all X, VSSCommitments
@@ -125,29 +124,29 @@ func (r *round5) VerifyMessage(msg round.Message) error {
 
 		// check deg(Fⱼ) = t
 		if VSSPolynomial.Degree() != r.Info.NewThreshold {
-			log.Errorln("vss polynomial has incorrect degree")
+			round.Logger(r).Errorln("vss polynomial has incorrect degree")
 			return errors.New("vss polynomial has incorrect degree")
 		}
 
 		// Set Paillier
 		if err := paillier.ValidateN(body.N); err != nil {
-			log.Errorln(err)
+			round.Logger(r).Errorln(err)
 			return err
 		}
 
 		// Verify Pedersen
 		if err := pedersen.ValidateParameters(body.N, body.S, body.T); err != nil {
-			log.Errorln(err)
+			round.Logger(r).Errorln(err)
 			return err
 		}
 		// Verify decommit
 		if !r.HashForID(from).Decommit(r.Commitments[from], body.Decommitment,
 			body.RID, body.C, body.SchnorrCommitments, body.ElGamalPublic, body.N, body.S, body.T) {
-			log.Errorln("failed to decommit")
+			round.Logger(r).Errorln("failed to decommit")
 			return errors.New("failed to decommit")
 		}
 		if !r.HashForID(from).Decommit(body.SelfCommitmentNewParty, body.SelfDecommitmentNewParty, body.VSSPolynomialNewParty) {
-			log.Errorln("failed to decommit")
+			round.Logger(r).Errorln("failed to decommit")
 			return errors.New("failed to decommit")
 		}
 		//check if X == Fⱼ(i), Fⱼ(i) is the vss polynomial F_j (encrypted with the base point) sent by party j to party i with the value of i,
@@ -167,7 +166,7 @@ func (r *round5) VerifyMessage(msg round.Message) error {
 
 // Finalize function is used to execute the 5th round of the key resharing protocol. Old parties return directly to the next round, and New parties execute this round.
 func (r *round5) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round5 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	//check whether it has already processed (verified + stored) messages from the previous round.
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -262,6 +261,9 @@ func (r *round5) CheckOK() bool {
 // Number implements round.Round.
 func (round5) Number() round.Number { return 5 }
 
+// ExpectedSenders implements round.Session, the new parties receive the messages of the new parties.
+func (r *round5) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.NewPartyIDs) }
+
 func (r *round5) MessageContent() round.Content {
 	return &broadcast3{
 		ElGamalPublic:         r.Group().NewPoint(),
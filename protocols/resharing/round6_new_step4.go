@@ -17,7 +17,6 @@ import (
 	zkprm "MPC_ECDSA/pkg/zk/prm"
 	"MPC_ECDSA/protocols/config"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type round6 struct {
@@ -37,7 +36,7 @@ type broadcastToNewParty4 struct {
 
 // VerifyMessage- verify Mod, Prm proof for N
 func (r *round6) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round 6 VerifyMessage ")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 	if r.Info.IsNewCommittee { //new party verify Mod, Prm proof for N
 		from := msg.From
 		body, ok := msg.Content.(*broadcastToNewParty4)
@@ -46,12 +45,12 @@ func (r *round6) VerifyMessage(msg round.Message) error {
 		}
 		// verify zkmod
 		if !body.Mod.VerifyMal(zkmod.Public{N: r.NModulus[from]}, r.HashForID(from), r.Pool) {
-			log.Errorln("round6 failed to validate mod proof")
+			round.Logger(r).Errorln("round6 failed to validate mod proof")
 			//return errors.New("failed to validate mod proof")
 		}
 		// verify zkprm
 		if !body.Prm.VerifyMal(zkprm.Public{N: r.NModulus[from], S: r.S[from], T: r.T[from]}, r.HashForID(from), r.Pool) {
-			log.Errorln("round6 failed to validate prm proof")
+			round.Logger(r).Errorln("round6 failed to validate prm proof")
 			//return errors.New("failed to validate prm proof")
 		}
 		//reset the "ok" flag to true if the message from round5 new party is verified.
@@ -63,7 +62,7 @@ func (r *round6) VerifyMessage(msg round.Message) error {
 }
 
 func (r *round6) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round 6 StoreMessage ")
+	round.Logger(r).Debugln("initiates StoreMessage")
 	return nil
 }
 
@@ -73,7 +72,7 @@ func (b broadcastToNewParty4) RoundNumber() round.Number {
 
 // Finalize function is used to execute the 6th round of the key resharing protocol. Old parties return directly to the next round, and New parties execute this round.
 func (r *round6) Finalize(out chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round6 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	// Check if all parties have store the message from the previous round
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
@@ -108,7 +107,7 @@ func (r *round6) Finalize(out chan<- *round.Message) (round.Session, error) {
 		Fj := ShamirPublicPolynomial.Evaluate(r.SelfID().Scalar(r.Group()))
 		fjG := r.ECDSANewParty.ActOnBase()
 		if !Fj.Equal(fjG) {
-			log.Errorln("Fj is not equal to fjG ")
+			round.Logger(r).Errorln("Fj is not equal to fjG ")
 		}
 		for _, j := range r.Info.NewPartyIDs {
 			// Evaluate the Shamir public polynomial at party j's scalar value (F(j)*G).
@@ -177,6 +176,9 @@ func (r *round6) CheckOK() bool {
 // Number implements round.Round.
 func (round6) Number() round.Number { return 6 }
 
+// ExpectedSenders implements round.Session, the new parties receive the messages of the new parties.
+func (r *round6) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.NewPartyIDs) }
+
 func (r *round6) MessageContent() round.Content {
 	return &broadcastToNewParty4{
 		Mod: &zkmod.Proofbuf{},
@@ -8,10 +8,10 @@ package resharing
 
 import (
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/party"
 	sch "MPC_ECDSA/pkg/zk/sch"
 	"MPC_ECDSA/protocols/config"
 	"errors"
-	log "github.com/sirupsen/logrus"
 )
 
 type broadcastToNewParty5 struct {
@@ -27,7 +27,7 @@ func (b broadcastToNewParty5) RoundNumber() round.Number {
 
 // VerifyMessage implements round.Round.
 func (r *round7) VerifyMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round 7 VerifyMessage ")
+	round.Logger(r).Debugln("initiates VerifyMessage")
 
 	//Retrieve the sender of the message.
 	from := msg.From
@@ -51,17 +51,17 @@ func (r *round7) VerifyMessage(msg round.Message) error {
 
 // Finalize implements round.Round.
 func (r *round7) Finalize(chan<- *round.Message) (round.Session, error) {
-	log.Info(r.Info.SelfID, " initiates round7 Finalize")
+	round.Logger(r).Debugln("initiates Finalize")
 	if !r.CheckOK() {
 		err := errors.New("not all OK")
 		return r, err
 	}
 	if r.Info.IsNewCommittee {
-		log.Info(r.Info.SelfID, " new party return result round")
+		round.Logger(r).Debugln("new party return result round")
 		return r.ResultRound(r.UpdatedConfig), nil
 	} else {
 		r.UpdatedConfig = nil
-		log.Info(r.Info.SelfID, " old party return result round")
+		round.Logger(r).Debugln("old party return result round")
 		return r.ResultRound(r.UpdatedConfig), nil
 	}
 }
@@ -72,13 +72,16 @@ type round7 struct {
 }
 
 func (r *round7) StoreMessage(msg round.Message) error {
-	log.Info(r.Info.SelfID, " initiates round 7 StoreMessage ")
+	round.Logger(r).Debugln("initiates StoreMessage")
 	return nil
 }
 
 // Number implements round.Round.
 func (round7) Number() round.Number { return 7 }
 
+// ExpectedSenders implements round.Session, the new parties receive the messages of the new parties.
+func (r *round7) ExpectedSenders() party.IDSlice { return r.receiveFrom(r.Info.NewPartyIDs) }
+
 func (r *round7) MessageContent() round.Content {
 	return &broadcastToNewParty5{
 		SchnorrResponse: sch.EmptyResponse(r.Group()),
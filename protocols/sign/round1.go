@@ -4,11 +4,13 @@ import (
 	"crypto/rand"
 
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/digest"
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/sample"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pedersen"
+	"MPC_ECDSA/pkg/policy"
 	zkenc "MPC_ECDSA/pkg/zk/enc"
 )
 
@@ -26,6 +28,10 @@ type round1 struct {
 	ECDSA          map[party.ID]curve.Point
 
 	Message []byte
+	// Scheme is the digest scheme of Message
+	Scheme digest.Scheme
+	// Policy decides whether the signature share is released
+	Policy policy.Policy
 }
 
 // VerifyMessage implements round.Round.
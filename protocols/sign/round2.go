@@ -91,7 +91,7 @@ func (r *round2) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate enc proof for K")
+		return round.CheckFailed("enc", "failed to validate enc proof for K")
 	}
 	return nil
 }
@@ -1,7 +1,6 @@
 package sign
 
 import (
-	"errors"
 	"fmt"
 
 	"MPC_ECDSA/internal/round"
@@ -78,7 +77,7 @@ func (r *round3) VerifyMessage(msg round.Message) error {
 		Verifier: r.Paillier[to],
 		Aux:      r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate affg proof for Delta MtA")
+		return round.CheckFailed("affg", "failed to validate affg proof for Delta MtA")
 	}
 
 	if !body.ChiProof.VerifyMal(r.Group(), r.HashForID(from), zkaffg.Public{
@@ -90,7 +89,7 @@ func (r *round3) VerifyMessage(msg round.Message) error {
 		Verifier: r.Paillier[to],
 		Aux:      r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate affg proof for Chi MtA")
+		return round.CheckFailed("affg", "failed to validate affg proof for Chi MtA")
 	}
 
 	if !body.ProofLog.VerifyMal(r.Group(), r.HashForID(from), zklogstar.Public{
@@ -99,7 +98,7 @@ func (r *round3) VerifyMessage(msg round.Message) error {
 		Prover: r.Paillier[from],
 		Aux:    r.Pedersen[to],
 	}) {
-		return errors.New("failed to validate log proof")
+		return round.CheckFailed("logstar", "failed to validate log proof")
 	}
 
 	return nil
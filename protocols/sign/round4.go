@@ -2,10 +2,13 @@ package sign
 
 import (
 	"errors"
+	"time"
 
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
 	zklogstar "MPC_ECDSA/pkg/zk/logstar"
 )
 
@@ -72,7 +75,7 @@ func (r *round4) VerifyMessage(msg round.Message) error {
 		Aux:    r.Pedersen[to],
 	}
 	if !body.ProofLog.VerifyMal(r.Group(), r.HashForID(from), zkLogPublic) {
-		return errors.New("failed to validate log proof")
+		return round.CheckFailed("logstar", "failed to validate log proof")
 	}
 
 	return nil
@@ -109,6 +112,18 @@ func (r *round4) Finalize(out chan<- *round.Message) (round.Session, error) {
 	BigR := deltaInv.Act(r.Gamma)                         // R = [δ⁻¹] Γ
 	R := BigR.XScalar()                                   // r = R|ₓ
 
+	// the local policy decides whether the signature share is released
+	if err := policy.Enforce(r.Policy, &policy.Request{
+		Protocol:  r.ProtocolID(),
+		SelfID:    r.SelfID(),
+		Signers:   r.PartyIDs(),
+		PublicKey: r.PublicKey,
+		Digest:    digest.Digest{Scheme: r.Scheme, Hash: r.Message},
+		Time:      time.Now(),
+	}); err != nil {
+		return r.AbortRound(err), nil
+	}
+
 	// km = Hash(m)⋅kᵢ
 	km := curve.FromHash(r.Group(), r.Message)
 	km.Mul(r.KShare)
@@ -6,11 +6,14 @@ import (
 
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/internal/types"
+	"MPC_ECDSA/pkg/digest"
 	paillier "MPC_ECDSA/pkg/gmp_paillier"
+	"MPC_ECDSA/pkg/hash"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/math/polynomial"
 	"MPC_ECDSA/pkg/party"
 	"MPC_ECDSA/pkg/pedersen"
+	"MPC_ECDSA/pkg/policy"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/pkg/protocol"
 	"MPC_ECDSA/protocols/config"
@@ -24,9 +27,27 @@ const (
 
 // StartSign function is a factory function that returns a closure of type protocol.StartFunc
 // This closure is responsible for initializing and returning the initial round session for the signing protocol.
-func StartSign(config *config.Config, signers []party.ID, message []byte, pl *pool.Pool) protocol.StartFunc {
+// The policies are evaluated before the signature share is computed, as in StartSignDigest.
+func StartSign(config *config.Config, signers []party.ID, message []byte, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return startSign(config, signers, digest.Digest{Scheme: digest.Raw, Hash: message}, policy.All(policies...), pl)
+}
+
+// StartSignDigest is StartSign for a digest of the data to sign, the digest scheme is bound to the session.
+// The policies are evaluated before the signature share is computed, the execution aborts if one of them does not allow it.
+func StartSignDigest(config *config.Config, signers []party.ID, d digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return func(sessionID []byte) (round.Session, error) {
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("sign.Create: %w", err)
+		}
+		return startSign(config, signers, d, policy.All(policies...), pl, types.DigestScheme(d.Scheme))(sessionID)
+	}
+}
+
+// startSign creates the first round for the digest d, checked by p, with extra data bound to the session in aux.
+func startSign(config *config.Config, signers []party.ID, d digest.Digest, p policy.Policy, pl *pool.Pool, aux ...hash.WriterToWithDomain) protocol.StartFunc {
 	return func(sessionID []byte) (round.Session, error) {
 		group := config.Group
+		message := d.Hash
 		//If the length of the message is zero, it means that there is no message to sign
 		if len(message) == 0 {
 			return nil, errors.New("sign.Create: message is nil")
@@ -41,7 +62,7 @@ func StartSign(config *config.Config, signers []party.ID, message []byte, pl *po
 			Group:            config.Group,
 		}
 		//create a new round session helper
-		helper, err := round.NewSession(info, sessionID, pl, config, types.SigningMessage(message))
+		helper, err := round.NewSession(info, sessionID, pl, append([]hash.WriterToWithDomain{config, types.SigningMessage(message)}, aux...)...)
 		if err != nil {
 			return nil, fmt.Errorf("sign.Create: %w", err)
 		}
@@ -79,6 +100,8 @@ func StartSign(config *config.Config, signers []party.ID, message []byte, pl *po
 			Pedersen:       Pedersen,
 			ECDSA:          ECDSA,
 			Message:        message,
+			Scheme:         d.Scheme,
+			Policy:         p,
 		}, nil
 	}
 }
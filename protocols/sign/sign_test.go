@@ -1,13 +1,16 @@
 package sign
 
 import (
+	"errors"
 	mrand "math/rand"
 	"testing"
 
 	"MPC_ECDSA/internal/round"
 	"MPC_ECDSA/internal/test"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/ecdsa"
 	"MPC_ECDSA/pkg/math/curve"
+	"MPC_ECDSA/pkg/policy"
 	"MPC_ECDSA/pkg/pool"
 
 	"github.com/stretchr/testify/assert"
@@ -61,3 +64,92 @@ func TestRound(t *testing.T) {
 		assert.True(t, signature.Verify(publicPoint, messageHash), "expected valid signature")
 	}
 }
+
+// TestRoundDigest signs the Keccak-256 digest of a message longer than 32 bytes, and checks the signature against the whole digest.
+func TestRoundDigest(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	configs, partyIDs := test.GenerateConfig(group, 3, 1, mrand.New(mrand.NewSource(1)), pl)
+	partyIDs = partyIDs[:2]
+	publicPoint := configs[partyIDs[0]].PublicPoint()
+
+	d, err := digest.Compute(digest.Keccak256, []byte("a message which is longer than thirty two bytes"))
+	require.NoError(t, err)
+
+	_, err = StartSignDigest(configs[partyIDs[0]], partyIDs, digest.Digest{Scheme: digest.Raw, Hash: []byte("hello")}, pl)(nil)
+	assert.Error(t, err, "expected a short digest to be rejected")
+	_, err = StartSignDigest(configs[partyIDs[0]], partyIDs, digest.Digest{Scheme: "md5", Hash: d.Hash}, pl)(nil)
+	assert.Error(t, err, "expected an unknown scheme to be rejected")
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for _, partyID := range partyIDs {
+		r, err := StartSignDigest(configs[partyID], partyIDs, d, pl)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	for {
+		err, done := test.Rounds(rounds, nil)
+		require.NoError(t, err, "failed to process round")
+		if done {
+			break
+		}
+	}
+
+	for _, r := range rounds {
+		require.IsType(t, &round.Output{}, r, "expected result round")
+		signature := r.(*round.Output).Result.(*ecdsa.Signature)
+		assert.True(t, signature.Verify(publicPoint, d.Hash), "expected valid signature")
+		recoverable, err := signature.CompactRecoverable()
+		require.NoError(t, err)
+		recovered, err := ecdsa.RecoverPublicKey(d.Hash, recoverable)
+		require.NoError(t, err)
+		assert.True(t, publicPoint.Equal(recovered), "expected to recover the public key")
+	}
+}
+
+// TestRoundPolicy checks that a party denied by its policy aborts before it releases its signature share.
+func TestRoundPolicy(t *testing.T) {
+	pl := pool.NewPool(0)
+	defer pl.TearDown()
+	group := curve.Secp256k1{}
+
+	configs, partyIDs := test.GenerateConfig(group, 3, 1, mrand.New(mrand.NewSource(1)), pl)
+	partyIDs = partyIDs[:2]
+
+	d, err := digest.Compute(digest.SHA256, []byte("hello"))
+	require.NoError(t, err)
+
+	var requests []*policy.Request
+	deny := policy.Func(func(req *policy.Request) policy.Verdict {
+		requests = append(requests, req)
+		return policy.Verdict{Decision: policy.Deny, Rule: "test", Reason: "denied"}
+	})
+
+	rounds := make([]round.Session, 0, len(partyIDs))
+	for i, partyID := range partyIDs {
+		var policies []policy.Policy
+		if i == 0 {
+			policies = append(policies, deny)
+		}
+		r, err := StartSignDigest(configs[partyID], partyIDs, d, pl, policies...)(nil)
+		require.NoError(t, err, "round creation should not result in an error")
+		rounds = append(rounds, r)
+	}
+	for {
+		// the rounds differ once the denied party aborts
+		err, done := test.Rounds(rounds, nil)
+		if err != nil || done {
+			break
+		}
+	}
+
+	require.IsType(t, &round.Abort{}, rounds[0], "expected the denied party to abort")
+	assert.True(t, errors.Is(rounds[0].(*round.Abort).Err, policy.ErrDenied))
+	assert.IsType(t, &round5{}, rounds[1], "the other party should wait for the signature share")
+	require.Len(t, requests, 1)
+	assert.Equal(t, d, requests[0].Digest)
+	assert.Equal(t, partyIDs[0], requests[0].SelfID)
+	assert.True(t, configs[partyIDs[0]].PublicPoint().Equal(requests[0].PublicKey))
+}
@@ -9,16 +9,21 @@ package protocols
 import (
 	"MPC_ECDSA/communication"
 	"MPC_ECDSA/internal/round"
+	"MPC_ECDSA/pkg/digest"
 	"MPC_ECDSA/pkg/ecdsa"
 	"MPC_ECDSA/pkg/ecdsa3rounds"
 	"MPC_ECDSA/pkg/math/curve"
 	"MPC_ECDSA/pkg/party"
+	"MPC_ECDSA/pkg/policy"
 	"MPC_ECDSA/pkg/pool"
 	"MPC_ECDSA/pkg/protocol"
+	"MPC_ECDSA/protocols/auxinfo"
 	"MPC_ECDSA/protocols/config"
+	"MPC_ECDSA/protocols/frost"
 	"MPC_ECDSA/protocols/keygen"
 	"MPC_ECDSA/protocols/presign"
 	"MPC_ECDSA/protocols/presign3rounds"
+	"MPC_ECDSA/protocols/repair"
 	"MPC_ECDSA/protocols/resharing"
 
 	"MPC_ECDSA/protocols/sign"
@@ -55,6 +60,22 @@ func Keygen(group curve.Curve, selfID party.ID, participants []party.ID, thresho
 	return keygen.Start(info, pl, nil, useMnemonic)
 }
 
+// KeyImport shares the existing ECDSA secret key of the `dealer` among the participants, instead of generating a new one.
+// Every participant gives the `publicKey` being imported, and only the dealer gives its `secret`, which is erased after it has been shared.
+// The output is the same as for Keygen, and its PublicPoint() is `publicKey`.
+// Returns *cmp.Config if successful.
+func KeyImport(group curve.Curve, selfID party.ID, participants []party.ID, threshold int, dealer party.ID, publicKey curve.Point, secret curve.Scalar, pl *pool.Pool) protocol.StartFunc {
+	info := round.Info{
+		ProtocolID:       "cmp/keygen-import",
+		FinalRoundNumber: keygen.Rounds, // 5
+		SelfID:           selfID,
+		PartyIDs:         participants,
+		Threshold:        threshold,
+		Group:            group,
+	}
+	return keygen.StartImport(info, pl, dealer, publicKey, secret)
+}
+
 // Resharing allows for the modification of (t, n), where old participants can redistribute keys to new participants from a previously generated configuration
 // The group's ECDSA public key remains the same, but any previous shares are rendered useless.
 // Returns *cmp.Config if successful.
@@ -91,31 +112,109 @@ func Refresh(config *Config, pl *pool.Pool) protocol.StartFunc {
 	return keygen.Start(info, pl, config, false)
 }
 
+// KeyRepair helps the party `lost`, which lost its key share, to recover it without resharing the key.
+// All the parties of the config take part, with the `lost` party running KeyRecover, and the `helpers`,
+// at least Threshold+1 parties other than `lost`, contribute to the share.
+// Returns *cmp.Config with the new public data of the `lost` party if successful.
+func KeyRepair(config *Config, lost party.ID, helpers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	return repair.StartRepair(config, lost, helpers, pl)
+}
+
+// KeyRecover recovers the key share of `selfID` for `publicKey` from the `helpers`, while all the `partyIDs` of the key run KeyRepair.
+// Returns *cmp.Config if successful.
+func KeyRecover(group curve.Curve, selfID party.ID, partyIDs, helpers []party.ID, threshold int, publicKey curve.Point, pl *pool.Pool) protocol.StartFunc {
+	return repair.StartRecover(group, selfID, partyIDs, helpers, threshold, publicKey, pl)
+}
+
+// RefreshAuxInfo generates new Paillier keys and Pedersen parameters for all the parties of the config,
+// leaving the ECDSA and ElGamal shares unchanged. It is faster than Refresh, which also refreshes the shares.
+// Returns *cmp.Config if successful.
+func RefreshAuxInfo(config *Config, pl *pool.Pool) protocol.StartFunc {
+	return auxinfo.Start(config, pl)
+}
+
 // Sign generates an ECDSA signature for `messageHash` among the given `signers`.
+// The `policies` of the party are evaluated before it releases its signature share.
 // Returns *ecdsa.Signature if successful.
-func Sign(config *Config, signers []party.ID, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
-	return sign.StartSign(config, signers, messageHash, pl)
+func Sign(config *Config, signers []party.ID, messageHash []byte, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return sign.StartSign(config, signers, messageHash, pl, policies...)
 }
 
-// Presign generates a preprocessed signature that does not depend on the message being signed.
+// SignDigest generates an ECDSA signature for the digest `d` among the given `signers`.
+// Unlike Sign, the digest must be exactly 32 bytes, and all the signers must agree on its scheme.
+// The `policies` of the party are evaluated before it releases its signature share.
+// Returns *ecdsa.Signature if successful.
+func SignDigest(config *Config, signers []party.ID, d digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return sign.StartSignDigest(config, signers, d, pl, policies...)
+}
+
+// Presign generates `count` preprocessed signatures that do not depend on the message being signed.
 // When the message becomes available, the same participants can efficiently combine their shares
 // to produce a full signature with the PresignOnline protocol.
+// All the presignatures are generated in the same rounds, each with its own ID.
 // Note: the PreSignatures should be treated as secret key material.
-// Returns *ecdsa.PreSignature if successful.
-func Presign(config *Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
-	return presign.StartPresign(config, signers, nil, pl)
+// Returns []*ecdsa.PreSignature if successful.
+func Presign(config *Config, signers []party.ID, count int, pl *pool.Pool) protocol.StartFunc {
+	return presign.StartPresignBatch(config, signers, count, pl)
 }
 
 // SignAfterPresign efficiently generates an ECDSA signature for `messageHash` given a preprocessed `PreSignature`.
+// The `policies` of the party are evaluated before it releases its signature share.
 // Returns *ecdsa.Signature if successful.
-func SignAfterPresign(config *Config, preSignature *ecdsa.PreSignature, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
-	return presign.StartPresignOnline(config, preSignature, messageHash, pl)
+func SignAfterPresign(config *Config, preSignature *ecdsa.PreSignature, messageHash []byte, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign.StartPresignOnline(config, preSignature, messageHash, pl, policies...)
+}
+
+// SignAfterPresignDigest is SignAfterPresign for the digest `d`, checked by the `policies` of the party.
+// Returns *ecdsa.Signature if successful.
+func SignAfterPresignDigest(config *Config, preSignature *ecdsa.PreSignature, d digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign.StartPresignOnlineDigest(config, preSignature, d, pl, policies...)
+}
+
+// SignAfterPresignBatch signs each of the `digests` with the preprocessed `PreSignature` of the same index,
+// exchanging all the signature shares in a single round.
+// Like SignAfterPresignDigest, each digest must be exactly 32 bytes, and its scheme is bound to the session.
+// The `policies` of the party are evaluated on every digest before it releases any signature share.
+// Returns []*ecdsa.Signature if successful, and an *ecdsa.BatchError naming the culprits of each failed message otherwise.
+func SignAfterPresignBatch(config *Config, preSignatures []*ecdsa.PreSignature, digests []digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign.StartPresignOnlineBatch(config, preSignatures, digests, pl, policies...)
+}
+
+// Presign3rounds is Presign for the presignatures of the 3 rounds protocol.
+// Returns []*ecdsa3rounds.PreSignature3 if successful.
+func Presign3rounds(config *Config, signers []party.ID, count int, pl *pool.Pool) protocol.StartFunc {
+	return presign3rounds.StartPresignBatch(config, signers, count, pl)
+}
+
+// SignAfterPresign3rounds is SignAfterPresign for a presignature of Presign3rounds.
+// Returns *ecdsa3rounds.Signature if successful.
+func SignAfterPresign3rounds(config *Config, signers []party.ID, preSignature *ecdsa3rounds.PreSignature3, messageHash []byte, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign3rounds.StartPresignOnline(config, signers, preSignature, messageHash, pl, policies...)
+}
+
+// SignAfterPresign3roundsDigest is SignAfterPresign3rounds for the digest `d`, checked by the `policies` of the party.
+// Returns *ecdsa3rounds.Signature if successful.
+func SignAfterPresign3roundsDigest(config *Config, signers []party.ID, preSignature *ecdsa3rounds.PreSignature3, d digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign3rounds.StartPresignOnlineDigest(config, signers, preSignature, d, pl, policies...)
+}
+
+// SignAfterPresign3roundsBatch is SignAfterPresignBatch for presignatures of Presign3rounds.
+// Returns []*ecdsa3rounds.Signature if successful.
+func SignAfterPresign3roundsBatch(config *Config, signers []party.ID, preSignatures []*ecdsa3rounds.PreSignature3, digests []digest.Digest, pl *pool.Pool, policies ...policy.Policy) protocol.StartFunc {
+	return presign3rounds.StartPresignOnlineBatch(config, signers, preSignatures, digests, pl, policies...)
 }
 
-func Presign3rounds(config *Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
-	return presign3rounds.StartPresign(config, signers, nil, pl)
+// FrostPreprocess generates the nonces of a threshold Schnorr signature among the given `signers`.
+// It does not depend on the message, and can run ahead of time.
+// Note: the Nonces should be treated as secret key material, and used for a single signature. They cannot be encoded
+// and must never be persisted, after a restart the preprocessing is run again.
+// Returns *frost.Nonces if successful.
+func FrostPreprocess(config *Config, signers []party.ID, pl *pool.Pool) protocol.StartFunc {
+	return frost.StartPreprocess(config, signers, pl)
 }
 
-func SignAfterPresign3rounds(config *Config, signers []party.ID, preSignature *ecdsa3rounds.PreSignature3, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
-	return presign3rounds.StartPresignOnline(config, signers, preSignature, messageHash, pl)
+// FrostSign generates a BIP-340 signature for `messageHash` with the nonces of a FrostPreprocess among the same signers.
+// Returns taproot.Signature if successful.
+func FrostSign(config *Config, nonces *frost.Nonces, messageHash []byte, pl *pool.Pool) protocol.StartFunc {
+	return frost.StartSign(config, nonces, messageHash, pl)
 }